@@ -0,0 +1,40 @@
+// Package audit records who changed what, for compliance
+package audit
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// UserCreated is recorded on sign up and TagRenamed when an admin renames a
+// tag, with the old tag as target. Recipe changes are recorded with the event
+// types of the events package.
+const (
+	UserCreated = "user.created"
+	TagRenamed  = "tag.renamed"
+)
+
+// Entry is one change made by a user
+type Entry struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	Actor     string             `json:"actor" bson:"actor"`
+	Action    string             `json:"action" bson:"action"`
+	TargetID  string             `json:"targetId" bson:"targetId"`
+	Timestamp time.Time          `json:"timestamp" bson:"timestamp"`
+}
+
+// Recorder stores audit entries
+type Recorder interface {
+	Record(ctx context.Context, entry Entry) error
+}
+
+func NewEntry(actor string, action string, targetID string) Entry {
+	return Entry{
+		ID:        primitive.NewObjectID(),
+		Actor:     actor,
+		Action:    action,
+		TargetID:  targetID,
+		Timestamp: time.Now().UTC(),
+	}
+}
@@ -0,0 +1,22 @@
+package audit
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoRecorder appends entries to a MongoDB collection
+type MongoRecorder struct {
+	collection *mongo.Collection
+}
+
+func NewMongoRecorder(collection *mongo.Collection) *MongoRecorder {
+	return &MongoRecorder{
+		collection: collection,
+	}
+}
+
+func (recorder *MongoRecorder) Record(ctx context.Context, entry Entry) error {
+	_, err := recorder.collection.InsertOne(ctx, entry)
+	return err
+}
@@ -0,0 +1,24 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrMiss is returned by Get when the key is not cached
+var ErrMiss = errors.New("cache miss")
+
+// ErrLocked is returned by Lock when another caller holds the lock
+var ErrLocked = errors.New("cache key locked")
+
+// Cache stores serialized responses for a limited time
+type Cache interface {
+	// Get returns the cached value for key, or ErrMiss when there is none
+	Get(key string) (string, error)
+	// Set caches value under key. A ttl of zero keeps it until deleted.
+	Set(key string, value string, ttl time.Duration) error
+	Del(keys ...string) error
+	// Lock takes the lock named key, released by calling unlock or after ttl
+	// at the latest. It returns ErrLocked when the lock is already taken.
+	Lock(key string, ttl time.Duration) (unlock func(), err error)
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// MemoryCache is an in-process LRU cache, for development without Redis.
+// It holds at most capacity entries, evicting the least recently used, and
+// no entry outlives maxTTL when maxTTL is positive.
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	maxTTL   time.Duration
+	order    *list.List
+	entries  map[string]*list.Element
+	// locks maps the taken locks to when they expire
+	locks map[string]*time.Time
+}
+
+type memoryEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+func NewMemoryCache(capacity int, maxTTL time.Duration) *MemoryCache {
+	return &MemoryCache{
+		capacity: capacity,
+		maxTTL:   maxTTL,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		locks:    make(map[string]*time.Time),
+	}
+}
+
+func (cache *MemoryCache) Get(key string) (string, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		return "", ErrMiss
+	}
+	entry := element.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && !time.Now().Before(entry.expiresAt) {
+		cache.remove(element)
+		return "", ErrMiss
+	}
+	cache.order.MoveToFront(element)
+	return entry.value, nil
+}
+
+func (cache *MemoryCache) Set(key string, value string, ttl time.Duration) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.maxTTL > 0 && (ttl <= 0 || ttl > cache.maxTTL) {
+		ttl = cache.maxTTL
+	}
+	entry := &memoryEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	if element, ok := cache.entries[key]; ok {
+		element.Value = entry
+		cache.order.MoveToFront(element)
+		return nil
+	}
+	cache.entries[key] = cache.order.PushFront(entry)
+	for cache.capacity > 0 && cache.order.Len() > cache.capacity {
+		cache.remove(cache.order.Back())
+	}
+	return nil
+}
+
+func (cache *MemoryCache) Del(keys ...string) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := cache.entries[key]; ok {
+			cache.remove(element)
+		}
+	}
+	return nil
+}
+
+// Lock only guards against the callers of this process
+func (cache *MemoryCache) Lock(key string, ttl time.Duration) (func(), error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if expiresAt, ok := cache.locks[key]; ok && time.Now().Before(*expiresAt) {
+		return nil, ErrLocked
+	}
+	expiresAt := time.Now().Add(ttl)
+	lock := &expiresAt
+	cache.locks[key] = lock
+	return func() {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		// The lock may have expired and been taken again in the meantime
+		if cache.locks[key] == lock {
+			delete(cache.locks, key)
+		}
+	}, nil
+}
+
+func (cache *MemoryCache) remove(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.entries, element.Value.(*memoryEntry).key)
+}
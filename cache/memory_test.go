@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func expectValue(t *testing.T, cache Cache, key string, want string) {
+	t.Helper()
+	value, err := cache.Get(key)
+	if want == "" {
+		if err != ErrMiss {
+			t.Errorf("Get(%q) = %q, %v, want a miss", key, value, err)
+		}
+		return
+	}
+	if err != nil || value != want {
+		t.Errorf("Get(%q) = %q, %v, want %q", key, value, err, want)
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewMemoryCache(2, 0)
+	cache.Set("a", "1", 0)
+	cache.Set("b", "2", 0)
+	// Reading a makes b the least recently used
+	expectValue(t, cache, "a", "1")
+	cache.Set("c", "3", 0)
+
+	expectValue(t, cache, "b", "")
+	expectValue(t, cache, "a", "1")
+	expectValue(t, cache, "c", "3")
+
+	// Overwriting an entry does not count towards the capacity
+	cache.Set("c", "4", 0)
+	expectValue(t, cache, "a", "1")
+	expectValue(t, cache, "c", "4")
+}
+
+func TestMemoryCacheExpiresEntries(t *testing.T) {
+	cache := NewMemoryCache(10, time.Hour)
+	cache.Set("short", "1", 20*time.Millisecond)
+	cache.Set("long", "2", time.Minute)
+	expectValue(t, cache, "short", "1")
+
+	time.Sleep(30 * time.Millisecond)
+	expectValue(t, cache, "short", "")
+	expectValue(t, cache, "long", "2")
+}
+
+func TestMemoryCacheCapsTTL(t *testing.T) {
+	cache := NewMemoryCache(10, 20*time.Millisecond)
+	cache.Set("forever", "1", 0)
+	cache.Set("day", "2", 24*time.Hour)
+
+	time.Sleep(30 * time.Millisecond)
+	expectValue(t, cache, "forever", "")
+	expectValue(t, cache, "day", "")
+}
+
+func TestMemoryCacheDel(t *testing.T) {
+	cache := NewMemoryCache(10, 0)
+	cache.Set("a", "1", 0)
+	cache.Set("b", "2", 0)
+	cache.Del("a", "missing")
+	expectValue(t, cache, "a", "")
+	expectValue(t, cache, "b", "2")
+}
+
+func TestMemoryCacheLock(t *testing.T) {
+	cache := NewMemoryCache(10, 0)
+	unlock, err := cache.Lock("rebuild", time.Minute)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if _, err := cache.Lock("rebuild", time.Minute); err != ErrLocked {
+		t.Errorf("second Lock = %v, want ErrLocked", err)
+	}
+	unlock()
+	if _, err := cache.Lock("rebuild", time.Minute); err != nil {
+		t.Errorf("Lock after unlock: %v", err)
+	}
+
+	if _, err := cache.Lock("expiring", 10*time.Millisecond); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := cache.Lock("expiring", time.Minute); err != nil {
+		t.Errorf("Lock after expiry: %v", err)
+	}
+}
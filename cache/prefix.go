@@ -0,0 +1,37 @@
+package cache
+
+import "time"
+
+// PrefixedCache prepends a prefix to every key, so that services sharing
+// a Redis instance do not overwrite each other's entries
+type PrefixedCache struct {
+	cache  Cache
+	prefix string
+}
+
+func NewPrefixedCache(cache Cache, prefix string) *PrefixedCache {
+	return &PrefixedCache{
+		cache:  cache,
+		prefix: prefix,
+	}
+}
+
+func (cache *PrefixedCache) Get(key string) (string, error) {
+	return cache.cache.Get(cache.prefix + key)
+}
+
+func (cache *PrefixedCache) Set(key string, value string, ttl time.Duration) error {
+	return cache.cache.Set(cache.prefix+key, value, ttl)
+}
+
+func (cache *PrefixedCache) Del(keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = cache.prefix + key
+	}
+	return cache.cache.Del(prefixed...)
+}
+
+func (cache *PrefixedCache) Lock(key string, ttl time.Duration) (func(), error) {
+	return cache.cache.Lock(cache.prefix+key, ttl)
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"testing"
+	"time"
+)
+
+func TestPrefixedCacheKeys(t *testing.T) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("starting miniredis: %v", err)
+	}
+	defer server.Close()
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	shared := NewRedisCache(client)
+	recipes := NewPrefixedCache(shared, "recipes-api:")
+	other := NewPrefixedCache(shared, "other:")
+
+	if err := recipes.Set("recipes", "[]", time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got, err := server.Get("recipes-api:recipes"); err != nil || got != "[]" {
+		t.Errorf("recipes-api:recipes = %q, %v, want the value written with the prefix", got, err)
+	}
+	if server.Exists("recipes") {
+		t.Error("the key was written without its prefix")
+	}
+	expectValue(t, recipes, "recipes", "[]")
+	expectValue(t, other, "recipes", "")
+
+	server.Set("recipes", "unprefixed")
+	expectValue(t, recipes, "recipes", "[]")
+
+	unlock, err := recipes.Lock("lock:recipes", time.Minute)
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if !server.Exists("recipes-api:lock:recipes") {
+		t.Error("the lock was taken without the prefix")
+	}
+	if _, err := other.Lock("lock:recipes", time.Minute); err != nil {
+		t.Errorf("another prefix could not take its own lock: %v", err)
+	}
+	unlock()
+
+	if err := recipes.Del("recipes", "tags"); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	expectValue(t, recipes, "recipes", "")
+	if got, _ := server.Get("recipes"); got != "unprefixed" {
+		t.Errorf("recipes = %q, want the unprefixed key left alone", got)
+	}
+}
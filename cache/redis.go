@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/go-redis/redis"
+	"log"
+	"time"
+)
+
+// unlockScript deletes a lock only while it still holds the token of its
+// owner, so a lock that expired and was taken by someone else is left alone
+var unlockScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0
+`)
+
+// RedisCache is a Cache backed by Redis
+type RedisCache struct {
+	client *redis.Client
+}
+
+func NewRedisCache(client *redis.Client) *RedisCache {
+	return &RedisCache{
+		client: client,
+	}
+}
+
+func (cache *RedisCache) Get(key string) (string, error) {
+	val, err := cache.client.Get(key).Result()
+	if err == redis.Nil {
+		return "", ErrMiss
+	}
+	return val, err
+}
+
+func (cache *RedisCache) Set(key string, value string, ttl time.Duration) error {
+	return cache.client.Set(key, value, ttl).Err()
+}
+
+func (cache *RedisCache) Del(keys ...string) error {
+	return cache.client.Del(keys...).Err()
+}
+
+// Lock sets key with SET NX, shared by every instance using the same Redis
+func (cache *RedisCache) Lock(key string, ttl time.Duration) (func(), error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	value := hex.EncodeToString(token)
+
+	acquired, err := cache.client.SetNX(key, value, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrLocked
+	}
+	return func() {
+		if err := unlockScript.Run(cache.client, []string{key}, value).Err(); err != nil {
+			log.Printf("Warning: could not release lock %s: %v", key, err)
+		}
+	}, nil
+}
@@ -0,0 +1,90 @@
+// Package config reads settings from the environment
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bool reads a boolean from the environment, returning def when key is
+// unset or not a valid boolean
+func Bool(key string, def bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using %v", value, key, def)
+		return def
+	}
+	return parsed
+}
+
+// Int reads an integer from the environment, returning def when key is
+// unset or not a valid integer
+func Int(key string, def int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using %v", value, key, def)
+		return def
+	}
+	return parsed
+}
+
+// Duration reads a duration such as "10s" from the environment, returning
+// def when key is unset or not a valid duration
+func Duration(key string, def time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return def
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using %v", value, key, def)
+		return def
+	}
+	return parsed
+}
+
+// String reads a string from the environment, returning def when key is unset or empty
+func String(key string, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// List reads a comma-separated list from the environment, ignoring blank
+// items. It returns nil when key is unset or empty.
+func List(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Missing returns the keys that are unset or empty in the environment
+func Missing(keys ...string) []string {
+	missing := make([]string, 0)
+	for _, key := range keys {
+		if os.Getenv(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// connectMongo connects with opts and checks the primary is reachable,
+// reporting connection and ping failures separately
+func connectMongo(ctx context.Context, opts *options.ClientOptions) (*mongo.Client, error) {
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to MongoDB: %w", err)
+	}
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		client.Disconnect(ctx)
+		return nil, fmt.Errorf("could not ping MongoDB: %w", err)
+	}
+	return client, nil
+}
+
+// clientOptions applies to uri the TLS, pool and timeout settings of the
+// MONGO_TLS*, MONGO_*_POOL_SIZE and MONGO_*_TIMEOUT variables, which take
+// precedence over the same options in the URI
+func clientOptions(uri string) (*options.ClientOptions, error) {
+	opts := options.Client().ApplyURI(uri)
+
+	caFile := os.Getenv("MONGO_TLS_CA_FILE")
+	insecure := config.Bool("MONGO_TLS_INSECURE", false)
+	if config.Bool("MONGO_TLS", false) {
+		tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
+		if caFile != "" {
+			if insecure {
+				return nil, errors.New("MONGO_TLS_CA_FILE and MONGO_TLS_INSECURE cannot be used together")
+			}
+			pem, err := ioutil.ReadFile(caFile)
+			if err != nil {
+				return nil, fmt.Errorf("could not read MONGO_TLS_CA_FILE: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, errors.New("MONGO_TLS_CA_FILE contains no certificate")
+			}
+			tlsConfig.RootCAs = pool
+		}
+		opts.SetTLSConfig(tlsConfig)
+	} else if caFile != "" || insecure {
+		return nil, errors.New("MONGO_TLS_CA_FILE and MONGO_TLS_INSECURE require MONGO_TLS")
+	}
+
+	maxPool := config.Int("MONGO_MAX_POOL_SIZE", 0)
+	minPool := config.Int("MONGO_MIN_POOL_SIZE", 0)
+	if maxPool < 0 || minPool < 0 {
+		return nil, errors.New("MONGO_MAX_POOL_SIZE and MONGO_MIN_POOL_SIZE must not be negative")
+	}
+	if maxPool > 0 && minPool > maxPool {
+		return nil, fmt.Errorf("MONGO_MIN_POOL_SIZE (%d) is larger than MONGO_MAX_POOL_SIZE (%d)", minPool, maxPool)
+	}
+	if maxPool > 0 {
+		opts.SetMaxPoolSize(uint64(maxPool))
+	}
+	if minPool > 0 {
+		opts.SetMinPoolSize(uint64(minPool))
+	}
+
+	if timeout := config.Duration("MONGO_CONNECT_TIMEOUT", 0); timeout > 0 {
+		opts.SetConnectTimeout(timeout)
+	}
+	if timeout := config.Duration("MONGO_SERVER_SELECTION_TIMEOUT", 0); timeout > 0 {
+		opts.SetServerSelectionTimeout(timeout)
+	}
+	if timeout := config.Duration("MONGO_SOCKET_TIMEOUT", 0); timeout > 0 {
+		opts.SetSocketTimeout(timeout)
+	}
+
+	return opts, opts.Validate()
+}
+
+// databaseOptions reads the read preference and write concern applied to
+// every collection from MONGO_READ_PREFERENCE (default primary) and
+// MONGO_WRITE_CONCERN (default majority, or a number of nodes)
+func databaseOptions() (*options.DatabaseOptions, error) {
+	mode, err := readpref.ModeFromString(config.String("MONGO_READ_PREFERENCE", "primary"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MONGO_READ_PREFERENCE: %w", err)
+	}
+	readPreference, err := readpref.New(mode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MONGO_READ_PREFERENCE: %w", err)
+	}
+
+	writeConcern := writeconcern.New(writeconcern.WMajority())
+	if value := config.String("MONGO_WRITE_CONCERN", "majority"); value != "majority" {
+		nodes, err := strconv.Atoi(value)
+		if err != nil || nodes < 0 {
+			return nil, fmt.Errorf("invalid MONGO_WRITE_CONCERN %q, must be majority or a number of nodes", value)
+		}
+		writeConcern = writeconcern.New(writeconcern.W(nodes))
+	}
+
+	return options.Database().SetReadPreference(readPreference).SetWriteConcern(writeConcern), nil
+}
+
+// retry runs op until it succeeds or attempts are exhausted, doubling the
+// delay between attempts up to maxDelay, and returns the last error. op is
+// always run at least once.
+func retry(name string, attempts int, maxDelay time.Duration, op func() error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := 500 * time.Millisecond
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = op(); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+		log.Printf("%s not ready (attempt %d/%d): %v, retrying in %v", name, attempt, attempts, err, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+	return err
+}
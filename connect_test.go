@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnectMongoFailsOnBadURI(t *testing.T) {
+	if _, err := clientOptions("not-a-mongo-uri"); err == nil {
+		t.Error("clientOptions accepted an invalid URI")
+	}
+
+	_, err := connectMongo(context.Background(), options.Client().ApplyURI("not-a-mongo-uri"))
+	if err == nil || !strings.Contains(err.Error(), "could not connect to MongoDB") {
+		t.Errorf("err = %v, want a connection error", err)
+	}
+}
+
+func TestConnectMongoFailsOnUnreachableServer(t *testing.T) {
+	opts := options.Client().ApplyURI("mongodb://127.0.0.1:1").SetServerSelectionTimeout(100 * time.Millisecond)
+	_, err := connectMongo(context.Background(), opts)
+	if err == nil || !strings.Contains(err.Error(), "could not ping MongoDB") {
+		t.Errorf("err = %v, want a ping error", err)
+	}
+}
+
+func TestRetryEventuallyConnects(t *testing.T) {
+	calls := 0
+	err := retry("backend", 5, time.Millisecond, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("err, calls = %v, %d, want success on the third attempt", err, calls)
+	}
+}
+
+func TestRetryReturnsLastError(t *testing.T) {
+	calls := 0
+	err := retry("backend", 3, time.Millisecond, func() error {
+		calls++
+		return fmt.Errorf("attempt %d failed", calls)
+	})
+	if calls != 3 || err == nil || err.Error() != "attempt 3 failed" {
+		t.Errorf("err, calls = %v, %d, want the error of the third attempt", err, calls)
+	}
+}
+
+func TestRetryRunsAtLeastOnce(t *testing.T) {
+	for _, attempts := range []int{0, -2} {
+		calls := 0
+		retry("backend", attempts, time.Millisecond, func() error {
+			calls++
+			return errors.New("connection refused")
+		})
+		if calls != 1 {
+			t.Errorf("attempts %d: ran %d times, want once", attempts, calls)
+		}
+	}
+}
+
+func TestDatabaseOptions(t *testing.T) {
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	tests := []struct {
+		readPreference string
+		writeConcern   string
+		wantMode       readpref.Mode
+		wantW          interface{}
+	}{
+		{"", "", readpref.PrimaryMode, "majority"},
+		{"secondaryPreferred", "2", readpref.SecondaryPreferredMode, 2},
+		{"nearest", "majority", readpref.NearestMode, "majority"},
+	}
+	for _, test := range tests {
+		t.Setenv("MONGO_READ_PREFERENCE", test.readPreference)
+		t.Setenv("MONGO_WRITE_CONCERN", test.writeConcern)
+		opts, err := databaseOptions()
+		if err != nil {
+			t.Fatalf("%q, %q: %v", test.readPreference, test.writeConcern, err)
+		}
+		database := client.Database("recipes", opts)
+		if mode := database.ReadPreference().Mode(); mode != test.wantMode {
+			t.Errorf("%q: read preference = %v, want %v", test.readPreference, mode, test.wantMode)
+		}
+		if w := database.WriteConcern().GetW(); w != test.wantW {
+			t.Errorf("%q: write concern w = %v, want %v", test.writeConcern, w, test.wantW)
+		}
+	}
+}
+
+func TestDatabaseOptionsRejectsInvalidValues(t *testing.T) {
+	for _, env := range [][2]string{
+		{"MONGO_READ_PREFERENCE", "fastest"},
+		{"MONGO_WRITE_CONCERN", "all"},
+		{"MONGO_WRITE_CONCERN", "-1"},
+	} {
+		t.Run(env[0]+"="+env[1], func(t *testing.T) {
+			t.Setenv(env[0], env[1])
+			if _, err := databaseOptions(); err == nil || !strings.Contains(err.Error(), env[0]) {
+				t.Errorf("err = %v, want an error naming %s", err, env[0])
+			}
+		})
+	}
+}
+
+func TestClientOptionsFromEnv(t *testing.T) {
+	t.Setenv("MONGO_MAX_POOL_SIZE", "50")
+	t.Setenv("MONGO_MIN_POOL_SIZE", "5")
+	t.Setenv("MONGO_CONNECT_TIMEOUT", "3s")
+	t.Setenv("MONGO_SERVER_SELECTION_TIMEOUT", "4s")
+	t.Setenv("MONGO_SOCKET_TIMEOUT", "5s")
+	t.Setenv("MONGO_TLS", "true")
+
+	opts, err := clientOptions("mongodb://localhost:27017/?maxPoolSize=10&connectTimeoutMS=1000")
+	if err != nil {
+		t.Fatalf("clientOptions: %v", err)
+	}
+	if *opts.MaxPoolSize != 50 || *opts.MinPoolSize != 5 {
+		t.Errorf("pool size = %d..%d, want 5..50", *opts.MinPoolSize, *opts.MaxPoolSize)
+	}
+	if *opts.ConnectTimeout != 3*time.Second || *opts.ServerSelectionTimeout != 4*time.Second || *opts.SocketTimeout != 5*time.Second {
+		t.Errorf("timeouts = %v, %v, %v, want 3s, 4s, 5s", *opts.ConnectTimeout, *opts.ServerSelectionTimeout, *opts.SocketTimeout)
+	}
+	if opts.TLSConfig == nil || opts.TLSConfig.InsecureSkipVerify {
+		t.Errorf("TLS config = %+v, want TLS verifying the server", opts.TLSConfig)
+	}
+}
+
+func TestClientOptionsDefaultsToTheURI(t *testing.T) {
+	opts, err := clientOptions("mongodb://localhost:27017/?maxPoolSize=10&connectTimeoutMS=1000")
+	if err != nil {
+		t.Fatalf("clientOptions: %v", err)
+	}
+	if *opts.MaxPoolSize != 10 || *opts.ConnectTimeout != time.Second || opts.MinPoolSize != nil || opts.TLSConfig != nil {
+		t.Errorf("options = %+v, want only those of the URI", opts)
+	}
+}
+
+func TestClientOptionsRejectsInvalidSettings(t *testing.T) {
+	tests := []map[string]string{
+		{"MONGO_MAX_POOL_SIZE": "5", "MONGO_MIN_POOL_SIZE": "10"},
+		{"MONGO_MAX_POOL_SIZE": "-1"},
+		{"MONGO_TLS_INSECURE": "true"},
+		{"MONGO_TLS": "true", "MONGO_TLS_INSECURE": "true", "MONGO_TLS_CA_FILE": "ca.pem"},
+		{"MONGO_TLS": "true", "MONGO_TLS_CA_FILE": "missing.pem"},
+	}
+	for _, env := range tests {
+		t.Run(fmt.Sprint(env), func(t *testing.T) {
+			for key, value := range env {
+				t.Setenv(key, value)
+			}
+			if _, err := clientOptions("mongodb://localhost:27017"); err == nil {
+				t.Error("clientOptions accepted the settings")
+			}
+		})
+	}
+}
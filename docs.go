@@ -0,0 +1,38 @@
+package main
+
+import (
+	_ "embed"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+//go:generate swagger generate spec -o ./swagger.json
+
+//go:embed swagger.json
+var swaggerSpec []byte
+
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<title>Recipes API</title>
+	<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@4/swagger-ui.css">
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script src="https://unpkg.com/swagger-ui-dist@4/swagger-ui-bundle.js"></script>
+	<script>
+		window.ui = SwaggerUIBundle({url: "/swagger.json", dom_id: "#swagger-ui"});
+	</script>
+</body>
+</html>`
+
+// SwaggerSpecHandler serves the spec generated from the swagger annotations
+func SwaggerSpecHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", swaggerSpec)
+}
+
+// SwaggerUIHandler serves a Swagger UI page rendering /swagger.json
+func SwaggerUIHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUI))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSwaggerSpecHandler(t *testing.T) {
+	rec := get("/swagger.json", SwaggerSpecHandler)
+
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("status, content type = %d, %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var spec struct {
+		Swagger string                     `json:"swagger"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("the spec is not valid JSON: %v", err)
+	}
+	if spec.Swagger != "2.0" {
+		t.Errorf("swagger = %q, want 2.0", spec.Swagger)
+	}
+	paths := []string{
+		"/recipes", "/recipes/{id}", "/recipes/search", "/signin", "/signup", "/refresh",
+		"/recipes/trending", "/recipes/recent", "/recipes/random", "/user/recipes",
+		"/shopping-list", "/mealplans", "/webhooks", "/admin/audit",
+	}
+	for _, path := range paths {
+		if _, ok := spec.Paths[path]; !ok {
+			t.Errorf("the spec has no %s path, regenerate it with go generate", path)
+		}
+	}
+	for path := range spec.Paths {
+		if strings.Contains(path, ":") {
+			t.Errorf("the spec has the gin style path %s instead of {param}", path)
+		}
+	}
+}
+
+func TestSwaggerUIHandler(t *testing.T) {
+	rec := get("/docs", SwaggerUIHandler)
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `url: "/swagger.json"`) {
+		t.Errorf("status = %d, body does not load /swagger.json: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,38 @@
+package events
+
+import "sync"
+
+// ChannelPublisher fans events out to in-process subscribers
+type ChannelPublisher struct {
+	mu          sync.RWMutex
+	subscribers []chan Event
+}
+
+func NewChannelPublisher() *ChannelPublisher {
+	return &ChannelPublisher{}
+}
+
+// Subscribe returns a channel receiving every event published from now on.
+// Events are dropped for subscribers whose buffer is full, so a slow consumer
+// never blocks a request.
+func (publisher *ChannelPublisher) Subscribe(buffer int) <-chan Event {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	subscriber := make(chan Event, buffer)
+	publisher.subscribers = append(publisher.subscribers, subscriber)
+	return subscriber
+}
+
+func (publisher *ChannelPublisher) Publish(event Event) error {
+	publisher.mu.RLock()
+	defer publisher.mu.RUnlock()
+
+	for _, subscriber := range publisher.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package events
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	RecipeCreated = "recipe.created"
+	RecipeUpdated = "recipe.updated"
+	RecipeDeleted = "recipe.deleted"
+)
+
+// Event describes a change to a recipe, for downstream consumers such as
+// search indexers or notifiers
+type Event struct {
+	Type      string             `json:"type"`
+	RecipeID  primitive.ObjectID `json:"recipeId"`
+	UserID    primitive.ObjectID `json:"userId"`
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+// Publisher delivers recipe events. Publishing is best effort: a failure
+// must not undo the change that triggered the event.
+type Publisher interface {
+	Publish(event Event) error
+}
+
+func NewEvent(eventType string, recipeID primitive.ObjectID, userID primitive.ObjectID) Event {
+	return Event{
+		Type:      eventType,
+		RecipeID:  recipeID,
+		UserID:    userID,
+		Timestamp: time.Now().UTC(),
+	}
+}
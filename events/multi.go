@@ -0,0 +1,23 @@
+package events
+
+// MultiPublisher publishes every event to each of its publishers
+type MultiPublisher struct {
+	publishers []Publisher
+}
+
+func NewMultiPublisher(publishers ...Publisher) *MultiPublisher {
+	return &MultiPublisher{
+		publishers: publishers,
+	}
+}
+
+// Publish tries every publisher and returns the first error encountered
+func (multi *MultiPublisher) Publish(event Event) error {
+	var firstErr error
+	for _, publisher := range multi.publishers {
+		if err := publisher.Publish(event); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
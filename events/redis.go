@@ -0,0 +1,27 @@
+package events
+
+import (
+	"encoding/json"
+	"github.com/go-redis/redis"
+)
+
+// RedisPublisher publishes events as JSON on a Redis pub/sub channel
+type RedisPublisher struct {
+	client  *redis.Client
+	channel string
+}
+
+func NewRedisPublisher(client *redis.Client, channel string) *RedisPublisher {
+	return &RedisPublisher{
+		client:  client,
+		channel: channel,
+	}
+}
+
+func (publisher *RedisPublisher) Publish(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return publisher.client.Publish(publisher.channel, string(data)).Err()
+}
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"encoding/json"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func TestRedisPublisherPublishesJSON(t *testing.T) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("starting miniredis: %v", err)
+	}
+	defer server.Close()
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	subscription := client.Subscribe("recipes.events")
+	defer subscription.Close()
+	if _, err := subscription.Receive(); err != nil {
+		t.Fatalf("subscribing: %v", err)
+	}
+
+	event := NewEvent(RecipeCreated, primitive.NewObjectID(), primitive.NewObjectID())
+	if err := NewRedisPublisher(client, "recipes.events").Publish(event); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	select {
+	case message := <-subscription.Channel():
+		var got Event
+		if err := json.Unmarshal([]byte(message.Payload), &got); err != nil {
+			t.Fatalf("decoding %s: %v", message.Payload, err)
+		}
+		if got.Type != event.Type || got.RecipeID != event.RecipeID || got.UserID != event.UserID || !got.Timestamp.Equal(event.Timestamp) {
+			t.Errorf("got %+v, want %+v", got, event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no message was published")
+	}
+}
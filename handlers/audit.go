@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gabrielsscti/Recipes-API/audit"
+	"github.com/gabrielsscti/Recipes-API/events"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"strings"
+)
+
+type AuditHandler struct {
+	collection Collection
+	ctx        context.Context
+}
+
+func NewAuditHandler(ctx context.Context, collection Collection) *AuditHandler {
+	return &AuditHandler{
+		collection: collection,
+		ctx:        ctx,
+	}
+}
+
+// AuditPage is the envelope returned by the audit log listing
+type AuditPage struct {
+	Entries []audit.Entry `json:"entries"`
+	Page    int           `json:"page"`
+	Limit   int           `json:"limit"`
+	Total   int64         `json:"total"`
+}
+
+// swagger:operation GET /admin/audit admin listAudit
+// Returns a page of the audit log, most recent first. Admin only.
+// ---
+// produces:
+// - application/json
+// - application/yaml
+// parameters:
+//   - name: actor
+//     in: query
+//     description: only return the changes made by this username
+//     required: false
+//     type: string
+//   - name: action
+//     in: query
+//     description: only return this action, such as recipe.updated
+//     required: false
+//     type: string
+//   - name: page
+//     in: query
+//     description: page number, starting at 1
+//     required: false
+//     type: integer
+//   - name: limit
+//     in: query
+//     description: number of entries per page
+//     required: false
+//     type: integer
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid pagination parameters
+//     '403':
+//         description: Caller is not an admin
+func (handler *AuditHandler) ListAuditHandler(c *gin.Context) {
+	filter := bson.M{}
+	if actor := normalizeUsername(c.Query("actor")); actor != "" {
+		filter["actor"] = actor
+	}
+	if action := strings.TrimSpace(c.Query("action")); action != "" {
+		filter["action"] = action
+	}
+
+	handler.listEntries(c, filter)
+}
+
+// activityActions are the audit actions shown in the activity feed of users
+var activityActions = []string{events.RecipeCreated, events.RecipeUpdated, events.RecipeDeleted}
+
+// swagger:operation GET /user/activity auth userActivity
+// Returns a page of the recipe changes made by the authenticated user, most
+// recent first. Only changes recorded while AUDIT_ENABLED is set are listed.
+// ---
+// produces:
+// - application/json
+// - application/yaml
+// parameters:
+//   - name: page
+//     in: query
+//     description: page number, starting at 1
+//     required: false
+//     type: integer
+//   - name: limit
+//     in: query
+//     description: number of entries per page
+//     required: false
+//     type: integer
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid pagination parameters
+func (handler *AuditHandler) ActivityHandler(c *gin.Context) {
+	handler.listEntries(c, bson.M{
+		"actor":  c.GetString("username"),
+		"action": bson.M{"$in": activityActions},
+	})
+}
+
+// listEntries writes the page of the audit entries matching filter requested
+// with the page and limit query parameters
+func (handler *AuditHandler) listEntries(c *gin.Context, filter bson.M) {
+	page, limit, err := parsePageParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	total, err := handler.collection.CountDocuments(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cur, err := handler.collection.Find(c.Request.Context(), filter, findOptions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer cur.Close(c.Request.Context())
+
+	entries := make([]audit.Entry, 0)
+	if err := cur.All(c.Request.Context(), &entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	setPaginationHeaders(c, page, limit, int(total))
+	render(c, http.StatusOK, AuditPage{
+		Entries: entries,
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+	})
+}
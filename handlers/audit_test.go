@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"github.com/gabrielsscti/Recipes-API/audit"
+	"github.com/gabrielsscti/Recipes-API/events"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecipeUpdateIsAudited(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("update then list", func(mt *mtest.T) {
+		alice := newCaller("alice")
+		env := memoryEnv(mt.T).as(alice)
+		env.handler.audit = audit.NewMongoRecorder(mt.Coll)
+		recipe := env.seed(mt.T, publishedRecipe("Pancakes", alice.ID))
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		updated := newRecipe("Crêpes")
+		expectStatus(mt.T, env.request(http.MethodPut, "/recipes/"+recipe.ID.Hex(), updated), http.StatusOK)
+
+		insert := mt.GetStartedEvent()
+		if insert == nil || insert.CommandName != "insert" {
+			mt.Fatalf("command = %v, want the audit entry inserted", insert)
+		}
+		raw, err := insert.Command.Lookup("documents").Array().IndexErr(0)
+		if err != nil {
+			mt.Fatalf("the insert has no document: %v", err)
+		}
+		var entry audit.Entry
+		var doc bson.D
+		if err := bson.Unmarshal(raw.Value().Document(), &entry); err != nil {
+			mt.Fatalf("decoding the entry: %v", err)
+		}
+		bson.Unmarshal(raw.Value().Document(), &doc)
+		if entry.Actor != "alice" || entry.Action != events.RecipeUpdated || entry.TargetID != recipe.ID.Hex() || entry.Timestamp.IsZero() {
+			mt.Fatalf("entry = %+v, want alice updating %s", entry, recipe.ID.Hex())
+		}
+
+		auditHandler := NewAuditHandler(mt.Context(), mt.Coll)
+		env.router.GET("/admin/audit", auditHandler.ListAuditHandler)
+		mt.AddMockResponses(
+			cursorOf(mt, bson.D{{Key: "n", Value: 1}}),
+			cursorOf(mt, doc),
+		)
+		rec := env.request(http.MethodGet, "/admin/audit?actor=Alice&action=recipe.updated&limit=10", nil)
+		expectStatus(mt.T, rec, http.StatusOK)
+		var page AuditPage
+		decodeBody(mt.T, rec, &page)
+		if page.Total != 1 || len(page.Entries) != 1 || page.Entries[0].ID != entry.ID || page.Entries[0].Action != events.RecipeUpdated {
+			mt.Errorf("page = %+v, want the update entry", page)
+		}
+
+		queries := 0
+		for _, event := range mt.GetAllStartedEvents() {
+			var filter string
+			switch event.CommandName {
+			case "aggregate":
+				filter = event.Command.Lookup("pipeline").String()
+			case "find":
+				filter = event.Command.Lookup("filter").String()
+			default:
+				continue
+			}
+			queries++
+			if !strings.Contains(filter, `"actor": "alice"`) || !strings.Contains(filter, `"action": "recipe.updated"`) {
+				mt.Errorf("%s filter = %s, want the actor and action", event.CommandName, filter)
+			}
+		}
+		if queries != 2 {
+			mt.Errorf("%d queries, want the count and the find", queries)
+		}
+	})
+}
+
+func TestCreatedRecipeAppearsInTheActivityFeed(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("create, update then list", func(mt *mtest.T) {
+		alice := newCaller("alice")
+		env := memoryEnv(mt.T).as(alice)
+		env.handler.audit = audit.NewMongoRecorder(mt.Coll)
+		auditHandler := NewAuditHandler(mt.Context(), mt.Coll)
+		env.router.GET("/user/activity", auditHandler.ActivityHandler)
+
+		// recorded keeps the audit entries inserted by the requests, oldest first
+		var recorded []bson.Raw
+		record := func(rec *httptest.ResponseRecorder) {
+			mt.T.Helper()
+			expectStatus(mt.T, rec, http.StatusOK)
+			event := mt.GetStartedEvent()
+			if event == nil || event.CommandName != "insert" {
+				mt.Fatalf("command = %v, want the audit entry inserted", event)
+			}
+			recorded = append(recorded, event.Command.Lookup("documents").Array().Index(0).Value().Document())
+			mt.ClearEvents()
+		}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		rec := env.request(http.MethodPost, "/recipes", newRecipe("Pancakes"))
+		var created models.Recipe
+		decodeBody(mt.T, rec, &created)
+		record(rec)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		record(env.request(http.MethodPut, "/recipes/"+created.ID.Hex(), newRecipe("Crêpes")))
+
+		// The collection answers for the sort it is sent, checked below
+		mt.AddMockResponses(
+			cursorOf(mt, bson.D{{Key: "n", Value: 2}}),
+			cursorOf(mt, toD(mt, recorded[1]), toD(mt, recorded[0])),
+		)
+		rec = env.request(http.MethodGet, "/user/activity?limit=10", nil)
+		expectStatus(mt.T, rec, http.StatusOK)
+		var page AuditPage
+		decodeBody(mt.T, rec, &page)
+		if page.Total != 2 || len(page.Entries) != 2 {
+			mt.Fatalf("page = %+v, want both entries", page)
+		}
+		if page.Entries[0].Action != events.RecipeUpdated || page.Entries[1].Action != events.RecipeCreated ||
+			page.Entries[1].TargetID != created.ID.Hex() {
+			mt.Errorf("entries = %+v, want the update then the creation of %s", page.Entries, created.ID.Hex())
+		}
+
+		finds := 0
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName != "find" {
+				continue
+			}
+			finds++
+			filter := event.Command.Lookup("filter").String()
+			if !strings.Contains(filter, `"actor": "alice"`) || !strings.Contains(filter, events.RecipeCreated) {
+				mt.Errorf("filter = %s, want the recipe changes of alice", filter)
+			}
+			if sort := event.Command.Lookup("sort").String(); !strings.HasPrefix(sort, `{"timestamp": {"$numberInt":"-1"}`) {
+				mt.Errorf("sort = %s, want the most recent first", sort)
+			}
+		}
+		if finds != 1 {
+			mt.Errorf("%d finds, want one", finds)
+		}
+	})
+}
+
+func toD(mt *mtest.T, raw bson.Raw) bson.D {
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		mt.Fatalf("decoding %s: %v", raw, err)
+	}
+	return doc
+}
@@ -2,37 +2,149 @@ package handlers
 
 import (
 	"context"
-	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/gabrielsscti/Recipes-API/audit"
+	"github.com/gabrielsscti/Recipes-API/cache"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"github.com/gabrielsscti/Recipes-API/mail"
 	"github.com/gabrielsscti/Recipes-API/models"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
 	"net/http"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 )
 
 type AuthHandler struct {
-	collection *mongo.Collection
+	collection Collection
 	ctx        context.Context
+	signer     *TokenSigner
+	audit      audit.Recorder
+	// invites holds the codes required to sign up when inviteOnly is set
+	invites Collection
+	// cache holds user profiles by username for userCacheTTL
+	cache        cache.Cache
+	userCacheTTL time.Duration
+	// refreshCookie sends the token to browsers in an HttpOnly cookie that
+	// /refresh reads back, instead of relying on the Authorization header
+	refreshCookie bool
+	// accessTTL is how long access tokens are valid, and refreshTTL how long
+	// refresh tokens can be exchanged for new ones
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	// sessionMax is how long after signing in tokens can be refreshed
+	sessionMax time.Duration
+	// detailedSignupErrors tells clients which username or email is taken,
+	// which also lets anyone find out who has an account
+	detailedSignupErrors bool
+	// signupEnabled is false for deployments closed to registration
+	signupEnabled bool
+	inviteOnly    bool
+	// emailVerification requires an email on sign up, and holds back sign in
+	// until the link mailed to it is followed
+	emailVerification bool
+	verificationTTL   time.Duration
+	mailer            mail.Mailer
+	// publicURL is where clients reach the API, for the links in emails
+	publicURL string
+
+	passwordPolicy PasswordPolicy
+	// now is the clock tokens are issued and validated with, replaced in tests
+	now func() time.Time
+	// issuer and audience are set on issued tokens. Tokens from another
+	// issuer, or for none of audiences, are rejected when they are set.
+	issuer    string
+	audience  string
+	audiences []string
 }
 
+const refreshCookieName = "refresh_token"
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_-]{3,30}$`)
+
 type Claims struct {
-	Username string `json:"username"`
+	Username string             `json:"username"`
+	UserID   primitive.ObjectID `json:"userId"`
+	Role     string             `json:"role"`
+	// SessionStart is when the user signed in, as a Unix time. Refreshed
+	// tokens keep it, so that sessions end after sessionMax.
+	SessionStart int64 `json:"sessionStart,omitempty"`
+	// Type tells access and refresh tokens apart, so that neither is
+	// accepted in place of the other
+	Type string `json:"type,omitempty"`
 	jwt.StandardClaims
 }
 
+// Values of the type claim
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+// tokenType returns the type claim. Tokens issued before it was added have
+// none and are access tokens.
+func (claims *Claims) tokenType() string {
+	if claims.Type == "" {
+		return tokenTypeAccess
+	}
+	return claims.Type
+}
+
 type JWTOutput struct {
-	Token   string    `json:"token"`
-	Expires time.Time `json:"expires"`
+	Token string `json:"token"`
+	// RefreshToken is only accepted by POST /refresh, and Token everywhere but
+	// there. It is left out when it is sent in the refresh cookie instead.
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	Expires      time.Time `json:"expires"`
 }
 
-func NewAuthHandler(ctx context.Context, collection *mongo.Collection) *AuthHandler {
+func NewAuthHandler(ctx context.Context, collection Collection, invites Collection, signer *TokenSigner, recorder audit.Recorder, usersCache cache.Cache, mailer mail.Mailer) *AuthHandler {
 	return &AuthHandler{
-		collection: collection,
-		ctx:        ctx,
+		collection:     collection,
+		invites:        invites,
+		ctx:            ctx,
+		signer:         signer,
+		audit:          recorder,
+		cache:          usersCache,
+		userCacheTTL:   config.Duration("USER_CACHE_TTL", time.Minute),
+		refreshCookie:  config.Bool("REFRESH_TOKEN_COOKIE", false),
+		accessTTL:      config.Duration("JWT_ACCESS_TTL", 10*time.Minute),
+		refreshTTL:     config.Duration("JWT_REFRESH_TTL", 24*time.Hour),
+		sessionMax:     config.Duration("JWT_SESSION_MAX", 24*time.Hour),
+		passwordPolicy: LoadPasswordPolicy(),
+
+		detailedSignupErrors: config.Bool("SIGNUP_DETAILED_ERRORS", false),
+		signupEnabled:        config.Bool("SIGNUP_ENABLED", true),
+		inviteOnly:           loadInviteOnly(),
+		emailVerification:    config.Bool("EMAIL_VERIFICATION", false),
+		verificationTTL:      config.Duration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		mailer:               mailer,
+		publicURL:            config.String("PUBLIC_URL", "http://localhost:8080"),
+		now:                  time.Now,
+		issuer:               os.Getenv("JWT_ISSUER"),
+		audience:             os.Getenv("JWT_AUDIENCE"),
+		audiences:            loadAudiences(),
+	}
+}
+
+// loadAudiences reads JWT_ALLOWED_AUDIENCES, the comma-separated audiences
+// accepted in tokens, which default to JWT_AUDIENCE
+func loadAudiences() []string {
+	if audiences := config.List("JWT_ALLOWED_AUDIENCES"); len(audiences) > 0 {
+		return audiences
+	}
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		return []string{audience}
 	}
+	return nil
 }
 
 // swagger:operation POST /signin auth signIn
@@ -45,6 +157,8 @@ func NewAuthHandler(ctx context.Context, collection *mongo.Collection) *AuthHand
 //         description: Successful operation
 //     '401':
 //         description: Invalid credentials
+//     '403':
+//         description: The email of the user is not verified yet
 func (handler *AuthHandler) SignInHandler(c *gin.Context) {
 	var user models.User
 	if err := c.ShouldBindJSON(&user); err != nil {
@@ -52,61 +166,191 @@ func (handler *AuthHandler) SignInHandler(c *gin.Context) {
 		return
 	}
 
-	h := sha256.New()
+	storedUser, err := handler.findUserToSignIn(c.Request.Context(), user.Username)
+	if err != nil && err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	cur := handler.collection.FindOne(handler.ctx, bson.M{
-		"username": user.Username,
-		"password": string(h.Sum([]byte(user.Password))),
-	})
-	if cur.Err() != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	// Unknown users and wrong passwords take the same time and get the same answer
+	ok, legacy := checkPassword(storedUser.Password, user.Password)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, errorBody(c, msgInvalidCredentials))
 		return
 	}
+	if storedUser.PendingVerification() {
+		c.JSON(http.StatusForbidden, errorBody(c, msgEmailNotVerified))
+		return
+	}
+	if legacy {
+		handler.upgradePasswordHash(c, storedUser, user.Password)
+	}
 
-	expirationTime := time.Now().Add(10 * time.Minute)
 	claims := &Claims{
-		Username: user.Username,
+		Username:     storedUser.Username,
+		UserID:       storedUser.ID,
+		Role:         storedUser.Role,
+		SessionStart: handler.now().Unix(),
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
+			Issuer:   handler.issuer,
+			Audience: handler.audience,
 		},
 	}
+	handler.issueTokens(c, *claims)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+// issueTokens answers an access token valid for accessTTL and a refresh token
+// valid for refreshTTL, made of claims. Neither outlives the session, and
+// they otherwise only differ by their type claim.
+func (handler *AuthHandler) issueTokens(c *gin.Context, claims Claims) {
+	now := handler.now()
+	sessionEnd := time.Unix(claims.SessionStart, 0).Add(handler.sessionMax)
+	accessExpires := minTime(now.Add(handler.accessTTL), sessionEnd)
+	refreshExpires := minTime(now.Add(handler.refreshTTL), sessionEnd)
+	claims.IssuedAt = now.Unix()
 
+	claims.Type = tokenTypeAccess
+	claims.ExpiresAt = accessExpires.Unix()
+	accessToken, err := handler.signer.Sign(&claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	claims.Type = tokenTypeRefresh
+	claims.ExpiresAt = refreshExpires.Unix()
+	refreshToken, err := handler.signer.Sign(&claims)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	jwtOutput := JWTOutput{
-		Token:   tokenString,
-		Expires: expirationTime,
+		Token:   accessToken,
+		Expires: accessExpires,
+	}
+	// The cookie lasts as long as the refresh token, and keeps it out of
+	// reach of the page scripts that read the body
+	if handler.refreshCookie {
+		handler.setRefreshCookie(c, refreshToken, refreshExpires)
+	} else {
+		jwtOutput.RefreshToken = refreshToken
 	}
 	c.JSON(http.StatusOK, jwtOutput)
 }
 
+// minTime returns the earliest of a and b
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
+
+// upgradePasswordHash replaces a legacy sha256 hash once the password is known
+func (handler *AuthHandler) upgradePasswordHash(c *gin.Context, user models.User, password string) {
+	hash, err := hashPassword(password)
+	if err == nil {
+		_, err = handler.collection.UpdateOne(c.Request.Context(), bson.M{"_id": user.ID}, bson.M{"$set": bson.M{"password": hash}})
+	}
+	if err != nil {
+		log.Printf("Could not upgrade the password hash of user %s: %v", user.ID.Hex(), err)
+		return
+	}
+	handler.invalidateUser(user.Username)
+}
+
+// setRefreshCookie stores the refresh token in an HttpOnly, Secure, SameSite cookie
+// scoped to /refresh when cookie mode is enabled
+func (handler *AuthHandler) setRefreshCookie(c *gin.Context, tokenString string, expires time.Time) {
+	if !handler.refreshCookie {
+		return
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(refreshCookieName, tokenString, int(expires.Sub(handler.now()).Seconds()), "/refresh", "", true, true)
+}
+
 func (handler *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tokenValue := c.GetHeader("Authorization")
-		claims := &Claims{}
-
-		tkn, err := jwt.ParseWithClaims(tokenValue, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-		if err != nil {
+		if !handler.authenticate(c) {
 			c.AbortWithStatus(http.StatusUnauthorized)
+			return
 		}
-		if tkn == nil || !tkn.Valid {
-			c.AbortWithStatus(http.StatusUnauthorized)
+		c.Next()
+	}
+}
+
+// OptionalAuthMiddleware identifies the caller when a valid token is sent,
+// but lets anonymous requests through, for public routes whose response
+// depends on who is asking
+func (handler *AuthHandler) OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			handler.authenticate(c)
 		}
 		c.Next()
 	}
 }
 
+// authenticate verifies the request token and stores the caller in the context
+func (handler *AuthHandler) authenticate(c *gin.Context) bool {
+	claims, err := handler.parseToken(c.GetHeader("Authorization"))
+	if err != nil {
+		return false
+	}
+	c.Set("username", claims.Username)
+	c.Set("userID", claims.UserID)
+	c.Set("role", claims.Role)
+	return true
+}
+
+var (
+	errInvalidToken = errors.New("invalid token")
+	errTokenExpired = errors.New("token is expired")
+)
+
+// parseToken verifies the access token tokenValue, including its expiry,
+// against the handler clock rather than the global one of the jwt package
+func (handler *AuthHandler) parseToken(tokenValue string) (*Claims, error) {
+	claims, err := handler.verifyToken(tokenValue)
+	if err != nil {
+		return nil, err
+	}
+	if claims.tokenType() != tokenTypeAccess {
+		return nil, errInvalidToken
+	}
+	if !claims.VerifyExpiresAt(handler.now().Unix(), true) {
+		return nil, errTokenExpired
+	}
+	return claims, nil
+}
+
+// verifyToken is parseToken without the type and expiry checks, for refresh
+// to answer each failure with its own error
+func (handler *AuthHandler) verifyToken(tokenValue string) (*Claims, error) {
+	claims := &Claims{}
+	tkn, err := handler.signer.Parse(tokenValue, claims)
+	if err != nil {
+		return nil, err
+	}
+	if tkn == nil || !tkn.Valid || claims.ExpiresAt == 0 {
+		return nil, errInvalidToken
+	}
+
+	now := handler.now().Unix()
+	if !claims.VerifyNotBefore(now, false) || !claims.VerifyIssuedAt(now, false) {
+		return nil, errInvalidToken
+	}
+	if handler.issuer != "" && !claims.VerifyIssuer(handler.issuer, true) {
+		return nil, errInvalidToken
+	}
+	if len(handler.audiences) > 0 && !containsString(handler.audiences, claims.Audience) {
+		return nil, errInvalidToken
+	}
+	return claims, nil
+}
+
 // swagger:operation POST /refresh auth refresh
-// Refresh token
+// Exchange a refresh token for new tokens, until it expires JWT_REFRESH_TTL after being issued
 // ---
 // produces:
 // - application/json
@@ -114,43 +358,40 @@ func (handler *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 //     '200':
 //         description: Successful operation
 //     '401':
-//         description: Invalid credentials
+//         description: Invalid, access or expired token, or signed in longer than JWT_SESSION_MAX ago
 func (handler *AuthHandler) RefreshHandler(c *gin.Context) {
 	tokenValue := c.GetHeader("Authorization")
-	claims := &Claims{}
-	tkn, err := jwt.ParseWithClaims(tokenValue, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
-
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		return
+	if handler.refreshCookie {
+		if cookie, err := c.Cookie(refreshCookieName); err == nil {
+			tokenValue = cookie
+		}
 	}
-
-	if tkn == nil || !tkn.Valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	claims, err := handler.verifyToken(tokenValue)
+	if err == nil && claims.tokenType() != tokenTypeRefresh {
+		err = errInvalidToken
+	}
+	if err == errInvalidToken {
+		c.JSON(http.StatusUnauthorized, errorBody(c, msgInvalidToken))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	if time.Unix(claims.ExpiresAt, 0).Sub(time.Now()) > 30*time.Second {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is not expired yet"})
+	if !claims.VerifyExpiresAt(handler.now().Unix(), true) {
+		c.JSON(http.StatusUnauthorized, errorBody(c, msgRefreshExpired))
 		return
 	}
 
-	expirationTime := time.Now().Add(5 * time.Minute)
-	claims.ExpiresAt = expirationTime.Unix()
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(os.Getenv("JWT_SECRET"))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Tokens issued before sessions were tracked have no start and cannot be
+	// refreshed, which only asks their users to sign in again
+	sessionEnd := time.Unix(claims.SessionStart, 0).Add(handler.sessionMax)
+	if claims.SessionStart == 0 || !handler.now().Before(sessionEnd) {
+		c.JSON(http.StatusUnauthorized, errorBody(c, msgSessionExpired))
 		return
 	}
 
-	jwtOutput := JWTOutput{
-		Token:   tokenString,
-		Expires: expirationTime,
-	}
-	c.JSON(http.StatusOK, jwtOutput)
+	handler.issueTokens(c, *claims)
 }
 
 // swagger:operation POST /signup auth signup
@@ -160,42 +401,158 @@ func (handler *AuthHandler) RefreshHandler(c *gin.Context) {
 // - application/json
 // responses:
 //     '200':
-//         description: Successful operation
+//         description: Sign up accepted. The user is only returned when SIGNUP_DETAILED_ERRORS is set.
 //     '400':
-//         description: User already exists
+//         description: Invalid username or weak password, or with SIGNUP_DETAILED_ERRORS the user already exists
+//     '403':
+//         description: Sign up is disabled with SIGNUP_ENABLED, or the invite code required by SIGNUP_MODE=invite is missing, used or expired
 //     '500':
 //         description: Internal error
 func (handler *AuthHandler) SignUpHandler(c *gin.Context) {
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !handler.signupEnabled {
+		c.JSON(http.StatusForbidden, errorBody(c, msgSignupDisabled))
 		return
 	}
 
-	cur := handler.collection.FindOne(handler.ctx, bson.M{
-		"username": user.Username,
-	})
+	var input models.SignUpInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if cur.Err() != mongo.ErrNoDocuments {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Username already exists"})
+	user := models.User{
+		Username: normalizeUsername(input.Username),
+		Email:    strings.TrimSpace(input.Email),
+	}
+	if !usernamePattern.MatchString(user.Username) {
+		c.JSON(http.StatusBadRequest, errorBody(c, msgInvalidUsername))
+		return
+	}
+	if handler.emailVerification && user.Email == "" {
+		c.JSON(http.StatusBadRequest, errorBody(c, msgEmailRequired))
 		return
 	}
 
-	h := sha256.New()
-	user.Password = string(h.Sum([]byte(user.Password)))
+	if rule := handler.passwordPolicy.Check(input.Password); rule != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": rule})
+		return
+	}
 
-	_, err := handler.collection.InsertOne(handler.ctx, user)
+	// Hash before looking for duplicates so that both outcomes take as long
+	hash, err := hashPassword(input.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	// The invite is claimed before looking for duplicates, so that an invalid
+	// code does not tell whether the username exists, and released when the
+	// account is not created
+	if handler.inviteOnly {
+		if input.InviteCode == "" {
+			c.JSON(http.StatusForbidden, errorBody(c, msgInviteRequired))
+			return
+		}
+		claimed, err := handler.claimInvite(c.Request.Context(), input.InviteCode, user.Username)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !claimed {
+			c.JSON(http.StatusForbidden, errorBody(c, msgInvalidInvite))
+			return
+		}
+	}
+	created := false
+	defer func() {
+		if handler.inviteOnly && !created {
+			handler.releaseInvite(c.Request.Context(), input.InviteCode, user.Username)
+		}
+	}()
+
+	taken := bson.A{bson.M{"username": usernameTakenFilter(user.Username)}}
+	if user.Email != "" {
+		taken = append(taken, bson.M{"email": user.Email})
+	}
+	var existing models.User
+	err = handler.collection.FindOne(c.Request.Context(), bson.M{"$or": taken}).Decode(&existing)
+	if err == nil {
+		handler.signUpConflict(c, strings.EqualFold(existing.Username, user.Username))
+		return
+	} else if err != mongo.ErrNoDocuments {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	user.ID = primitive.NewObjectID()
+	user.Role = models.RoleUser
+	user.Password = hash
+	var verificationToken string
+	if handler.emailVerification {
+		verificationToken, err = handler.startVerification(&user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	_, err = handler.collection.InsertOne(c.Request.Context(), user)
+	if mongo.IsDuplicateKeyError(err) {
+		handler.signUpConflict(c, true)
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	created = true
+	if handler.emailVerification {
+		handler.sendVerification(c, user, verificationToken)
+	}
+
+	entry := audit.NewEntry(user.Username, audit.UserCreated, user.ID.Hex())
+	if err := handler.audit.Record(c.Request.Context(), entry); err != nil {
+		log.Printf("Warning: could not audit the sign up of %s: %v", user.Username, err)
+	}
+
+	if handler.detailedSignupErrors {
+		c.JSON(http.StatusOK, models.UserProfile{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			Role:     user.Role,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, signUpAccepted)
+}
+
+// signUpAccepted answers every well-formed sign up, whether or not the
+// account could be created, so that existing accounts cannot be discovered
+var signUpAccepted = gin.H{"message": "If the username is available, the account has been created"}
+
+// signUpConflict answers a sign up for a username or email already in use
+func (handler *AuthHandler) signUpConflict(c *gin.Context, usernameTaken bool) {
+	if !handler.detailedSignupErrors {
+		c.JSON(http.StatusOK, signUpAccepted)
+		return
+	}
+	if usernameTaken {
+		c.JSON(http.StatusBadRequest, errorBody(c, msgUsernameTaken))
+		return
+	}
+	c.JSON(http.StatusBadRequest, errorBody(c, msgEmailTaken))
 }
 
-// swagger:operation GET /user/:username auth getUser
-// Gets an user
+// swagger:operation GET /user/{username} auth getUser
+// Gets the public profile of an user, without their email
 // ---
+// parameters:
+// - name: username
+//   in: path
+//   description: username of the user
+//   required: true
+//   type: string
 // produces:
 // - application/json
 // responses:
@@ -204,18 +561,160 @@ func (handler *AuthHandler) SignUpHandler(c *gin.Context) {
 //     '404':
 //         description: User not found
 func (handler *AuthHandler) GetUserHandler(c *gin.Context) {
-	username := c.Param("username")
+	username := normalizeUsername(c.Param("username"))
 
-	findResult := handler.collection.FindOne(c, bson.M{
-		"username": username,
-	})
+	profile, err := handler.lookupProfile(c.Request.Context(), username)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, errorBody(c, msgUserNotFound))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	if findResult.Err() == mongo.ErrNoDocuments {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found!"})
+	c.JSON(http.StatusOK, profile.Public())
+}
+
+// swagger:operation GET /whoami auth whoami
+// Returns the profile of the authenticated user
+// ---
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation
+//     '401':
+//         description: Invalid token
+//     '404':
+//         description: The user was deleted since the token was issued
+func (handler *AuthHandler) WhoAmIHandler(c *gin.Context) {
+	profile, err := handler.lookupProfile(c.Request.Context(), c.GetString("username"))
+	// A user deleted and signed up again under the same name is someone else
+	if err == mongo.ErrNoDocuments || (err == nil && profile.ID != currentUserID(c)) {
+		c.JSON(http.StatusNotFound, errorBody(c, msgUserNotFound))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.JSON(http.StatusOK, profile)
+}
+
+// lookupProfile returns the profile of username, from the cache when it was
+// looked up recently. It returns mongo.ErrNoDocuments for unknown users.
+func (handler *AuthHandler) lookupProfile(ctx context.Context, username string) (models.UserProfile, error) {
+	var profile models.UserProfile
+	key := "user:" + username
+	if val, err := handler.cache.Get(key); err == nil {
+		if json.Unmarshal([]byte(val), &profile) == nil {
+			return profile, nil
+		}
+	} else if err != cache.ErrMiss {
+		log.Printf("Warning: cache unavailable, bypassing it: %v", err)
+	}
+
+	err := handler.collection.FindOne(ctx, bson.M{
+		"username": username,
+	}, options.FindOne().SetProjection(bson.M{"password": 0})).Decode(&profile)
+	if err != nil {
+		return profile, err
+	}
+
+	data, _ := json.Marshal(profile)
+	if err := handler.cache.Set(key, string(data), handler.userCacheTTL); err != nil {
+		log.Printf("Warning: could not write %s to the cache: %v", key, err)
+	}
+	return profile, nil
+}
+
+// invalidateUser drops the cached profile of username. It must be called
+// whenever the user's credentials or account change.
+func (handler *AuthHandler) invalidateUser(username string) {
+	if err := handler.cache.Del("user:" + username); err != nil {
+		log.Printf("Warning: could not invalidate the cached user %s: %v", username, err)
+	}
+}
+
+// findUserToSignIn looks up the user signing in as username. Users who signed
+// up before usernames were lowercased may still be stored with capitals until
+// the lowercase usernames migration is run, so they are looked up as typed
+// when no lowercase user exists.
+func (handler *AuthHandler) findUserToSignIn(ctx context.Context, username string) (models.User, error) {
 	var user models.User
-	findResult.Decode(&user)
-	c.JSON(http.StatusOK, user)
+	typed := strings.TrimSpace(username)
+	err := handler.collection.FindOne(ctx, bson.M{"username": normalizeUsername(typed)}).Decode(&user)
+	if err == mongo.ErrNoDocuments && typed != normalizeUsername(typed) {
+		err = handler.collection.FindOne(ctx, bson.M{"username": typed}).Decode(&user)
+	}
+	return user, err
+}
+
+// usernameTakenFilter matches username whatever its case, so that nobody signs
+// up as "alice" while a user who signed up as "Alice" before usernames were
+// lowercased has not been migrated yet
+func usernameTakenFilter(username string) primitive.Regex {
+	return primitive.Regex{Pattern: "^" + regexp.QuoteMeta(username) + "$", Options: "i"}
+}
+
+// normalizeUsername makes usernames case-insensitive, so "Alice" and "alice"
+// are the same account
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// currentUserID returns the ID of the user authenticated by AuthMiddleware
+func currentUserID(c *gin.Context) primitive.ObjectID {
+	userID, _ := c.Get("userID")
+	id, _ := userID.(primitive.ObjectID)
+	return id
+}
+
+// canView reports whether the caller may see recipe: drafts are only visible
+// to their owner and to admins, private recipes to their owner alone
+func canView(c *gin.Context, recipe models.Recipe) bool {
+	if recipe.IsPublic() {
+		return true
+	}
+	userID := currentUserID(c)
+	if !userID.IsZero() && recipe.UserID == userID {
+		return true
+	}
+	return !recipe.IsPrivate() && isAdmin(c)
+}
+
+// visibleRecipes filters out the recipes the caller may not see
+func visibleRecipes(c *gin.Context, recipes []models.Recipe) []models.Recipe {
+	visible := make([]models.Recipe, 0, len(recipes))
+	for _, recipe := range recipes {
+		if canView(c, recipe) {
+			visible = append(visible, recipe)
+		}
+	}
+	return visible
+}
+
+// isAdmin reports whether the user authenticated by AuthMiddleware is an admin
+func isAdmin(c *gin.Context) bool {
+	return c.GetString("role") == models.RoleAdmin
+}
+
+// AdminMiddleware rejects users that are not admins. It must run after AuthMiddleware.
+func (handler *AuthHandler) AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, errorBody(c, msgAdminRequired))
+			return
+		}
+		c.Next()
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,599 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthMiddlewareAcceptsSignedToken(t *testing.T) {
+	env := newAuthEnv(t, nil, nil)
+	alice := newCaller("alice")
+	env.cacheProfile(t, alice, "alice@example.com")
+
+	rec := env.request(http.MethodGet, "/whoami", nil, env.token(t, alice))
+	expectStatus(t, rec, http.StatusOK)
+}
+
+func TestAuthMiddlewareRejectsNoneAlgorithm(t *testing.T) {
+	env := newAuthEnv(t, nil, nil)
+	alice := newCaller("alice")
+	env.cacheProfile(t, alice, "alice@example.com")
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, &Claims{
+		Username: alice.Username,
+		UserID:   alice.ID,
+		Role:     alice.Role,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: env.clock.Add(time.Minute).Unix(),
+		},
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("signing with none: %v", err)
+	}
+
+	rec := env.request(http.MethodGet, "/whoami", nil, token)
+	expectStatus(t, rec, http.StatusUnauthorized)
+}
+
+// refreshCookieOf returns the refresh cookie set by rec, failing when there is none
+func refreshCookieOf(t *testing.T, rec *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == refreshCookieName {
+			return cookie
+		}
+	}
+	t.Fatalf("no %s cookie in %v", refreshCookieName, rec.Header()["Set-Cookie"])
+	return nil
+}
+
+func TestSignInSetsRefreshCookie(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("cookie attributes", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		env.handler.refreshCookie = true
+		env.handler.refreshTTL = time.Hour
+		hash, _ := hashPassword("correct horse")
+		mt.AddMockResponses(cursorOf(mt, bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "username", Value: "alice"},
+			{Key: "password", Value: hash},
+			{Key: "role", Value: models.RoleUser},
+		}))
+
+		rec := env.request(http.MethodPost, "/signin", gin.H{"username": "alice", "password": "correct horse"}, "")
+		expectStatus(mt.T, rec, http.StatusOK)
+
+		cookie := refreshCookieOf(mt.T, rec)
+		expectRefreshTokenOnlyInCookie(mt.T, env, rec, cookie)
+		if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteStrictMode || cookie.Path != "/refresh" {
+			mt.Errorf("cookie = %+v, want HttpOnly, Secure, SameSite=Strict and Path=/refresh", cookie)
+		}
+		if want := int(time.Hour.Seconds()); cookie.MaxAge != want {
+			mt.Errorf("MaxAge = %d, want %d", cookie.MaxAge, want)
+		}
+	})
+}
+
+func TestRefreshFromCookieOnly(t *testing.T) {
+	env := newAuthEnv(t, nil, nil)
+	env.handler.refreshCookie = true
+	alice := newCaller("alice")
+	refreshToken := env.sign(t, alice, tokenTypeRefresh, env.clock.Add(10*time.Second))
+
+	req := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: refreshCookieName, Value: refreshToken})
+	rec := httptest.NewRecorder()
+	env.router.ServeHTTP(rec, req)
+	expectStatus(t, rec, http.StatusOK)
+
+	expectRefreshTokenOnlyInCookie(t, env, rec, refreshCookieOf(t, rec))
+}
+
+// expectRefreshTokenOnlyInCookie checks that cookie holds a refresh token and
+// that the body of rec has an access token but no refresh token
+func expectRefreshTokenOnlyInCookie(t *testing.T, env *authEnv, rec *httptest.ResponseRecorder, cookie *http.Cookie) {
+	t.Helper()
+	var body map[string]interface{}
+	decodeBody(t, rec, &body)
+	if _, ok := body["refreshToken"]; ok {
+		t.Errorf("body = %v, want no refreshToken in cookie mode", body)
+	}
+	if body["token"] == nil || body["token"] == "" {
+		t.Errorf("body = %v, want an access token", body)
+	}
+	claims := &Claims{}
+	if _, err := env.handler.signer.Parse(cookie.Value, claims); err != nil || claims.Type != tokenTypeRefresh {
+		t.Errorf("the cookie holds a %q token (%v), want a refresh token", claims.Type, err)
+	}
+}
+
+func TestRefreshIgnoresCookieWhenDisabled(t *testing.T) {
+	env := newAuthEnv(t, nil, nil)
+	refreshToken := env.sign(t, newCaller("alice"), tokenTypeRefresh, env.clock.Add(10*time.Second))
+
+	req := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: refreshCookieName, Value: refreshToken})
+	rec := httptest.NewRecorder()
+	env.router.ServeHTTP(rec, req)
+	expectStatus(t, rec, http.StatusUnauthorized)
+}
+
+func userDoc(id primitive.ObjectID, username string, password string) bson.D {
+	hash, _ := hashPassword(password)
+	return bson.D{
+		{Key: "_id", Value: id},
+		{Key: "username", Value: username},
+		{Key: "password", Value: hash},
+		{Key: "role", Value: models.RoleUser},
+	}
+}
+
+func TestSignUpUsernames(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	signUp := func(env *authEnv, username string) *httptest.ResponseRecorder {
+		return env.request(http.MethodPost, "/signup", gin.H{"username": username, "password": "correct horse"}, "")
+	}
+
+	mt.Run("valid", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		env.handler.detailedSignupErrors = true
+		mt.AddMockResponses(cursorOf(mt), mtest.CreateSuccessResponse())
+
+		rec := signUp(env, " Alice_1 ")
+		expectStatus(mt.T, rec, http.StatusOK)
+		var profile models.UserProfile
+		decodeBody(mt.T, rec, &profile)
+		if profile.Username != "alice_1" {
+			mt.Errorf("username = %q, want it trimmed and lowercased", profile.Username)
+		}
+	})
+
+	mt.Run("too short", func(mt *mtest.T) {
+		rec := signUp(newAuthEnv(mt.T, mt.Coll, nil), "ab")
+		expectStatus(mt.T, rec, http.StatusBadRequest)
+	})
+
+	mt.Run("too long", func(mt *mtest.T) {
+		rec := signUp(newAuthEnv(mt.T, mt.Coll, nil), strings.Repeat("a", 31))
+		expectStatus(mt.T, rec, http.StatusBadRequest)
+	})
+
+	mt.Run("illegal characters", func(mt *mtest.T) {
+		for _, username := range []string{"al ice", "alice!", "ali.ce", "álice"} {
+			rec := signUp(newAuthEnv(mt.T, mt.Coll, nil), username)
+			if rec.Code != http.StatusBadRequest {
+				mt.Errorf("%q: status = %d, want 400", username, rec.Code)
+			}
+		}
+	})
+
+	mt.Run("case collision", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		env.handler.detailedSignupErrors = true
+		mt.AddMockResponses(cursorOf(mt, userDoc(primitive.NewObjectID(), "alice", "something else")))
+
+		rec := signUp(env, "ALICE")
+		expectStatus(mt.T, rec, http.StatusBadRequest)
+		if lookup := mt.GetStartedEvent(); !strings.Contains(lookup.Command.String(), `{"pattern":"^alice$","options":"i"}`) {
+			mt.Errorf("the duplicate lookup %s is not by the username in any case", lookup.Command)
+		}
+	})
+
+	mt.Run("legacy capitalized user", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		env.handler.detailedSignupErrors = true
+		mt.AddMockResponses(cursorOf(mt, userDoc(primitive.NewObjectID(), "Alice", "something else")))
+
+		rec := signUp(env, "alice")
+		expectCode(mt.T, rec, http.StatusBadRequest, msgUsernameTaken)
+	})
+}
+
+func TestSignInFallsBackToUsernameAsTyped(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("legacy capitalized user", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		mt.AddMockResponses(
+			cursorOf(mt),
+			cursorOf(mt, userDoc(primitive.NewObjectID(), "Alice", "correct horse")),
+		)
+
+		rec := env.request(http.MethodPost, "/signin", gin.H{"username": "Alice", "password": "correct horse"}, "")
+		expectStatus(mt.T, rec, http.StatusOK)
+	})
+}
+
+func TestSignInFailuresLookAlike(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	signIn := func(mt *mtest.T, responses ...bson.D) *httptest.ResponseRecorder {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		mt.AddMockResponses(responses...)
+		return env.request(http.MethodPost, "/signin", gin.H{"username": "alice", "password": "wrong horse"}, "")
+	}
+
+	var unknown, wrong *httptest.ResponseRecorder
+	mt.Run("unknown user", func(mt *mtest.T) {
+		unknown = signIn(mt, cursorOf(mt))
+	})
+	mt.Run("wrong password", func(mt *mtest.T) {
+		wrong = signIn(mt, cursorOf(mt, userDoc(primitive.NewObjectID(), "alice", "correct horse")))
+	})
+
+	if unknown.Code != http.StatusUnauthorized || wrong.Code != http.StatusUnauthorized {
+		t.Fatalf("statuses = %d, %d, want 401 for both", unknown.Code, wrong.Code)
+	}
+	if unknown.Body.String() != wrong.Body.String() {
+		t.Errorf("an unknown user got %s but a wrong password got %s", unknown.Body, wrong.Body)
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	hash, _ := hashPassword("correct horse")
+	if ok, legacy := checkPassword(hash, "correct horse"); !ok || legacy {
+		t.Errorf("the right password: ok, legacy = %v, %v", ok, legacy)
+	}
+	if ok, _ := checkPassword(hash, "wrong horse"); ok {
+		t.Error("a wrong password matched")
+	}
+	if ok, _ := checkPassword("", ""); ok {
+		t.Error("an unknown user matched an empty password")
+	}
+}
+
+func TestSignUpDoesNotRevealTakenUsernames(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	signUp := func(mt *mtest.T, responses ...bson.D) *httptest.ResponseRecorder {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		mt.AddMockResponses(responses...)
+		return env.request(http.MethodPost, "/signup", gin.H{"username": "alice", "password": "correct horse"}, "")
+	}
+
+	responses := make(map[string]*httptest.ResponseRecorder)
+	mt.Run("available", func(mt *mtest.T) {
+		responses["available"] = signUp(mt, cursorOf(mt), mtest.CreateSuccessResponse())
+	})
+	mt.Run("taken", func(mt *mtest.T) {
+		responses["taken"] = signUp(mt, cursorOf(mt, userDoc(primitive.NewObjectID(), "alice", "something else")))
+	})
+	mt.Run("taken concurrently", func(mt *mtest.T) {
+		responses["taken concurrently"] = signUp(mt, cursorOf(mt), mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index: 0, Code: 11000, Message: "duplicate key error",
+		}))
+	})
+
+	available := responses["available"]
+	expectStatus(t, available, http.StatusOK)
+	for name, rec := range responses {
+		if rec.Code != available.Code || rec.Body.String() != available.Body.String() {
+			t.Errorf("%s: %d %s, want the same answer as an available username: %d %s",
+				name, rec.Code, rec.Body, available.Code, available.Body)
+		}
+	}
+}
+
+func TestGetUserIsCached(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("second lookup and password change", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		alice := newCaller("alice")
+		token := env.token(mt.T, alice)
+		profile := bson.D{{Key: "_id", Value: alice.ID}, {Key: "username", Value: "alice"}, {Key: "role", Value: models.RoleUser}}
+		lookups := func() int {
+			count := 0
+			for _, event := range mt.GetAllStartedEvents() {
+				if event.CommandName == "find" && strings.Contains(event.Command.String(), `"projection": {"password"`) {
+					count++
+				}
+			}
+			return count
+		}
+
+		mt.AddMockResponses(cursorOf(mt, profile))
+		expectStatus(mt.T, env.request(http.MethodGet, "/user/alice", nil, token), http.StatusOK)
+		expectStatus(mt.T, env.request(http.MethodGet, "/user/Alice", nil, token), http.StatusOK)
+		if n := lookups(); n != 1 {
+			mt.Fatalf("%d lookups for two requests, want the second read from the cache", n)
+		}
+
+		// Signing in with a legacy hash replaces it, which changes the password
+		legacy := string(sha256.New().Sum([]byte("correct horse")))
+		mt.AddMockResponses(
+			cursorOf(mt, bson.D{{Key: "_id", Value: alice.ID}, {Key: "username", Value: "alice"}, {Key: "password", Value: legacy}}),
+			mtest.CreateSuccessResponse(),
+		)
+		expectStatus(mt.T, env.request(http.MethodPost, "/signin", gin.H{"username": "alice", "password": "correct horse"}, ""), http.StatusOK)
+
+		mt.AddMockResponses(cursorOf(mt, profile))
+		expectStatus(mt.T, env.request(http.MethodGet, "/user/alice", nil, token), http.StatusOK)
+		if n := lookups(); n != 2 {
+			mt.Errorf("%d lookups, want the password change to bust the cache", n)
+		}
+	})
+}
+
+func TestWhoAmI(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	alice := newCaller("alice")
+
+	mt.Run("profile", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		env.cacheProfile(mt.T, alice, "alice@example.com")
+
+		rec := env.request(http.MethodGet, "/whoami", nil, env.token(mt.T, alice))
+		expectStatus(mt.T, rec, http.StatusOK)
+		var profile models.UserProfile
+		decodeBody(mt.T, rec, &profile)
+		if profile.ID != alice.ID || profile.Username != "alice" || profile.Email != "alice@example.com" || profile.Role != models.RoleUser {
+			mt.Errorf("profile = %+v, want alice with their email", profile)
+		}
+		if strings.Contains(rec.Body.String(), "password") {
+			mt.Errorf("the profile %s contains the password", rec.Body)
+		}
+
+		// Others only see the public profile
+		rec = env.request(http.MethodGet, "/user/alice", nil, env.token(mt.T, newCaller("bob")))
+		expectStatus(mt.T, rec, http.StatusOK)
+		if strings.Contains(rec.Body.String(), "alice@example.com") {
+			mt.Errorf("the public profile %s contains the email", rec.Body)
+		}
+	})
+
+	mt.Run("deleted user", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		mt.AddMockResponses(cursorOf(mt))
+
+		rec := env.request(http.MethodGet, "/whoami", nil, env.token(mt.T, alice))
+		expectStatus(mt.T, rec, http.StatusNotFound)
+	})
+
+	mt.Run("username taken again", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		env.cacheProfile(mt.T, newCaller("alice"), "someone@example.com")
+
+		rec := env.request(http.MethodGet, "/whoami", nil, env.token(mt.T, alice))
+		expectStatus(mt.T, rec, http.StatusNotFound)
+	})
+}
+
+func TestSignUpCannotAssignRole(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("admin role requested", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		env.handler.detailedSignupErrors = true
+		mt.AddMockResponses(cursorOf(mt), mtest.CreateSuccessResponse())
+
+		id := primitive.NewObjectID()
+		rec := env.request(http.MethodPost, "/signup", gin.H{
+			"id": id.Hex(), "username": "mallory", "password": "correct horse", "role": models.RoleAdmin,
+		}, "")
+		expectStatus(mt.T, rec, http.StatusOK)
+		var profile models.UserProfile
+		decodeBody(mt.T, rec, &profile)
+		if profile.Role != models.RoleUser || profile.ID == id {
+			mt.Errorf("profile = %+v, want a new user with the user role", profile)
+		}
+
+		inserted := false
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName != "insert" {
+				continue
+			}
+			inserted = true
+			user := event.Command.Lookup("documents").Array().Index(0).Value().Document()
+			if role := user.Lookup("role").StringValue(); role != models.RoleUser {
+				mt.Errorf("stored role = %q, want %q", role, models.RoleUser)
+			}
+		}
+		if !inserted {
+			mt.Error("the user was not inserted")
+		}
+	})
+
+	mt.Run("malformed payloads", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		for _, body := range []gin.H{
+			{"username": "mallory"},
+			{"password": "correct horse"},
+			{"username": "mallory", "password": "correct horse", "email": "not an email"},
+		} {
+			if rec := env.request(http.MethodPost, "/signup", body, ""); rec.Code != http.StatusBadRequest {
+				mt.Errorf("%v: status = %d, want 400", body, rec.Code)
+			}
+		}
+	})
+}
+
+func TestSignUpFeatureFlag(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	body := gin.H{"username": "alice", "password": "correct horse"}
+
+	mt.Run("enabled", func(mt *mtest.T) {
+		mt.Setenv("SIGNUP_ENABLED", "true")
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		mt.AddMockResponses(cursorOf(mt), mtest.CreateSuccessResponse())
+		expectStatus(mt.T, env.request(http.MethodPost, "/signup", body, ""), http.StatusOK)
+	})
+
+	mt.Run("disabled", func(mt *mtest.T) {
+		mt.Setenv("SIGNUP_ENABLED", "false")
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		rec := env.request(http.MethodPost, "/signup", body, "")
+		expectStatus(mt.T, rec, http.StatusForbidden)
+		var answer map[string]string
+		decodeBody(mt.T, rec, &answer)
+		if answer["code"] != msgSignupDisabled {
+			mt.Errorf("body = %v, want %s", answer, msgSignupDisabled)
+		}
+		if events := mt.GetAllStartedEvents(); len(events) != 0 {
+			mt.Errorf("%d commands were sent with sign up disabled", len(events))
+		}
+
+		// Existing users can still sign in
+		mt.AddMockResponses(cursorOf(mt, userDoc(primitive.NewObjectID(), "bob", "correct horse")))
+		expectStatus(mt.T, env.request(http.MethodPost, "/signin", gin.H{"username": "bob", "password": "correct horse"}, ""), http.StatusOK)
+	})
+}
+
+func TestAccessTokenExpiryFollowsTheClock(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("sign in then wait", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		alice := newCaller("alice")
+		env.cacheProfile(mt.T, alice, "")
+		mt.AddMockResponses(cursorOf(mt, userDoc(alice.ID, "alice", "correct horse")))
+		rec := env.request(http.MethodPost, "/signin", gin.H{"username": "alice", "password": "correct horse"}, "")
+		expectStatus(mt.T, rec, http.StatusOK)
+		var output JWTOutput
+		decodeBody(mt.T, rec, &output)
+		signedIn := env.clock
+		if !output.Expires.Equal(signedIn.Add(10 * time.Minute)) {
+			mt.Errorf("expires = %v, want ten minutes after %v", output.Expires, signedIn)
+		}
+
+		tests := []struct {
+			after time.Duration
+			want  int
+		}{
+			{0, http.StatusOK},
+			{9*time.Minute + 59*time.Second, http.StatusOK},
+			{10*time.Minute + time.Second, http.StatusUnauthorized},
+			{time.Hour, http.StatusUnauthorized},
+		}
+		for _, test := range tests {
+			env.clock = signedIn.Add(test.after)
+			if rec := env.request(http.MethodGet, "/whoami", nil, output.Token); rec.Code != test.want {
+				mt.Errorf("%v after signing in: status = %d, want %d", test.after, rec.Code, test.want)
+			}
+		}
+	})
+}
+
+func TestAuthMiddlewareRejectsTokensIssuedInTheFuture(t *testing.T) {
+	env := newAuthEnv(t, nil, nil)
+	alice := newCaller("alice")
+	env.cacheProfile(t, alice, "")
+	now := env.clock
+	env.clock = now.Add(time.Hour)
+	token := env.token(t, alice)
+	env.clock = now
+
+	expectStatus(t, env.request(http.MethodGet, "/whoami", nil, token), http.StatusUnauthorized)
+}
+
+func TestSignInStampsIssuerAndAudience(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("claims", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		env.handler.issuer = "recipes-api"
+		env.handler.audience = "web"
+		env.handler.audiences = []string{"web"}
+		mt.AddMockResponses(cursorOf(mt, userDoc(primitive.NewObjectID(), "alice", "correct horse")))
+
+		rec := env.request(http.MethodPost, "/signin", gin.H{"username": "alice", "password": "correct horse"}, "")
+		expectStatus(mt.T, rec, http.StatusOK)
+		var output JWTOutput
+		decodeBody(mt.T, rec, &output)
+		claims := &Claims{}
+		if _, err := env.handler.signer.Parse(output.Token, claims); err != nil {
+			mt.Fatalf("parsing the token: %v", err)
+		}
+		if claims.Issuer != "recipes-api" || claims.Audience != "web" {
+			mt.Errorf("iss, aud = %q, %q, want recipes-api, web", claims.Issuer, claims.Audience)
+		}
+	})
+}
+
+func TestAuthMiddlewareChecksIssuerAndAudience(t *testing.T) {
+	tests := []struct {
+		name     string
+		issuer   string
+		audience string
+		status   int
+	}{
+		{"matching", "recipes-api", "web", http.StatusOK},
+		{"another allowed audience", "recipes-api", "mobile", http.StatusOK},
+		{"another issuer", "someone-else", "web", http.StatusUnauthorized},
+		{"no issuer", "", "web", http.StatusUnauthorized},
+		{"another audience", "recipes-api", "billing", http.StatusUnauthorized},
+		{"no audience", "recipes-api", "", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := newAuthEnv(t, nil, nil)
+			env.handler.issuer = "recipes-api"
+			env.handler.audiences = []string{"web", "mobile"}
+			alice := newCaller("alice")
+			env.cacheProfile(t, alice, "alice@example.com")
+
+			token, err := env.handler.signer.Sign(&Claims{
+				Username:     alice.Username,
+				UserID:       alice.ID,
+				Role:         alice.Role,
+				SessionStart: env.clock.Unix(),
+				Type:         tokenTypeAccess,
+				StandardClaims: jwt.StandardClaims{
+					ExpiresAt: env.clock.Add(time.Minute).Unix(),
+					IssuedAt:  env.clock.Unix(),
+					Issuer:    test.issuer,
+					Audience:  test.audience,
+				},
+			})
+			if err != nil {
+				t.Fatalf("signing a token: %v", err)
+			}
+			rec := env.request(http.MethodGet, "/whoami", nil, token)
+			expectStatus(t, rec, test.status)
+		})
+	}
+}
+
+func TestLoadAudiences(t *testing.T) {
+	t.Setenv("JWT_ALLOWED_AUDIENCES", "")
+	t.Setenv("JWT_AUDIENCE", "")
+	if audiences := loadAudiences(); audiences != nil {
+		t.Errorf("audiences = %v, want none", audiences)
+	}
+
+	t.Setenv("JWT_AUDIENCE", "web")
+	if audiences := loadAudiences(); len(audiences) != 1 || audiences[0] != "web" {
+		t.Errorf("audiences = %v, want [web]", audiences)
+	}
+
+	t.Setenv("JWT_ALLOWED_AUDIENCES", "web, mobile")
+	if audiences := loadAudiences(); len(audiences) != 2 || audiences[0] != "web" || audiences[1] != "mobile" {
+		t.Errorf("audiences = %v, want [web mobile]", audiences)
+	}
+}
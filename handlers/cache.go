@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// swagger:operation POST /admin/cache/warm admin warmCache
+// Reload the cached recipe list and tags from MongoDB, after a deploy or a manual change to the database. Admin only
+// ---
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation, returns the number of recipes and tags cached
+//     '403':
+//         description: Caller is not an admin
+func (handler *RecipesHandler) WarmCacheHandler(c *gin.Context) {
+	recipes, err := handler.store.List(c.Request.Context(), store.ListFilter{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tags, err := handler.store.TagCounts(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, _ := json.Marshal(recipes)
+	handler.setCached("recipes", string(data), 0)
+	data, _ = json.Marshal(tags)
+	handler.setCached("tags", string(data), tagsCacheTTL)
+
+	log.Printf("Warmed the cache with %d recipes and %d tags", len(recipes), len(tags))
+	c.JSON(http.StatusOK, gin.H{"recipes": len(recipes), "tags": len(tags)})
+}
+
+// swagger:operation POST /admin/cache/flush admin flushCache
+// Remove the cached recipe list, tags, recent recipes and stats. Admin only
+// ---
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation
+//     '403':
+//         description: Caller is not an admin
+func (handler *RecipesHandler) FlushCacheHandler(c *gin.Context) {
+	if err := handler.cache.Del(flushableKeys()...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	handler.expireSearches()
+	log.Println("Flushed the cache")
+	c.JSON(http.StatusOK, gin.H{"message": "Cache flushed"})
+}
+
+// flushableKeys returns every key the recipe listings may be cached under.
+// Recent recipes and stats are keyed by a limit of at most maxPageSize.
+// Searches are expired by moving on to a new generation instead, their keys
+// holding whatever parameters were searched for.
+func flushableKeys() []string {
+	keys := append([]string{"recipes", "tags"}, recentKeys()...)
+	for limit := 1; limit <= maxPageSize; limit++ {
+		keys = append(keys, fmt.Sprintf("stats:%d", limit))
+	}
+	return keys
+}
+
+// recentKeys returns every key GET /recipes/recent may be cached under, one
+// per limit
+func recentKeys() []string {
+	keys := make([]string, 0, maxPageSize)
+	for limit := 1; limit <= maxPageSize; limit++ {
+		keys = append(keys, "recent:"+strconv.Itoa(limit))
+	}
+	return keys
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestWarmCache(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	for _, name := range []string{"Pancakes", "Waffles"} {
+		recipe := publishedRecipe(name, owner)
+		recipe.Tags = []string{"breakfast"}
+		env.seed(t, recipe)
+	}
+
+	rec := env.request(http.MethodPost, "/admin/cache/warm", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var counts map[string]int
+	decodeBody(t, rec, &counts)
+	if counts["recipes"] != 2 || counts["tags"] != 1 {
+		t.Errorf("counts = %v, want 2 recipes and 1 tag", counts)
+	}
+
+	cached, err := env.redis.Get("recipes")
+	if err != nil {
+		t.Fatalf("the recipes were not cached: %v", err)
+	}
+	var recipes []models.Recipe
+	json.Unmarshal([]byte(cached), &recipes)
+	if len(recipes) != 2 {
+		t.Errorf("%d recipes cached, want 2", len(recipes))
+	}
+	if !env.redis.Exists("tags") {
+		t.Error("the tags were not cached")
+	}
+}
+
+func TestFlushCache(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	first := publishedRecipe("Pancakes", owner)
+	first.Tags = []string{"breakfast"}
+	env.seed(t, first)
+	for _, target := range []string{"/recipes", "/recipes/tags", "/recipes/recent?limit=5", "/recipes/stats", "/recipes/search?tag=breakfast"} {
+		expectStatus(t, env.request(http.MethodGet, target, nil), http.StatusOK)
+	}
+
+	// Changed behind the API's back, as by a manual edit of the database
+	second := publishedRecipe("Waffles", owner)
+	second.Tags = []string{"breakfast"}
+	env.seed(t, second)
+	if names := namesOf(t, env.request(http.MethodGet, "/recipes", nil)); len(names) != 1 {
+		t.Fatalf("recipes = %v, want the stale cached list", names)
+	}
+
+	keys := []string{"recipes", "tags", "recent:5", "stats:10"}
+	for _, key := range keys {
+		if !env.redis.Exists(key) {
+			t.Fatalf("%s was not cached before the flush", key)
+		}
+	}
+	expectStatus(t, env.request(http.MethodPost, "/admin/cache/flush", nil), http.StatusOK)
+	for _, key := range keys {
+		if env.redis.Exists(key) {
+			t.Errorf("%s is still cached", key)
+		}
+	}
+	want := []string{"Pancakes", "Waffles"}
+	for _, target := range []string{"/recipes?sort=name", "/recipes/search?tag=breakfast"} {
+		if names := namesOf(t, env.request(http.MethodGet, target, nil)); len(names) != 2 {
+			t.Errorf("GET %s = %v after the flush, want %v", target, names, want)
+		}
+	}
+	var tags []models.TagCount
+	decodeBody(t, env.request(http.MethodGet, "/recipes/tags", nil), &tags)
+	if !reflect.DeepEqual(tags, []models.TagCount{{Tag: "breakfast", Count: 2}}) {
+		t.Errorf("tags = %v, want breakfast twice", tags)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strconv"
+	"time"
+)
+
+// pageCursor records the sort values of the last recipe of a page. The next
+// page starts right after it in the sort order, so recipes deleted or created
+// between requests do not shift the following pages the way offsets do.
+type pageCursor struct {
+	Sort        string    `json:"s"`
+	Name        string    `json:"n"`
+	PublishedAt time.Time `json:"p"`
+	ID          string    `json:"id"`
+}
+
+func newCursor(recipe models.Recipe, sort string) pageCursor {
+	return pageCursor{
+		Sort:        sort,
+		Name:        recipe.Name,
+		PublishedAt: recipe.PublishedAt,
+		ID:          recipe.ID.Hex(),
+	}
+}
+
+// parseCursor decodes a cursor returned as nextCursor. An empty value starts
+// from the first recipe.
+func parseCursor(value, sort string) (pageCursor, error) {
+	if value == "" {
+		return pageCursor{Sort: sort}, nil
+	}
+	invalid := errors.New("cursor is invalid")
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return pageCursor{}, invalid
+	}
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return pageCursor{}, invalid
+	}
+	if _, err := primitive.ObjectIDFromHex(cursor.ID); err != nil {
+		return pageCursor{}, invalid
+	}
+	if cursor.Sort != sort {
+		return pageCursor{}, fmt.Errorf("cursor was issued for sort %s, not %s", cursor.Sort, sort)
+	}
+	return cursor, nil
+}
+
+func (cursor pageCursor) encode() string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// first reports whether the cursor asks for the first page
+func (cursor pageCursor) first() bool {
+	return cursor.ID == ""
+}
+
+// recipe returns a recipe holding the sort values of the cursor, to be
+// compared with recipeLess
+func (cursor pageCursor) recipe() models.Recipe {
+	id, _ := primitive.ObjectIDFromHex(cursor.ID)
+	return models.Recipe{ID: id, Name: cursor.Name, PublishedAt: cursor.PublishedAt}
+}
+
+// setCursorHeaders sets X-Total-Count, X-Next-Cursor and a Link header to the
+// next page of a cursor listing
+func setCursorHeaders(c *gin.Context, page RecipesPage) {
+	c.Header("X-Total-Count", strconv.Itoa(page.Total))
+	if page.NextCursor == "" {
+		return
+	}
+	c.Header("X-Next-Cursor", page.NextCursor)
+
+	u := *c.Request.URL
+	query := u.Query()
+	query.Del("page")
+	query.Set("cursor", page.NextCursor)
+	query.Set("limit", strconv.Itoa(page.Limit))
+	u.RawQuery = query.Encode()
+	c.Header("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+}
@@ -0,0 +1,457 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/middleware"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRelatedLimit  = 5
+	defaultSimilarLimit  = 5
+	defaultRecentLimit   = 10
+	defaultTrendingLimit = 10
+)
+
+// defaultTrendingWindow is how far back views count towards trending when
+// the client does not pass window
+const defaultTrendingWindow = 24 * time.Hour
+
+// maxTrendingFetch bounds the number of most viewed recipes read to fill a
+// page of GET /recipes/trending once drafts and deleted recipes are dropped
+const maxTrendingFetch = 1000
+
+// similarCandidateLimit bounds the number of recipes scored in Go by GET /recipes/{id}/similar
+const similarCandidateLimit = 500
+
+// recentCacheTTL bounds how long the recent feed may lag behind new recipes
+const recentCacheTTL = 30 * time.Second
+
+// swagger:operation GET /recipes/random recipes randomRecipe
+// Returns a random published recipe, for when you don't know what to cook
+// ---
+// parameters:
+// - name: tag
+//   in: query
+//   description: only pick recipes having this tag, may be repeated
+//   required: false
+//   type: string
+// - name: difficulty
+//   in: query
+//   description: only pick recipes of this difficulty, easy, medium or hard
+//   required: false
+//   type: string
+// - name: maxTime
+//   in: query
+//   description: only pick recipes taking at most this many minutes
+//   required: false
+//   type: integer
+// produces:
+// - application/json
+// - application/yaml
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid difficulty or maxTime
+//     '404':
+//         description: No recipe matches the filter
+func (handler *RecipesHandler) RandomRecipeHandler(c *gin.Context) {
+	criteria := store.RandomCriteria{
+		Tags:       normalizeTags(c.QueryArray("tag")),
+		Difficulty: strings.ToLower(c.Query("difficulty")),
+	}
+	if !validDifficulty(criteria.Difficulty) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "difficulty must be easy, medium or hard"})
+		return
+	}
+	if value := c.Query("maxTime"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "maxTime must be a positive number of minutes"})
+			return
+		}
+		criteria.MaxTime = parsed
+	}
+
+	recipe, err := handler.store.Random(c.Request.Context(), criteria)
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgNoRecipeMatches))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	render(c, http.StatusOK, localize(c, recipe))
+}
+
+// swagger:operation GET /recipes/{id}/related recipes relatedRecipes
+// Returns the recipes sharing the most tags with the given recipe
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the recipe
+//   required: true
+//   type: string
+// - name: limit
+//   in: query
+//   description: maximum number of recipes returned, defaults to 5
+//   required: false
+//   type: integer
+// produces:
+// - application/json
+// - application/yaml
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid limit
+//     '404':
+//         description: Invalid recipe ID
+func (handler *RecipesHandler) RelatedRecipesHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	limit := defaultRelatedLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = minInt(parsed, maxPageSize)
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorBody(c, msgInvalidRecipeID))
+		return
+	}
+
+	recipe, err := handler.store.GetByID(c.Request.Context(), objectId)
+	if err == nil && !canView(c, recipe) {
+		err = store.ErrNotFound
+	}
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgRecipeNotFound, id))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	related, err := handler.store.Related(c.Request.Context(), recipe, int64(limit))
+	if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	render(c, http.StatusOK, localizeAll(c, related))
+}
+
+// swagger:operation GET /recipes/{id}/similar recipes similarRecipes
+// Returns the recipes whose ingredients are the most similar to those of the
+// given recipe, by Jaccard similarity of the ingredient names
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the recipe
+//   required: true
+//   type: string
+// - name: limit
+//   in: query
+//   description: maximum number of recipes returned, defaults to 5
+//   required: false
+//   type: integer
+// produces:
+// - application/json
+// - application/yaml
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid limit
+//     '404':
+//         description: Invalid recipe ID
+func (handler *RecipesHandler) SimilarRecipesHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	limit := defaultSimilarLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = minInt(parsed, maxPageSize)
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorBody(c, msgInvalidRecipeID))
+		return
+	}
+
+	recipe, err := handler.store.GetByID(c.Request.Context(), objectId)
+	if err == nil && !canView(c, recipe) {
+		err = store.ErrNotFound
+	}
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgRecipeNotFound, id))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	similar := make([]models.SimilarRecipe, 0)
+	wanted := ingredientNames(recipe.Ingredients)
+	if len(wanted) == 0 {
+		render(c, http.StatusOK, similar)
+		return
+	}
+
+	// Candidates share at least one ingredient name, as a substring, and are
+	// scored on the exact names. Hidden recipes are left out by the query so
+	// that they do not take the place of visible ones under the limit.
+	names := make([]string, 0, len(wanted))
+	for name := range wanted {
+		names = append(names, name)
+	}
+	candidates, err := handler.store.Search(c.Request.Context(), store.SearchCriteria{
+		Ingredients: names,
+		PublicOnly:  true,
+		OwnedBy:     currentUserID(c),
+		Limit:       similarCandidateLimit,
+	})
+	if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, candidate := range candidates {
+		if candidate.ID == recipe.ID {
+			continue
+		}
+		if score := jaccard(wanted, ingredientNames(candidate.Ingredients)); score > 0 {
+			similar = append(similar, models.SimilarRecipe{Recipe: candidate, Similarity: score})
+		}
+	}
+	sort.Slice(similar, func(i, j int) bool {
+		a, b := similar[i], similar[j]
+		if a.Similarity != b.Similarity {
+			return a.Similarity > b.Similarity
+		}
+		if !a.Recipe.PublishedAt.Equal(b.Recipe.PublishedAt) {
+			return a.Recipe.PublishedAt.After(b.Recipe.PublishedAt)
+		}
+		return a.Recipe.ID.Hex() > b.Recipe.ID.Hex()
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	for i := range similar {
+		similar[i].Recipe = localize(c, similar[i].Recipe)
+	}
+
+	render(c, http.StatusOK, similar)
+}
+
+// ingredientNames returns the set of ingredient names of the lines, without
+// their quantities and units
+func ingredientNames(lines []string) map[string]bool {
+	names := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		if name := models.ParseIngredient(line).Name; name != "" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// jaccard returns the size of the intersection of a and b over the size of their union
+func jaccard(a, b map[string]bool) float64 {
+	shared := 0
+	for name := range a {
+		if b[name] {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// swagger:operation GET /recipes/recent recipes recentRecipes
+// Returns the most recently published recipes, for a homepage feed
+// ---
+// parameters:
+// - name: limit
+//   in: query
+//   description: number of recipes returned, defaults to 10
+//   required: false
+//   type: integer
+// produces:
+// - application/json
+// - application/yaml
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid limit
+func (handler *RecipesHandler) RecentRecipesHandler(c *gin.Context) {
+	limit := defaultRecentLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = minInt(parsed, maxPageSize)
+	}
+
+	key := "recent:" + strconv.Itoa(limit)
+	if val, ok := handler.getCached(key); ok {
+		log.Printf("Request to Redis")
+		recipes := make([]models.Recipe, 0)
+		json.Unmarshal([]byte(val), &recipes)
+		render(c, http.StatusOK, localizeAll(c, recipes))
+		return
+	}
+
+	log.Printf("Request to MongoDB")
+	recipes, err := handler.store.Recent(c.Request.Context(), int64(limit))
+	if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, _ := json.Marshal(recipes)
+	handler.setCached(key, string(data), recentCacheTTL)
+	render(c, http.StatusOK, localizeAll(c, recipes))
+}
+
+// swagger:operation GET /recipes/trending recipes trendingRecipes
+// Returns the recipes viewed the most recently, with their number of views
+// ---
+// parameters:
+// - name: window
+//   in: query
+//   description: how far back views are counted, such as 6h, defaults to 24h
+//   required: false
+//   type: string
+// - name: limit
+//   in: query
+//   description: number of recipes returned, defaults to 10
+//   required: false
+//   type: integer
+// produces:
+// - application/json
+// - application/yaml
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid window or limit
+func (handler *RecipesHandler) TrendingRecipesHandler(c *gin.Context) {
+	limit := defaultTrendingLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = minInt(parsed, maxPageSize)
+	}
+	window := defaultTrendingWindow
+	if value := c.Query("window"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "window must be a positive duration such as 24h"})
+			return
+		}
+		window = parsed
+	}
+
+	trending, err := handler.trendingRecipes(c, window, limit)
+	if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	render(c, http.StatusOK, trending)
+}
+
+// trendingRecipes returns up to limit public recipes with the most views
+// within window. Deleted recipes and drafts may still have views in the
+// window, so twice as many recipes as are missing are read again until the
+// page is full or every viewed recipe has been read.
+func (handler *RecipesHandler) trendingRecipes(c *gin.Context, window time.Duration, limit int) ([]models.TrendingRecipe, error) {
+	fetch := limit
+	for {
+		counts, err := handler.views.Trending(window, int64(fetch))
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]primitive.ObjectID, len(counts))
+		for i, count := range counts {
+			ids[i] = count.RecipeID
+		}
+		recipes, err := handler.store.List(c.Request.Context(), store.ListFilter{IDs: ids})
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[primitive.ObjectID]models.Recipe, len(recipes))
+		for _, recipe := range recipes {
+			byID[recipe.ID] = recipe
+		}
+
+		trending := make([]models.TrendingRecipe, 0, limit)
+		for _, count := range counts {
+			if recipe, ok := byID[count.RecipeID]; ok && recipe.IsPublic() {
+				trending = append(trending, models.TrendingRecipe{Recipe: localize(c, recipe), Views: count.Views})
+			}
+			if len(trending) == limit {
+				break
+			}
+		}
+		if len(trending) == limit || len(counts) < fetch || fetch >= maxTrendingFetch {
+			return trending, nil
+		}
+		fetch = minInt(fetch+2*(limit-len(trending)), maxTrendingFetch)
+	}
+}
+
+// countView counts a view of the recipe by the authenticated user, or by the
+// client IP for anonymous requests
+func (handler *RecipesHandler) countView(c *gin.Context, recipe models.Recipe) {
+	viewer := "ip:" + middleware.ClientIP(c)
+	if userID := currentUserID(c); !userID.IsZero() {
+		viewer = "user:" + userID.Hex()
+	}
+	if err := handler.views.Record(recipe.ID, viewer); err != nil {
+		log.Printf("Warning: could not count a view of recipe %s: %v", recipe.ID.Hex(), err)
+	}
+}
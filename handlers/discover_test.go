@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/views"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRandomRecipeMatchesFilter(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	seed := func(name string, tag string, difficulty string, minutes int, status string) models.Recipe {
+		recipe := publishedRecipe(name, owner)
+		recipe.Tags = []string{tag}
+		recipe.Difficulty = difficulty
+		recipe.TotalTime = minutes
+		recipe.Status = status
+		return env.seed(t, recipe)
+	}
+	carbonara := seed("Carbonara", "italian", models.DifficultyEasy, 20, models.StatusPublished)
+	lasagna := seed("Lasagna", "italian", models.DifficultyHard, 90, models.StatusPublished)
+	seed("Risotto", "italian", models.DifficultyEasy, 25, models.StatusDraft)
+	seed("Ratatouille", "french", models.DifficultyEasy, 20, models.StatusPublished)
+
+	random := func(query string) models.Recipe {
+		t.Helper()
+		rec := env.request(http.MethodGet, "/recipes/random?"+query, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var recipe models.Recipe
+		decodeBody(t, rec, &recipe)
+		return recipe
+	}
+
+	picked := make(map[string]bool)
+	for i := 0; i < 30; i++ {
+		recipe := random("tag=Italian")
+		if recipe.ID != carbonara.ID && recipe.ID != lasagna.ID {
+			t.Fatalf("tag=Italian picked %q", recipe.Name)
+		}
+		picked[recipe.Name] = true
+	}
+	if len(picked) != 2 {
+		t.Errorf("30 picks only returned %v", picked)
+	}
+
+	for i := 0; i < 10; i++ {
+		if recipe := random("tag=italian&difficulty=EASY&maxTime=30"); recipe.ID != carbonara.ID {
+			t.Fatalf("an easy italian recipe within 30 minutes picked %q", recipe.Name)
+		}
+	}
+
+	expectStatus(t, env.request(http.MethodGet, "/recipes/random?tag=japanese", nil), http.StatusNotFound)
+	expectStatus(t, env.request(http.MethodGet, "/recipes/random?tag=italian&difficulty=hard&maxTime=60", nil), http.StatusNotFound)
+}
+
+func TestRandomRecipeRejectsInvalidFilters(t *testing.T) {
+	env := memoryEnv(t)
+	for _, query := range []string{"difficulty=extreme", "maxTime=0", "maxTime=soon"} {
+		rec := env.request(http.MethodGet, "/recipes/random?"+query, nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestRelatedRecipesRankBySharedTags(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	published := time.Now().UTC().Truncate(time.Millisecond)
+	seed := func(name string, age time.Duration, tags ...string) models.Recipe {
+		recipe := publishedRecipe(name, owner)
+		recipe.Tags = tags
+		recipe.PublishedAt = published.Add(-age)
+		return env.seed(t, recipe)
+	}
+	pizza := seed("Pizza", 0, "italian", "baked", "cheese")
+	seed("Lasagna", time.Hour, "italian", "baked", "cheese")
+	seed("Calzone", 2*time.Hour, "italian", "baked")
+	seed("Focaccia", time.Minute, "italian")
+	seed("Tiramisu", 3*time.Hour, "italian")
+	seed("Sushi", 0, "japanese")
+	draft := publishedRecipe("Stromboli", owner)
+	draft.Tags = []string{"italian", "baked", "cheese"}
+	draft.Status = models.StatusDraft
+	env.seed(t, draft)
+
+	rec := env.request(http.MethodGet, "/recipes/"+pizza.ID.Hex()+"/related", nil)
+	expectStatus(t, rec, http.StatusOK)
+	want := []string{"Lasagna", "Calzone", "Focaccia", "Tiramisu"}
+	if names := namesOf(t, rec); !reflect.DeepEqual(names, want) {
+		t.Errorf("related = %v, want %v", names, want)
+	}
+
+	rec = env.request(http.MethodGet, "/recipes/"+pizza.ID.Hex()+"/related?limit=2", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if names := namesOf(t, rec); !reflect.DeepEqual(names, want[:2]) {
+		t.Errorf("related with limit=2 = %v, want %v", names, want[:2])
+	}
+	expectStatus(t, env.request(http.MethodGet, "/recipes/"+pizza.ID.Hex()+"/related?limit=0", nil), http.StatusBadRequest)
+}
+
+func TestSimilarRecipesRankBySharedIngredients(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	seed := func(name string, status string, ingredients ...string) models.Recipe {
+		recipe := publishedRecipe(name, owner)
+		recipe.Ingredients = ingredients
+		recipe.Status = status
+		return env.seed(t, recipe)
+	}
+	pancakes := seed("Pancakes", models.StatusPublished, "200 g flour", "2 eggs", "300 ml milk", "1 tbsp sugar")
+	seed("Crepes", models.StatusPublished, "flour", "eggs", "milk", "butter")
+	seed("Bread", models.StatusPublished, "flour", "water", "yeast", "salt")
+	seed("Omelette", models.StatusPublished, "eggs", "milk", "cheese", "ham", "salt")
+	seed("Salad", models.StatusPublished, "lettuce", "tomato")
+	seed("Waffles", models.StatusDraft, "flour", "eggs", "milk", "sugar")
+
+	rec := env.request(http.MethodGet, "/recipes/"+pancakes.ID.Hex()+"/similar", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var similar []models.SimilarRecipe
+	decodeBody(t, rec, &similar)
+	var names []string
+	for i, entry := range similar {
+		names = append(names, entry.Recipe.Name)
+		if i > 0 && entry.Similarity > similar[i-1].Similarity {
+			t.Errorf("%s scores %v, more than %s before it", entry.Recipe.Name, entry.Similarity, similar[i-1].Recipe.Name)
+		}
+	}
+	// Crepes share 3 of 5 ingredients, Omelette 2 of 7 and Bread 1 of 7, the
+	// draft Waffles being left out despite sharing all of them
+	if want := []string{"Crepes", "Omelette", "Bread"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("similar = %v, want %v", names, want)
+	}
+	if len(similar) > 0 && similar[0].Similarity != 0.6 {
+		t.Errorf("Crepes similarity = %v, want 0.6", similar[0].Similarity)
+	}
+
+	rec = env.request(http.MethodGet, "/recipes/"+pancakes.ID.Hex()+"/similar?limit=1", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decodeBody(t, rec, &similar)
+	if len(similar) != 1 || similar[0].Recipe.Name != "Crepes" {
+		t.Errorf("similar with limit=1 = %+v, want Crepes alone", similar)
+	}
+}
+
+func TestRecentRecipesNewestFirstWithoutDrafts(t *testing.T) {
+	env := memoryEnv(t)
+	alice := newCaller("alice")
+	published := time.Now().UTC().Add(-24 * time.Hour).Truncate(time.Millisecond)
+	for i, name := range []string{"Oldest", "Older", "Newer", "Newest"} {
+		recipe := publishedRecipe(name, alice.ID)
+		recipe.PublishedAt = published.Add(time.Duration(i) * time.Hour)
+		env.seed(t, recipe)
+	}
+	draft := publishedRecipe("Draft", alice.ID)
+	draft.Status = models.StatusDraft
+	draft.PublishedAt = published.Add(24 * time.Hour)
+	env.seed(t, draft)
+	private := publishedRecipe("Private", alice.ID)
+	private.Visibility = models.VisibilityPrivate
+	private.PublishedAt = published.Add(24 * time.Hour)
+	env.seed(t, private)
+
+	rec := env.as(alice).request(http.MethodGet, "/recipes/recent?limit=3", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if names, want := namesOf(t, rec), []string{"Newest", "Newer", "Older"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("recent = %v, want %v", names, want)
+	}
+
+	rec = env.request(http.MethodGet, "/recipes/recent", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if names, want := namesOf(t, rec), []string{"Newest", "Newer", "Older", "Oldest"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("recent = %v, want %v", names, want)
+	}
+	expectStatus(t, env.request(http.MethodGet, "/recipes/recent?limit=-1", nil), http.StatusBadRequest)
+
+	// Publishing and hiding recipes expire the cached feeds
+	expectStatus(t, env.as(alice).request(http.MethodPost, "/recipes/"+draft.ID.Hex()+"/publish", nil), http.StatusOK)
+	rec = env.request(http.MethodGet, "/recipes/recent?limit=3", nil)
+	if names, want := namesOf(t, rec), []string{"Draft", "Newest", "Newer"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("recent after publishing = %v, want %v", names, want)
+	}
+	target := "/recipes/" + draft.ID.Hex() + "/visibility"
+	expectStatus(t, env.as(alice).request(http.MethodPut, target, gin.H{"visibility": models.VisibilityPrivate}), http.StatusOK)
+	rec = env.request(http.MethodGet, "/recipes/recent?limit=3", nil)
+	if names, want := namesOf(t, rec), []string{"Newest", "Newer", "Older"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("recent after hiding = %v, want %v", names, want)
+	}
+}
+
+func TestTrendingRecipesOrderedByRecentViews(t *testing.T) {
+	env := memoryEnv(t)
+	client := redis.NewClient(&redis.Options{Addr: env.redis.Addr()})
+	defer client.Close()
+	env.handler.views = views.NewRedisCounter(client, "", time.Hour, 24*time.Hour)
+
+	alice := newCaller("alice")
+	view := func(recipe models.Recipe, viewers ...string) {
+		for _, ip := range viewers {
+			req := httptest.NewRequest(http.MethodGet, "/recipes/"+recipe.ID.Hex(), nil)
+			req.RemoteAddr = ip + ":1234"
+			expectStatus(t, env.serve(req), http.StatusOK)
+		}
+	}
+	popular := env.seed(t, publishedRecipe("Popular", alice.ID))
+	liked := env.seed(t, publishedRecipe("Liked", alice.ID))
+	ignored := env.seed(t, publishedRecipe("Ignored", alice.ID))
+	hidden := env.seed(t, publishedRecipe("Hidden", alice.ID))
+	view(popular, "192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.3")
+	view(liked, "192.0.2.1", "192.0.2.2")
+	view(ignored)
+	view(hidden, "192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4", "192.0.2.5")
+	// A recipe unpublished after being viewed is not trending anymore
+	hidden.Status = models.StatusDraft
+	env.store.Delete(context.Background(), hidden.ID)
+	env.seed(t, hidden)
+
+	rec := env.request(http.MethodGet, "/recipes/trending?window=1h", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var trending []models.TrendingRecipe
+	decodeBody(t, rec, &trending)
+	got := make([]string, 0)
+	for _, recipe := range trending {
+		got = append(got, fmt.Sprintf("%s:%d", recipe.Recipe.Name, recipe.Views))
+	}
+	if want := []string{"Popular:3", "Liked:2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("trending = %v, want %v", got, want)
+	}
+
+	// The most viewed recipe is a draft, so a page of one reads further
+	rec = env.request(http.MethodGet, "/recipes/trending?window=1h&limit=1", nil)
+	expectStatus(t, rec, http.StatusOK)
+	trending = nil
+	decodeBody(t, rec, &trending)
+	if len(trending) != 1 || trending[0].Recipe.Name != "Popular" {
+		t.Errorf("trending with limit=1 = %v, want Popular", trending)
+	}
+
+	for _, query := range []string{"limit=0", "window=0s", "window=today"} {
+		expectStatus(t, env.request(http.MethodGet, "/recipes/trending?"+query, nil), http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// recipeFields are the JSON field names of a recipe clients may project on
+var recipeFields = jsonFields(reflect.TypeOf(models.Recipe{}))
+
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+// parseFields reads the comma-separated fields query parameter. An empty
+// result means every field is returned.
+func parseFields(c *gin.Context) ([]string, error) {
+	value := c.Query("fields")
+	if value == "" {
+		return nil, nil
+	}
+
+	fields := make([]string, 0)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !recipeFields[field] {
+			known := make([]string, 0, len(recipeFields))
+			for name := range recipeFields {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return nil, errors.New("unknown field " + field + ", fields must be among " + strings.Join(known, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// storedFields returns the fields to read from the store to answer with
+// fields: those, plus the ones the recipes are filtered, translated and paged
+// on. It returns nil, every field, when no field is given.
+func storedFields(fields []string) []string {
+	if len(fields) == 0 {
+		return nil
+	}
+	return append([]string{"userId", "status", "visibility", "metadata", "translations", "name", "publishedAt"}, fields...)
+}
+
+// projectRecipes keeps only the given fields of each recipe, or returns the
+// recipes untouched when no field is given
+func projectRecipes(recipes []models.Recipe, fields []string) interface{} {
+	if len(fields) == 0 {
+		return recipes
+	}
+
+	projected := make([]map[string]json.RawMessage, len(recipes))
+	for i, recipe := range recipes {
+		projected[i] = projectRecipe(recipe, fields)
+	}
+	return projected
+}
+
+// projectRecipe keeps only the given fields of recipe
+func projectRecipe(recipe models.Recipe, fields []string) map[string]json.RawMessage {
+	data, _ := json.Marshal(recipe)
+	var all map[string]json.RawMessage
+	json.Unmarshal(data, &all)
+
+	projected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			projected[field] = value
+		}
+	}
+	return projected
+}
+
+// projectPage applies projectRecipes to a page, keeping the envelope
+func projectPage(page RecipesPage, fields []string) interface{} {
+	if len(fields) == 0 {
+		return page
+	}
+	projected := gin.H{
+		"recipes": projectRecipes(page.Recipes, fields),
+		"limit":   page.Limit,
+		"total":   page.Total,
+	}
+	if page.Page > 0 {
+		projected["page"] = page.Page
+	}
+	if page.NextCursor != "" {
+		projected["nextCursor"] = page.NextCursor
+	}
+	return projected
+}
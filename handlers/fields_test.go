@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// keysOf returns the sorted field names of a projected recipe
+func keysOf(recipe map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(recipe))
+	for key := range recipe {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestFieldsProjection(t *testing.T) {
+	env := memoryEnv(t)
+	alice := newCaller("alice")
+	recipe := publishedRecipe("Pancakes", alice.ID)
+	recipe.Tags = []string{"breakfast"}
+	env.seed(t, recipe)
+	want := []string{"name", "tags"}
+
+	for _, target := range []string{"/recipes?fields=name,tags", "/recipes/search?tag=breakfast&fields=name,%20tags"} {
+		rec := env.request(http.MethodGet, target, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var recipes []map[string]json.RawMessage
+		decodeBody(t, rec, &recipes)
+		if len(recipes) != 1 {
+			t.Fatalf("%s: %d recipes, want 1", target, len(recipes))
+		}
+		if keys := keysOf(recipes[0]); !reflect.DeepEqual(keys, want) {
+			t.Errorf("%s: fields = %v, want %v", target, keys, want)
+		}
+		if string(recipes[0]["name"]) != `"Pancakes"` || string(recipes[0]["tags"]) != `["breakfast"]` {
+			t.Errorf("%s: projected %s", target, rec.Body)
+		}
+	}
+
+	for _, target := range []string{"/recipes?fields=name,tags&envelope=true", "/user/recipes?fields=name,tags&envelope=true"} {
+		rec := env.as(alice).request(http.MethodGet, target, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var page struct {
+			Recipes []map[string]json.RawMessage `json:"recipes"`
+			Total   int                          `json:"total"`
+		}
+		decodeBody(t, rec, &page)
+		if page.Total != 1 || len(page.Recipes) != 1 || !reflect.DeepEqual(keysOf(page.Recipes[0]), want) {
+			t.Errorf("%s: envelope = %s, want the pagination fields around projected recipes", target, rec.Body)
+		}
+	}
+
+	rec := env.request(http.MethodGet, "/recipes", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var full []map[string]json.RawMessage
+	decodeBody(t, rec, &full)
+	if _, ok := full[0]["ingredients"]; !ok {
+		t.Errorf("without fields the recipe lacks its ingredients: %s", rec.Body)
+	}
+}
+
+func TestFieldsRejectsUnknownFields(t *testing.T) {
+	env := memoryEnv(t)
+	for _, target := range []string{"/recipes?fields=name,password", "/recipes/search?fields=secret"} {
+		expectStatus(t, env.request(http.MethodGet, target, nil), http.StatusBadRequest)
+	}
+}
@@ -4,75 +4,247 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/gabrielsscti/Recipes-API/audit"
+	"github.com/gabrielsscti/Recipes-API/cache"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"github.com/gabrielsscti/Recipes-API/events"
+	"github.com/gabrielsscti/Recipes-API/middleware"
 	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gabrielsscti/Recipes-API/views"
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type RecipesHandler struct {
-	collection  *mongo.Collection
-	ctx         context.Context
-	redisClient *redis.Client
+	store     store.RecipeStore
+	ctx       context.Context
+	cache     cache.Cache
+	publisher events.Publisher
+	audit     audit.Recorder
+	views     views.Counter
 }
 
-func NewRecipesHandler(ctx context.Context, collection *mongo.Collection, redisClient *redis.Client) *RecipesHandler {
+func NewRecipesHandler(ctx context.Context, recipeStore store.RecipeStore, recipesCache cache.Cache, publisher events.Publisher, recorder audit.Recorder, counter views.Counter) *RecipesHandler {
 	return &RecipesHandler{
-		collection:  collection,
-		ctx:         ctx,
-		redisClient: redisClient,
+		store:     recipeStore,
+		ctx:       ctx,
+		cache:     recipesCache,
+		publisher: publisher,
+		audit:     recorder,
+		views:     counter,
 	}
 }
 
 // swagger:operation GET /recipes recipes listRecipes
-// Returns list of recipes
+// Returns a page of recipes as an array, paged by the Link and X-Total-Count
+// headers, or every recipe one per line with Accept: application/x-ndjson
 // ---
 // produces:
 // - application/json
+// - application/yaml
+// - application/x-ndjson
+// parameters:
+//   - name: page
+//     in: query
+//     description: page number, starting at 1
+//     required: false
+//     type: integer
+//   - name: limit
+//     in: query
+//     description: number of recipes per page
+//     required: false
+//     type: integer
+//   - name: cursor
+//     in: query
+//     description: nextCursor of the previous page, or empty for the first page, to page by cursor instead of page number
+//     required: false
+//     type: string
+//   - name: sort
+//     in: query
+//     description: sort field (name or publishedAt), prefix with - for descending
+//     required: false
+//     type: string
+//   - name: fields
+//     in: query
+//     description: comma-separated recipe fields to return, all by default
+//     required: false
+//     type: string
+//   - name: envelope
+//     in: query
+//     description: true to wrap the recipes in an object with page, limit, total and nextCursor
+//     required: false
+//     type: boolean
+//   - name: lang
+//     in: query
+//     description: locale of the translations to return, such as pt, falling back to the original content
+//     required: false
+//     type: string
+//   - name: metadata.{key}
+//     in: query
+//     description: only return recipes whose metadata key has this value, may be given for several keys
+//     required: false
+//     type: string
 // responses:
 //     '200':
 //         description: Successful operation
+//     '400':
+//         description: Invalid pagination parameters
 func (handler *RecipesHandler) ListRecipesHandler(c *gin.Context) {
-	val, err := handler.redisClient.Get("recipes").Result()
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fields, err := parseFields(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	metadata, err := parseMetadataFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if middleware.AcceptsNDJSON(c.GetHeader("Accept")) {
+		handler.streamRecipes(c, fields, metadata)
+		return
+	}
 
-	if err == redis.Nil {
+	val, err := handler.getOrRebuild(c.Request.Context(), "recipes", 0, func() (string, error) {
 		log.Printf("Request to MongoDB")
-		cur, err := handler.collection.Find(handler.ctx, bson.M{})
+		recipes, err := handler.store.List(c.Request.Context(), store.ListFilter{})
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+			return "", err
 		}
-		defer cur.Close(handler.ctx)
+		data, _ := json.Marshal(recipes)
+		return string(data), nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	recipes := make([]models.Recipe, 0)
+	json.Unmarshal([]byte(val), &recipes)
 
-		recipes := make([]models.Recipe, 0)
-		for cur.Next(handler.ctx) {
-			var recipe models.Recipe
-			cur.Decode(&recipe)
-			recipes = append(recipes, recipe)
-		}
+	page := paginateRecipes(filterByMetadata(visibleRecipes(c, recipes), metadata), opts)
+	page.Recipes = localizeAll(c, page.Recipes)
+	setPageHeaders(c, page)
+	if wantsEnvelope(c) {
+		render(c, http.StatusOK, projectPage(page, fields))
+		return
+	}
+	render(c, http.StatusOK, projectRecipes(page.Recipes, fields))
+}
 
-		data, _ := json.Marshal(recipes)
-		handler.redisClient.Set("recipes", string(data), 0)
-		c.JSON(http.StatusOK, recipes)
-	} else if err != nil {
+// swagger:operation GET /user/recipes recipes listUserRecipes
+// Returns a page of the recipes owned by the authenticated user as an array,
+// paged by the Link and X-Total-Count headers like GET /recipes
+// ---
+// produces:
+// - application/json
+// - application/yaml
+// parameters:
+//   - name: page
+//     in: query
+//     description: page number, starting at 1
+//     required: false
+//     type: integer
+//   - name: limit
+//     in: query
+//     description: number of recipes per page
+//     required: false
+//     type: integer
+//   - name: cursor
+//     in: query
+//     description: nextCursor of the previous page, or empty for the first page, to page by cursor instead of page number
+//     required: false
+//     type: string
+//   - name: sort
+//     in: query
+//     description: sort field (name or publishedAt), prefix with - for descending
+//     required: false
+//     type: string
+//   - name: fields
+//     in: query
+//     description: comma-separated recipe fields to return, all by default
+//     required: false
+//     type: string
+//   - name: envelope
+//     in: query
+//     description: true to wrap the recipes in an object with page, limit, total and nextCursor
+//     required: false
+//     type: boolean
+//   - name: lang
+//     in: query
+//     description: locale of the translations to return, such as pt, falling back to the original content
+//     required: false
+//     type: string
+//   - name: metadata.{key}
+//     in: query
+//     description: only return recipes whose metadata key has this value, may be given for several keys
+//     required: false
+//     type: string
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid pagination parameters
+func (handler *RecipesHandler) ListUserRecipesHandler(c *gin.Context) {
+	opts, err := parseListOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fields, err := parseFields(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	metadata, err := parseMetadataFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := handler.listPage(c.Request.Context(), store.ListFilter{
+		UserID:   currentUserID(c),
+		Metadata: metadata,
+		Fields:   storedFields(fields),
+	}, opts)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
-	} else {
-		log.Printf("Request to Redis")
-		recipes := make([]models.Recipe, 0)
-		json.Unmarshal([]byte(val), &recipes)
-		c.JSON(http.StatusOK, recipes)
 	}
+
+	page.Recipes = localizeAll(c, page.Recipes)
+	setPageHeaders(c, page)
+	if wantsEnvelope(c) {
+		render(c, http.StatusOK, projectPage(page, fields))
+		return
+	}
+	render(c, http.StatusOK, projectRecipes(page.Recipes, fields))
 }
 
+// rejectDuplicates makes creating a recipe fail when its owner already has
+// one with the same name, unless allowDuplicate is passed. Set with
+// REJECT_DUPLICATE_RECIPES.
+var rejectDuplicates = config.Bool("REJECT_DUPLICATE_RECIPES", true)
+
 // swagger:operation POST /recipes recipes newRecipe
 // Create a new recipe
 // ---
+// parameters:
+//   - name: allowDuplicate
+//     in: query
+//     description: create the recipe even if the caller has one with the same name
+//     required: false
+//     type: boolean
 // produces:
 // - application/json
 // responses:
@@ -80,65 +252,283 @@ func (handler *RecipesHandler) ListRecipesHandler(c *gin.Context) {
 //         description: Successful operation
 //     '400':
 //         description: Invalid input
+//     '403':
+//         description: The caller has reached MAX_RECIPES_PER_USER
+//     '409':
+//         description: The caller already has a recipe with the same name, whose ID is returned
 func (handler *RecipesHandler) NewRecipeHandler(c *gin.Context) {
 	var recipe models.Recipe
-	if err := c.ShouldBindJSON(&recipe); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+	if !bindRecipe(c, &recipe) {
+		return
+	}
+	if !handler.checkRecipeLimit(c) {
 		return
 	}
+	if rejectDuplicates && c.Query("allowDuplicate") != "true" {
+		existing, err := handler.store.List(c.Request.Context(), store.ListFilter{UserID: currentUserID(c), Name: recipe.Name})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(existing) > 0 {
+			body := errorBody(c, msgDuplicateRecipe, existing[0].Name)
+			body["id"] = existing[0].ID.Hex()
+			c.JSON(http.StatusConflict, body)
+			return
+		}
+	}
 	recipe.ID = primitive.NewObjectID()
 	recipe.PublishedAt = time.Now()
-	_, err := handler.collection.InsertOne(handler.ctx, recipe)
+	recipe.UserID = currentUserID(c)
+	recipe.Views = 0
+	if recipe.Status == "" {
+		recipe.Status = models.StatusDraft
+	}
+	err := handler.createRecipe(c.Request.Context(), &recipe)
 	if err != nil {
 		fmt.Println(err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error while inserting a new recipe"})
+		c.JSON(http.StatusInternalServerError, errorBody(c, msgInsertRecipeFailed))
 		return
 	}
 
 	handler.clearRecipesFromRedis()
+	handler.publish(c, events.RecipeCreated, recipe)
 
 	c.JSON(http.StatusOK, recipe)
 }
 
+// swagger:operation POST /recipes/validate recipes validateRecipe
+// Validate a recipe as it would be on creation, without saving it
+// ---
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: The recipe is valid
+//     '400':
+//         description: Invalid input, with the problem of each field
+func (handler *RecipesHandler) ValidateRecipeHandler(c *gin.Context) {
+	var recipe models.Recipe
+	if !bindRecipe(c, &recipe) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"valid": true})
+}
+
+// bindRecipe decodes, normalizes and validates the recipe in the request body, replying
+// with 400 and reporting false when it is invalid
+func bindRecipe(c *gin.Context, recipe *models.Recipe) bool {
+	if err := c.ShouldBindJSON(recipe); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return false
+	}
+
+	recipe.Tags = normalizeTags(recipe.Tags)
+	if errs := validateRecipe(*recipe); len(errs) > 0 {
+		body := errorBody(c, msgInvalidRecipe)
+		body["valid"] = false
+		body["fields"] = errs
+		c.JSON(http.StatusBadRequest, body)
+		return false
+	}
+	return true
+}
+
+// publish notifies the change of a recipe and records it in the audit log
+func (handler *RecipesHandler) publish(c *gin.Context, eventType string, recipe models.Recipe) {
+	if err := handler.publisher.Publish(events.NewEvent(eventType, recipe.ID, recipe.UserID)); err != nil {
+		log.Printf("Warning: could not publish %s for recipe %s: %v", eventType, recipe.ID.Hex(), err)
+	}
+	entry := audit.NewEntry(c.GetString("username"), eventType, recipe.ID.Hex())
+	if err := handler.audit.Record(c.Request.Context(), entry); err != nil {
+		log.Printf("Warning: could not audit %s for recipe %s: %v", eventType, recipe.ID.Hex(), err)
+	}
+}
+
 func (handler *RecipesHandler) clearRecipesFromRedis() {
 	log.Println("Remove data from Redis")
-	handler.redisClient.Del("recipes")
+	if err := handler.cache.Del(append([]string{"recipes"}, recentKeys()...)...); err != nil {
+		log.Printf("Warning: could not remove data from the cache: %v", err)
+	}
+	handler.expireSearches()
+}
+
+// expireSearches moves the cached searches on to a new generation. Those
+// cached before it expire before it does, so falling back to no generation
+// once it expires serves none of them.
+func (handler *RecipesHandler) expireSearches() {
+	handler.setCached(searchGenerationKey, strconv.FormatInt(time.Now().UnixNano(), 36), searchCacheTTL)
+}
+
+// getCached returns the cached value for key. Cache failures other than a
+// miss are logged and treated as a miss, so the API keeps serving from the
+// store when Redis is down.
+func (handler *RecipesHandler) getCached(key string) (string, bool) {
+	val, err := handler.cache.Get(key)
+	if err == cache.ErrMiss {
+		return "", false
+	} else if err != nil {
+		log.Printf("Warning: cache unavailable, bypassing it: %v", err)
+		return "", false
+	}
+	return val, true
+}
+
+// setCached caches value under key, logging failures instead of returning them
+func (handler *RecipesHandler) setCached(key string, value string, ttl time.Duration) {
+	if err := handler.cache.Set(key, value, ttl); err != nil {
+		log.Printf("Warning: could not write %s to the cache: %v", key, err)
+	}
 }
 
 // swagger:operation GET /recipes/search recipes findRecipe
-// Search recipes based on tags
+// Search recipes based on tags and name
 // ---
 // produces:
 // - application/json
+// - application/yaml
 // parameters:
 //   - name: tag
 //     in: query
-//     description: recipe tag
-//     required: true
+//     description: recipe tag, may be repeated
+//     required: false
+//     type: string
+//   - name: q
+//     in: query
+//     description: text contained in the recipe name
+//     required: false
+//     type: string
+//   - name: match
+//     in: query
+//     description: whether recipes must have all or any of the tags (defaults to any)
+//     required: false
+//     type: string
+//   - name: source
+//     in: query
+//     description: text to look for in the name or URL of the recipe source
+//     required: false
+//     type: string
+//   - name: fuzzy
+//     in: query
+//     description: tolerate typos when matching q against recipe names
+//     required: false
+//     type: boolean
+//   - name: metadata.{key}
+//     in: query
+//     description: only return recipes whose metadata key has this value, may be given for several keys
+//     required: false
+//     type: string
+//   - name: fields
+//     in: query
+//     description: comma-separated recipe fields to return, all by default
+//     required: false
 //     type: string
 // responses:
 //     '200':
 //         description: Successful operation
+//     '400':
+//         description: Invalid search parameters
 func (handler *RecipesHandler) SearchRecipeHandler(c *gin.Context) {
-	tag := c.Query("tag")
+	query, err := parseSearchQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fields, err := parseFields(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	cur, err := handler.collection.Find(handler.ctx, bson.M{"tags": tag})
+	generation, _ := handler.getCached(searchGenerationKey)
+	key := query.cacheKey(generation)
+	if val, ok := handler.getCached(key); ok {
+		log.Printf("Search cache hit for %s", key)
+		recipes := make([]models.Recipe, 0)
+		json.Unmarshal([]byte(val), &recipes)
+		recipes = visibleRecipes(c, recipes)
+		c.Header("X-Total-Count", strconv.Itoa(len(recipes)))
+		render(c, http.StatusOK, projectRecipes(localizeAll(c, recipes), fields))
+		return
+	}
+	log.Printf("Search cache miss for %s", key)
+
+	candidates, err := handler.store.Search(c.Request.Context(), query.criteria())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	defer cur.Close(handler.ctx)
 
-	recipes := make([]models.Recipe, 0)
-	for cur.Next(handler.ctx) {
-		var recipe models.Recipe
-		cur.Decode(&recipe)
+	recipes := make([]models.Recipe, 0, len(candidates))
+	for _, recipe := range candidates {
+		if query.Fuzzy && !query.fuzzyMatch(recipe.Name) {
+			continue
+		}
 		recipes = append(recipes, recipe)
 	}
 
-	c.JSON(http.StatusOK, recipes)
+	data, _ := json.Marshal(recipes)
+	handler.setCached(key, string(data), searchCacheTTL)
+	recipes = visibleRecipes(c, recipes)
+	c.Header("X-Total-Count", strconv.Itoa(len(recipes)))
+	render(c, http.StatusOK, projectRecipes(localizeAll(c, recipes), fields))
+}
+
+// swagger:operation GET /recipes/by-ingredients recipes searchByIngredients
+// Search recipes by the ingredients they use
+// ---
+// produces:
+// - application/json
+// - application/yaml
+// parameters:
+//   - name: ingredient
+//     in: query
+//     description: ingredient to look for, matched as a substring, may be repeated
+//     required: true
+//     type: string
+//   - name: match
+//     in: query
+//     description: any (default) returns recipes with at least one of the ingredients, all requires every one
+//     required: false
+//     type: string
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid search parameters
+func (handler *RecipesHandler) SearchByIngredientsHandler(c *gin.Context) {
+	match := strings.ToLower(strings.TrimSpace(c.DefaultQuery("match", matchAny)))
+	if match != matchAny && match != matchAll {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "match must be either any or all"})
+		return
+	}
+
+	ingredients := make([]string, 0)
+	for _, ingredient := range c.QueryArray("ingredient") {
+		if ingredient = strings.ToLower(strings.TrimSpace(ingredient)); ingredient != "" {
+			ingredients = append(ingredients, ingredient)
+		}
+	}
+	if len(ingredients) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one ingredient must be given"})
+		return
+	}
+
+	recipes, err := handler.store.Search(c.Request.Context(), store.SearchCriteria{
+		Ingredients:         ingredients,
+		MatchAllIngredients: match == matchAll,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	recipes = visibleRecipes(c, recipes)
+	c.Header("X-Total-Count", strconv.Itoa(len(recipes)))
+	render(c, http.StatusOK, localizeAll(c, recipes))
 }
 
 // swagger:operation PUT /recipes/{id} recipes updateRecipe
@@ -157,34 +547,177 @@ func (handler *RecipesHandler) SearchRecipeHandler(c *gin.Context) {
 //         description: Successful operation
 //     '400':
 //         description: Invalid input
+//     '403':
+//         description: The recipe belongs to another user
 //     '404':
 //         description: Invalid recipe ID
 func (handler *RecipesHandler) UpdateRecipeHandler(c *gin.Context) {
 	id := c.Param("id")
 	var recipe models.Recipe
-	if err := c.ShouldBindJSON(&recipe); err != nil {
+	if !bindRecipe(c, &recipe) {
+		return
+	}
+
+	objectId, _ := primitive.ObjectIDFromHex(id)
+	current, err := handler.store.GetByID(c.Request.Context(), objectId)
+	if err == nil && !canView(c, current) {
+		err = store.ErrNotFound
+	}
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgRecipeNotFound, id))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if current.UserID != currentUserID(c) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, errorBody(c, msgNotOwnerEdit))
+		return
+	}
+
+	patch := models.RecipePatch{
+		Name:         &recipe.Name,
+		Instructions: &recipe.Instructions,
+		Ingredients:  &recipe.Ingredients,
+		Tags:         &recipe.Tags,
+		Servings:     &recipe.Servings,
+		Nutrition:    &recipe.Nutrition,
+		SourceURL:    &recipe.SourceURL,
+		SourceName:   &recipe.SourceName,
+		Yield:        &recipe.Yield,
+		Translations: &recipe.Translations,
+		Metadata:     &recipe.Metadata,
+		Difficulty:   &recipe.Difficulty,
+		TotalTime:    &recipe.TotalTime,
+	}
+	// Like the status, the visibility is kept unless the body sets it
+	if recipe.Visibility != "" {
+		patch.Visibility = &recipe.Visibility
+	}
+	if err := handler.updateSlug(c.Request.Context(), current, &patch); err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := handler.store.Update(c.Request.Context(), objectId, patch)
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgRecipeNotFound, id))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	handler.clearRecipesFromRedis()
+	handler.publish(c, events.RecipeUpdated, updated)
+	c.JSON(http.StatusOK, gin.H{"message": "Recipe has been updated"})
+}
+
+// swagger:operation PATCH /recipes/{id} recipes patchRecipe
+// Update only the given fields of an existing recipe
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the recipe
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid input
+//     '403':
+//         description: The recipe belongs to another user
+//     '404':
+//         description: Invalid recipe ID
+func (handler *RecipesHandler) PatchRecipeHandler(c *gin.Context) {
+	id := c.Param("id")
+	var patch models.RecipePatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
-	objectId, _ := primitive.ObjectIDFromHex(id)
-	_, err := handler.collection.UpdateOne(handler.ctx, bson.M{
-		"_id": objectId,
-	}, bson.D{{"$set", bson.D{
-		{"name", recipe.Name},
-		{"instructions", recipe.Instructions},
-		{"ingredients", recipe.Ingredients},
-		{"tags", recipe.Tags},
-	}}})
+	patch.PublishedAt = nil
+	if patch.IsEmpty() {
+		c.JSON(http.StatusBadRequest, errorBody(c, msgNoFieldsToUpdate))
+		return
+	}
+	if patch.Status != nil && *patch.Status == "" {
+		c.JSON(http.StatusBadRequest, errorBody(c, msgInvalidStatus))
+		return
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
+		c.JSON(http.StatusNotFound, errorBody(c, msgInvalidRecipeID))
+		return
+	}
+
+	if patch.Tags != nil {
+		tags := normalizeTags(*patch.Tags)
+		patch.Tags = &tags
+	}
+
+	// Validate the recipe as it will be once patched, so partial updates
+	// follow the same rules as full ones
+	current, err := handler.store.GetByID(c.Request.Context(), objectId)
+	if err == nil && !canView(c, current) {
+		err = store.ErrNotFound
+	}
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgRecipeNotFound, id))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if current.UserID != currentUserID(c) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, errorBody(c, msgNotOwnerEdit))
+		return
+	}
+	// Publishing through PATCH dates the recipe like PublishRecipeHandler
+	if patch.Status != nil && *patch.Status == models.StatusPublished && current.Status != models.StatusPublished {
+		now := time.Now()
+		patch.PublishedAt = &now
+	}
+
+	if err := handler.updateSlug(c.Request.Context(), current, &patch); err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	patch.Apply(&current)
+	if errs := validateRecipe(current); len(errs) > 0 {
+		body := errorBody(c, msgInvalidRecipe)
+		body["fields"] = errs
+		c.JSON(http.StatusBadRequest, body)
+		return
+	}
+
+	updated, err := handler.store.Update(c.Request.Context(), objectId, patch)
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgRecipeNotFound, id))
+		return
+	} else if err != nil {
 		fmt.Println(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	handler.clearRecipesFromRedis()
+	handler.publish(c, events.RecipeUpdated, updated)
 	c.JSON(http.StatusOK, gin.H{"message": "Recipe has been updated"})
 }
 
@@ -202,22 +735,37 @@ func (handler *RecipesHandler) UpdateRecipeHandler(c *gin.Context) {
 // responses:
 //     '200':
 //         description: Successful operation
+//     '403':
+//         description: The recipe belongs to another user
 //     '404':
 //         description: Invalid recipe ID
 func (handler *RecipesHandler) DeleteRecipeHandler(c *gin.Context) {
 	id := c.Param("id")
 
 	objectId, _ := primitive.ObjectIDFromHex(id)
-	deleteResult, err := handler.collection.DeleteOne(handler.ctx, bson.M{"_id": objectId})
-	if err != nil {
+	recipe, err := handler.store.GetByID(c.Request.Context(), objectId)
+	if err == nil && !canView(c, recipe) {
+		err = store.ErrNotFound
+	}
+	if err == nil && recipe.UserID != currentUserID(c) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, errorBody(c, msgNotOwnerDelete))
+		return
+	}
+
+	var deleted models.Recipe
+	if err == nil {
+		deleted, err = handler.store.Delete(c.Request.Context(), objectId)
+	}
+	if err != nil && err != store.ErrNotFound {
 		fmt.Println(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	var returnMessage string
-	if deleteResult.DeletedCount > 0 {
+	if err == nil {
 		handler.clearRecipesFromRedis()
+		handler.publish(c, events.RecipeDeleted, deleted)
 		returnMessage = "Recipe has been deleted"
 	} else {
 		returnMessage = "No recipes have been deleted"
@@ -228,33 +776,372 @@ func (handler *RecipesHandler) DeleteRecipeHandler(c *gin.Context) {
 	})
 }
 
-// swagger:operation GET /recipes/:id recipes getRecipe
+// swagger:operation POST /recipes/bulk-delete recipes bulkDeleteRecipes
+// Delete several recipes at once. Only the caller's recipes are deleted, unless the caller is an admin
+// ---
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid input
+func (handler *RecipesHandler) BulkDeleteRecipesHandler(c *gin.Context) {
+	var request models.BulkDeleteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	objectIds := make([]primitive.ObjectID, 0, len(request.IDs))
+	invalidIds := make([]string, 0)
+	for _, id := range request.IDs {
+		objectId, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			invalidIds = append(invalidIds, id)
+			continue
+		}
+		objectIds = append(objectIds, objectId)
+	}
+
+	deleted := make([]models.Recipe, 0)
+	if len(objectIds) > 0 {
+		var owner primitive.ObjectID
+		if !isAdmin(c) {
+			owner = currentUserID(c)
+		}
+
+		var err error
+		deleted, err = handler.store.DeleteMany(c.Request.Context(), objectIds, owner)
+		if err != nil {
+			fmt.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if len(deleted) > 0 {
+		handler.clearRecipesFromRedis()
+	}
+	for _, recipe := range deleted {
+		handler.publish(c, events.RecipeDeleted, recipe)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deletedCount": len(deleted),
+		"invalidIds":   invalidIds,
+	})
+}
+
+// swagger:operation GET /recipes/{id} recipes getRecipe
 // Returns a recipe by its ID
 // ---
+// parameters:
+//   - name: id
+//     in: path
+//     description: ID of the recipe
+//     required: true
+//     type: string
+//   - name: lang
+//     in: query
+//     description: locale of the translation to return, such as pt, falling back to the original content
+//     required: false
+//     type: string
+//   - name: format
+//     in: query
+//     description: markdown to get the recipe as a Markdown document instead of JSON
+//     required: false
+//     type: string
 // produces:
 // - application/json
+// - application/yaml
+// - text/markdown
 // responses:
 //     '200':
 //         description: Successful operation
+//     '400':
+//         description: Invalid format
 func (handler *RecipesHandler) GetRecipeHandler(c *gin.Context) {
 	id := c.Param("id")
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "markdown" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or markdown"})
+		return
+	}
 
 	objectId, _ := primitive.ObjectIDFromHex(id)
-	findResult := handler.collection.FindOne(handler.ctx, bson.M{"_id": objectId})
-	findError := findResult.Err()
+	recipe, findError := handler.store.GetByID(c.Request.Context(), objectId)
+	if findError == nil && !canView(c, recipe) {
+		findError = store.ErrNotFound
+	}
 
-	if findError == mongo.ErrNoDocuments {
-		fmt.Println(findResult.Err())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "No match was found for ID " + id})
+	if findError == store.ErrNotFound {
+		fmt.Println(findError)
+		c.JSON(http.StatusInternalServerError, errorBody(c, msgRecipeNotFound, id))
 		return
 	} else if findError != nil {
-		fmt.Println(findResult.Err())
+		fmt.Println(findError)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": findError.Error()})
 		return
 	}
 
-	var recipe models.Recipe
-	findResult.Decode(&recipe)
+	handler.countView(c, recipe)
+	if format == "markdown" {
+		renderMarkdown(c, http.StatusOK, localize(c, recipe))
+		return
+	}
+	render(c, http.StatusOK, localize(c, recipe))
+
+}
+
+// swagger:operation POST /recipes/{id}/clone recipes cloneRecipe
+// Copy an existing recipe into a new recipe owned by the authenticated user
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the recipe to copy
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation
+//     '403':
+//         description: The caller has reached MAX_RECIPES_PER_USER
+//     '404':
+//         description: Invalid recipe ID
+func (handler *RecipesHandler) CloneRecipeHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorBody(c, msgInvalidRecipeID))
+		return
+	}
+
+	recipe, err := handler.store.GetByID(c.Request.Context(), objectId)
+	if err == nil && !canView(c, recipe) {
+		err = store.ErrNotFound
+	}
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgRecipeNotFound, id))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !handler.checkRecipeLimit(c) {
+		return
+	}
+
+	recipe.ID = primitive.NewObjectID()
+	recipe.Name = recipe.Name + " (copy)"
+	recipe.PublishedAt = time.Now()
+	recipe.UserID = currentUserID(c)
+	recipe.Status = models.StatusDraft
+	recipe.Views = 0
+	err = handler.createRecipe(c.Request.Context(), &recipe)
+	if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, errorBody(c, msgInsertRecipeFailed))
+		return
+	}
+
+	handler.clearRecipesFromRedis()
+	handler.publish(c, events.RecipeCreated, recipe)
+
 	c.JSON(http.StatusOK, recipe)
+}
 
+// swagger:operation POST /recipes/{id}/publish recipes publishRecipe
+// Publish a draft recipe, making it visible to everyone
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the recipe
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation
+//     '403':
+//         description: The recipe belongs to another user
+//     '404':
+//         description: Invalid recipe ID
+func (handler *RecipesHandler) PublishRecipeHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorBody(c, msgInvalidRecipeID))
+		return
+	}
+
+	recipe, err := handler.store.GetByID(c.Request.Context(), objectId)
+	if err == nil && !canView(c, recipe) {
+		err = store.ErrNotFound
+	}
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgRecipeNotFound, id))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if recipe.UserID != currentUserID(c) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, errorBody(c, msgNotOwner))
+		return
+	}
+
+	status := models.StatusPublished
+	now := time.Now()
+	updated, err := handler.store.Update(c.Request.Context(), objectId, models.RecipePatch{
+		Status:      &status,
+		PublishedAt: &now,
+	})
+	if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	handler.clearRecipesFromRedis()
+	handler.publish(c, events.RecipeUpdated, updated)
+	c.JSON(http.StatusOK, updated)
+}
+
+// swagger:operation PUT /recipes/{id}/visibility recipes setRecipeVisibility
+// Make a recipe public or private, sent as {"visibility": "private"}.
+// Private recipes are only visible to their owner
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the recipe
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation, returns the updated recipe
+//     '400':
+//         description: Invalid visibility
+//     '403':
+//         description: The recipe belongs to another user
+//     '404':
+//         description: Invalid recipe ID
+func (handler *RecipesHandler) SetVisibilityHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	var request models.VisibilityRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validVisibility(request.Visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be either public or private"})
+		return
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorBody(c, msgInvalidRecipeID))
+		return
+	}
+
+	recipe, err := handler.store.GetByID(c.Request.Context(), objectId)
+	if err == nil && !canView(c, recipe) {
+		err = store.ErrNotFound
+	}
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgRecipeNotFound, id))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if recipe.UserID != currentUserID(c) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, errorBody(c, msgNotOwnerEdit))
+		return
+	}
+
+	updated, err := handler.store.Update(c.Request.Context(), objectId, models.RecipePatch{
+		Visibility: &request.Visibility,
+	})
+	if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	handler.clearRecipesFromRedis()
+	handler.publish(c, events.RecipeUpdated, updated)
+	c.JSON(http.StatusOK, updated)
+}
+
+// swagger:operation GET /recipes/{id}/nutrition recipes getRecipeNutrition
+// Returns the total and per serving nutrition of a recipe
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the recipe
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation
+//     '404':
+//         description: Invalid recipe ID
+func (handler *RecipesHandler) GetRecipeNutritionHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorBody(c, msgInvalidRecipeID))
+		return
+	}
+
+	recipe, err := handler.store.GetByID(c.Request.Context(), objectId)
+	if err == nil && !canView(c, recipe) {
+		err = store.ErrNotFound
+	}
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgRecipeNotFound, id))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total models.NutritionFacts
+	for _, item := range recipe.Nutrition {
+		total = total.Add(item.NutritionFacts)
+	}
+
+	// Recipes without a servings count are treated as a single serving
+	servings := recipe.Servings
+	if servings < 1 {
+		servings = 1
+	}
+
+	render(c, http.StatusOK, models.NutritionSummary{
+		Servings:   servings,
+		Total:      total,
+		PerServing: total.Divide(servings),
+	})
 }
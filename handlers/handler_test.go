@@ -0,0 +1,646 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gabrielsscti/Recipes-API/events"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// mongoEnv serves the recipes routes against the mocked collection of mt
+func mongoEnv(mt *mtest.T) *testEnv {
+	return newTestEnv(mt.T, store.NewMongoStore(mt.Coll))
+}
+
+func recipeDoc(mt *mtest.T, recipe models.Recipe) bson.D {
+	data, err := bson.Marshal(recipe)
+	if err != nil {
+		mt.Fatalf("marshalling %q: %v", recipe.Name, err)
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		mt.Fatalf("unmarshalling %q: %v", recipe.Name, err)
+	}
+	return doc
+}
+
+func cursorOf(mt *mtest.T, docs ...bson.D) bson.D {
+	ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+	return mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, docs...)
+}
+
+func publishedRecipe(name string, owner primitive.ObjectID) models.Recipe {
+	return models.Recipe{
+		ID:           primitive.NewObjectID(),
+		Name:         name,
+		Ingredients:  []string{"flour"},
+		Instructions: []string{"bake"},
+		Status:       models.StatusPublished,
+		UserID:       owner,
+		PublishedAt:  time.Now().UTC().Truncate(time.Millisecond),
+	}
+}
+
+func TestRecipesHandlerWithMongo(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("create", func(mt *mtest.T) {
+		env := mongoEnv(mt)
+		env.redis.Set("recipes", "[]")
+		mt.AddMockResponses(
+			cursorOf(mt), // recipes of the caller with the same name
+			cursorOf(mt), // slugs taken
+			mtest.CreateSuccessResponse(),
+		)
+
+		rec := env.as(newCaller("alice")).request(http.MethodPost, "/recipes", models.Recipe{
+			Name:         "Pancakes",
+			Ingredients:  []string{"flour", "milk"},
+			Instructions: []string{"mix", "fry"},
+		})
+		expectStatus(mt.T, rec, http.StatusOK)
+
+		var created models.Recipe
+		decodeBody(mt.T, rec, &created)
+		if created.ID.IsZero() || created.UserID != env.caller.ID {
+			mt.Fatalf("created = %+v, want an ID and the caller as owner", created)
+		}
+		if created.Slug != "pancakes" || created.Status != models.StatusDraft {
+			mt.Errorf("slug, status = %q, %q, want pancakes, draft", created.Slug, created.Status)
+		}
+		if env.redis.Exists("recipes") {
+			mt.Errorf("the cached list was not cleared")
+		}
+	})
+
+	mt.Run("get", func(mt *mtest.T) {
+		env := mongoEnv(mt)
+		recipe := publishedRecipe("Pancakes", primitive.NewObjectID())
+		mt.AddMockResponses(cursorOf(mt, recipeDoc(mt, recipe)))
+
+		rec := env.as(newCaller("bob")).request(http.MethodGet, "/recipes/"+recipe.ID.Hex(), nil)
+		expectStatus(mt.T, rec, http.StatusOK)
+
+		var got models.Recipe
+		decodeBody(mt.T, rec, &got)
+		if got.ID != recipe.ID || got.Name != recipe.Name {
+			mt.Errorf("got %+v, want %+v", got, recipe)
+		}
+	})
+
+	mt.Run("list with cache", func(mt *mtest.T) {
+		env := mongoEnv(mt)
+		owner := primitive.NewObjectID()
+		mt.AddMockResponses(cursorOf(mt,
+			recipeDoc(mt, publishedRecipe("Pancakes", owner)),
+			recipeDoc(mt, publishedRecipe("Waffles", owner)),
+		))
+
+		for i := 0; i < 2; i++ {
+			rec := env.request(http.MethodGet, "/recipes", nil)
+			expectStatus(mt.T, rec, http.StatusOK)
+
+			var recipes []models.Recipe
+			decodeBody(mt.T, rec, &recipes)
+			if len(recipes) != 2 {
+				mt.Fatalf("request %d: got %d recipes, want 2", i+1, len(recipes))
+			}
+		}
+		if finds := len(mt.GetAllStartedEvents()); finds != 1 {
+			mt.Errorf("MongoDB was queried %d times, want 1", finds)
+		}
+		if !env.redis.Exists("recipes") {
+			mt.Errorf("the list was not cached")
+		}
+	})
+
+	mt.Run("delete", func(mt *mtest.T) {
+		env := mongoEnv(mt)
+		owner := newCaller("alice")
+		recipe := publishedRecipe("Pancakes", owner.ID)
+		env.redis.Set("recipes", "[]")
+		mt.AddMockResponses(
+			cursorOf(mt, recipeDoc(mt, recipe)),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: recipeDoc(mt, recipe)}),
+		)
+
+		rec := env.as(owner).request(http.MethodDelete, "/recipes/"+recipe.ID.Hex(), nil)
+		expectStatus(mt.T, rec, http.StatusOK)
+
+		var body map[string]string
+		decodeBody(mt.T, rec, &body)
+		if body["message"] != "Recipe has been deleted" {
+			mt.Errorf("message = %q", body["message"])
+		}
+		if env.redis.Exists("recipes") {
+			mt.Errorf("the cached list was not cleared")
+		}
+	})
+
+	mt.RunOpts("configured names", mtest.NewOptions().DatabaseName("kitchen").CollectionName("dishes"), func(mt *mtest.T) {
+		env := mongoEnv(mt)
+		mt.AddMockResponses(cursorOf(mt), cursorOf(mt), mtest.CreateSuccessResponse())
+
+		rec := env.as(newCaller("alice")).request(http.MethodPost, "/recipes", newRecipe("Pancakes"))
+		expectStatus(mt.T, rec, http.StatusOK)
+
+		started := mt.GetAllStartedEvents()
+		if len(started) != 3 {
+			mt.Fatalf("sent %d commands, want 3", len(started))
+		}
+		for _, event := range started {
+			collection, _ := event.Command.Lookup(event.CommandName).StringValueOK()
+			if event.DatabaseName != "kitchen" || collection != "dishes" {
+				mt.Errorf("%s targeted %s.%s, want kitchen.dishes", event.CommandName, event.DatabaseName, collection)
+			}
+		}
+	})
+
+	mt.Run("delete of another user's recipe", func(mt *mtest.T) {
+		env := mongoEnv(mt)
+		recipe := publishedRecipe("Pancakes", primitive.NewObjectID())
+		mt.AddMockResponses(cursorOf(mt, recipeDoc(mt, recipe)))
+
+		rec := env.as(newCaller("mallory")).request(http.MethodDelete, "/recipes/"+recipe.ID.Hex(), nil)
+		expectStatus(mt.T, rec, http.StatusForbidden)
+	})
+}
+
+func newRecipe(name string) models.Recipe {
+	return models.Recipe{
+		Name:         name,
+		Ingredients:  []string{"flour", "milk"},
+		Instructions: []string{"mix", "fry"},
+	}
+}
+
+func TestRecipesHandlerWithMemoryStore(t *testing.T) {
+	env := memoryEnv(t).as(newCaller("alice"))
+
+	rec := env.request(http.MethodPost, "/recipes", newRecipe("Pancakes"))
+	expectStatus(t, rec, http.StatusOK)
+	var created models.Recipe
+	decodeBody(t, rec, &created)
+
+	rec = env.request(http.MethodGet, "/recipes/"+created.ID.Hex(), nil)
+	expectStatus(t, rec, http.StatusOK)
+	var got models.Recipe
+	decodeBody(t, rec, &got)
+	if got.Name != "Pancakes" || got.UserID != env.caller.ID {
+		t.Fatalf("got %+v, want the created recipe", got)
+	}
+
+	update := newRecipe("Crepes")
+	update.Status = models.StatusPublished
+	rec = env.request(http.MethodPut, "/recipes/"+created.ID.Hex(), update)
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = env.request(http.MethodGet, "/recipes", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var recipes []models.Recipe
+	decodeBody(t, rec, &recipes)
+	if len(recipes) != 1 || recipes[0].Name != "Crepes" {
+		t.Fatalf("list = %+v, want the updated recipe alone", recipes)
+	}
+
+	rec = env.request(http.MethodDelete, "/recipes/"+created.ID.Hex(), nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	rec = env.request(http.MethodGet, "/recipes", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decodeBody(t, rec, &recipes)
+	if len(recipes) != 0 {
+		t.Errorf("list = %+v after delete, want none", recipes)
+	}
+}
+
+func TestNewRecipeHandlerRejectsInvalidRecipe(t *testing.T) {
+	env := memoryEnv(t).as(newCaller("alice"))
+
+	rec := env.request(http.MethodPost, "/recipes", models.Recipe{Name: "Pancakes"})
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	recipes, _ := env.store.List(context.Background(), store.ListFilter{})
+	if len(recipes) != 0 {
+		t.Errorf("store has %d recipes, want none", len(recipes))
+	}
+}
+
+func TestRecipeMutationsPublishEvents(t *testing.T) {
+	env := memoryEnv(t).as(newCaller("alice"))
+	publisher := events.NewChannelPublisher()
+	received := publisher.Subscribe(10)
+	env.handler.publisher = publisher
+
+	expectEvent := func(eventType string, recipeID primitive.ObjectID) {
+		t.Helper()
+		select {
+		case event := <-received:
+			if event.Type != eventType || event.RecipeID != recipeID || event.UserID != env.caller.ID {
+				t.Errorf("event = %+v, want %s of %s by %s", event, eventType, recipeID.Hex(), env.caller.ID.Hex())
+			}
+			if event.Timestamp.IsZero() {
+				t.Errorf("%s has no timestamp", event.Type)
+			}
+		default:
+			t.Fatalf("no %s event was published", eventType)
+		}
+	}
+
+	rec := env.request(http.MethodPost, "/recipes", newRecipe("Pancakes"))
+	expectStatus(t, rec, http.StatusOK)
+	var created models.Recipe
+	decodeBody(t, rec, &created)
+	expectEvent(events.RecipeCreated, created.ID)
+
+	rec = env.request(http.MethodPut, "/recipes/"+created.ID.Hex(), newRecipe("Crepes"))
+	expectStatus(t, rec, http.StatusOK)
+	expectEvent(events.RecipeUpdated, created.ID)
+
+	rec = env.request(http.MethodPatch, "/recipes/"+created.ID.Hex(), map[string]string{"name": "Galettes"})
+	expectStatus(t, rec, http.StatusOK)
+	expectEvent(events.RecipeUpdated, created.ID)
+
+	rec = env.request(http.MethodDelete, "/recipes/"+created.ID.Hex(), nil)
+	expectStatus(t, rec, http.StatusOK)
+	expectEvent(events.RecipeDeleted, created.ID)
+
+	rec = env.request(http.MethodDelete, "/recipes/"+created.ID.Hex(), nil)
+	expectStatus(t, rec, http.StatusOK)
+	select {
+	case event := <-received:
+		t.Errorf("deleting a missing recipe published %+v", event)
+	default:
+	}
+}
+
+func TestValidateRecipeHandler(t *testing.T) {
+	env := memoryEnv(t).as(newCaller("alice"))
+
+	rec := env.request(http.MethodPost, "/recipes/validate", newRecipe("Pancakes"))
+	expectStatus(t, rec, http.StatusOK)
+	var valid map[string]interface{}
+	decodeBody(t, rec, &valid)
+	if valid["valid"] != true {
+		t.Errorf("body = %v, want valid", valid)
+	}
+
+	rec = env.request(http.MethodPost, "/recipes/validate", models.Recipe{Name: " ", Instructions: []string{"mix", ""}})
+	expectStatus(t, rec, http.StatusBadRequest)
+	var invalid struct {
+		Valid  bool              `json:"valid"`
+		Fields map[string]string `json:"fields"`
+	}
+	decodeBody(t, rec, &invalid)
+	for _, field := range []string{"name", "ingredients", "instructions"} {
+		if invalid.Fields[field] == "" {
+			t.Errorf("fields = %v, want a problem with %s", invalid.Fields, field)
+		}
+	}
+
+	if count, _ := env.store.Count(context.Background(), store.ListFilter{}); count != 0 {
+		t.Errorf("validating stored %d recipes", count)
+	}
+}
+
+// listNames returns the names of the recipes listed by GET /recipes
+func listNames(t *testing.T, env *testEnv) []string {
+	t.Helper()
+	rec := env.request(http.MethodGet, "/recipes", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var recipes []models.Recipe
+	decodeBody(t, rec, &recipes)
+	names := make([]string, 0, len(recipes))
+	for _, recipe := range recipes {
+		names = append(names, recipe.Name)
+	}
+	return names
+}
+
+func TestDraftsAreOnlyVisibleToTheirOwner(t *testing.T) {
+	env := memoryEnv(t)
+	alice, bob := newCaller("alice"), newCaller("bob")
+
+	rec := env.as(alice).request(http.MethodPost, "/recipes", newRecipe("Pancakes"))
+	expectStatus(t, rec, http.StatusOK)
+	var draft models.Recipe
+	decodeBody(t, rec, &draft)
+	if draft.Status != models.StatusDraft {
+		t.Fatalf("status = %q, want new recipes to be drafts", draft.Status)
+	}
+
+	if names := listNames(t, env.as(alice)); !reflect.DeepEqual(names, []string{"Pancakes"}) {
+		t.Errorf("the owner lists %v, want the draft", names)
+	}
+	expectStatus(t, env.as(alice).request(http.MethodGet, "/recipes/"+draft.ID.Hex(), nil), http.StatusOK)
+
+	if names := listNames(t, env.as(bob)); len(names) != 0 {
+		t.Errorf("another user lists %v, want no drafts", names)
+	}
+	if names := listNames(t, env.as(caller{})); len(names) != 0 {
+		t.Errorf("an anonymous client lists %v, want no drafts", names)
+	}
+	if rec := env.as(bob).request(http.MethodGet, "/recipes/"+draft.ID.Hex(), nil); rec.Code == http.StatusOK {
+		t.Errorf("another user got the draft")
+	}
+	expectStatus(t, env.as(bob).request(http.MethodPost, "/recipes/"+draft.ID.Hex()+"/publish", nil), http.StatusNotFound)
+
+	rec = env.as(alice).request(http.MethodPost, "/recipes/"+draft.ID.Hex()+"/publish", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var published models.Recipe
+	decodeBody(t, rec, &published)
+	if published.Status != models.StatusPublished || !published.PublishedAt.After(draft.PublishedAt) {
+		t.Errorf("status, publishedAt = %q, %v, want published now", published.Status, published.PublishedAt)
+	}
+
+	if names := listNames(t, env.as(bob)); !reflect.DeepEqual(names, []string{"Pancakes"}) {
+		t.Errorf("another user lists %v after publishing, want the recipe", names)
+	}
+	expectStatus(t, env.as(bob).request(http.MethodGet, "/recipes/"+draft.ID.Hex(), nil), http.StatusOK)
+}
+
+func TestPatchStatusIsReservedToTheOwner(t *testing.T) {
+	env := memoryEnv(t)
+	alice, bob := newCaller("alice"), newCaller("bob")
+	admin := newCaller("root")
+	admin.Role = models.RoleAdmin
+	draft := newRecipe("Pancakes")
+	draft.ID = primitive.NewObjectID()
+	draft.UserID = alice.ID
+	draft.Status = models.StatusDraft
+	draft.PublishedAt = time.Now().Add(-time.Hour)
+	env.seed(t, draft)
+
+	published := map[string]string{"status": models.StatusPublished}
+	expectStatus(t, env.as(bob).request(http.MethodPatch, "/recipes/"+draft.ID.Hex(), published), http.StatusNotFound)
+
+	expectStatus(t, env.as(admin).request(http.MethodPatch, "/recipes/"+draft.ID.Hex(), published), http.StatusOK)
+	patched, _ := env.store.GetByID(context.Background(), draft.ID)
+	if patched.Status != models.StatusPublished || !patched.PublishedAt.After(draft.PublishedAt) {
+		t.Errorf("status, publishedAt = %q, %v, want published now", patched.Status, patched.PublishedAt)
+	}
+
+	unpublished := map[string]string{"status": models.StatusDraft}
+	expectStatus(t, env.as(bob).request(http.MethodPatch, "/recipes/"+draft.ID.Hex(), unpublished), http.StatusForbidden)
+	if recipe, _ := env.store.GetByID(context.Background(), draft.ID); recipe.Status != models.StatusPublished {
+		t.Errorf("another user unpublished the recipe")
+	}
+}
+
+func TestGetRecipeNutritionSumsIngredients(t *testing.T) {
+	env := memoryEnv(t)
+	recipe := publishedRecipe("Pancakes", newCaller("alice").ID)
+	recipe.Servings = 4
+	recipe.Nutrition = []models.IngredientNutrition{
+		{Ingredient: "flour", NutritionFacts: models.NutritionFacts{Calories: 364, Protein: 10, Carbs: 76, Fat: 1}},
+		{Ingredient: "milk", NutritionFacts: models.NutritionFacts{Calories: 122, Protein: 8, Carbs: 12, Fat: 5}},
+		{Ingredient: "egg", NutritionFacts: models.NutritionFacts{Calories: 70, Protein: 6, Carbs: 0, Fat: 5}},
+	}
+	recipe = env.seed(t, recipe)
+
+	rec := env.request(http.MethodGet, "/recipes/"+recipe.ID.Hex()+"/nutrition", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var summary models.NutritionSummary
+	decodeBody(t, rec, &summary)
+	want := models.NutritionSummary{
+		Servings:   4,
+		Total:      models.NutritionFacts{Calories: 556, Protein: 24, Carbs: 88, Fat: 11},
+		PerServing: models.NutritionFacts{Calories: 139, Protein: 6, Carbs: 22, Fat: 2.75},
+	}
+	if summary != want {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+}
+
+func TestGetRecipeNutritionWithoutServings(t *testing.T) {
+	env := memoryEnv(t)
+	recipe := publishedRecipe("Toast", newCaller("alice").ID)
+	recipe.Nutrition = []models.IngredientNutrition{
+		{Ingredient: "bread", NutritionFacts: models.NutritionFacts{Calories: 80, Carbs: 15}},
+	}
+	recipe = env.seed(t, recipe)
+
+	rec := env.request(http.MethodGet, "/recipes/"+recipe.ID.Hex()+"/nutrition", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var summary models.NutritionSummary
+	decodeBody(t, rec, &summary)
+	if summary.Servings != 1 || summary.PerServing != summary.Total {
+		t.Errorf("summary = %+v, want a single serving", summary)
+	}
+}
+
+func TestNewRecipeRejectsDuplicates(t *testing.T) {
+	alice := newCaller("alice")
+	env := memoryEnv(t).as(alice)
+	rec := env.request(http.MethodPost, "/recipes", newRecipe("Pancakes"))
+	expectStatus(t, rec, http.StatusOK)
+	var original models.Recipe
+	decodeBody(t, rec, &original)
+
+	// Names only differing in case and spacing are the same
+	rec = env.request(http.MethodPost, "/recipes", newRecipe("  pancakes "))
+	expectCode(t, rec, http.StatusConflict, msgDuplicateRecipe)
+	var conflict map[string]string
+	decodeBody(t, rec, &conflict)
+	if conflict["id"] != original.ID.Hex() {
+		t.Errorf("conflict = %v, want the ID of the existing recipe", conflict)
+	}
+
+	expectStatus(t, env.request(http.MethodPost, "/recipes?allowDuplicate=true", newRecipe("Pancakes")), http.StatusOK)
+	// Another user may use the same name
+	expectStatus(t, env.as(newCaller("bob")).request(http.MethodPost, "/recipes", newRecipe("Pancakes")), http.StatusOK)
+
+	count, _ := env.store.Count(context.Background(), store.ListFilter{UserID: alice.ID})
+	if count != 2 {
+		t.Errorf("alice has %d recipes, want the original and the allowed duplicate", count)
+	}
+}
+
+func TestNewRecipeAllowsDuplicatesWhenNotRejected(t *testing.T) {
+	defer func(reject bool) { rejectDuplicates = reject }(rejectDuplicates)
+	rejectDuplicates = false
+
+	env := memoryEnv(t).as(newCaller("alice"))
+	expectStatus(t, env.request(http.MethodPost, "/recipes", newRecipe("Pancakes")), http.StatusOK)
+	expectStatus(t, env.request(http.MethodPost, "/recipes", newRecipe("Pancakes")), http.StatusOK)
+}
+
+func TestListUserRecipesOnlyListsTheCallersRecipes(t *testing.T) {
+	env := memoryEnv(t)
+	alice, bob := newCaller("alice"), newCaller("bob")
+	owned := map[string][]string{}
+	for _, created := range []struct {
+		owner caller
+		name  string
+	}{{alice, "Pancakes"}, {bob, "Waffles"}, {alice, "Crepes"}, {bob, "Omelette"}} {
+		rec := env.as(created.owner).request(http.MethodPost, "/recipes", newRecipe(created.name))
+		expectStatus(t, rec, http.StatusOK)
+		var recipe models.Recipe
+		decodeBody(t, rec, &recipe)
+		owned[created.owner.Username] = append(owned[created.owner.Username], recipe.ID.Hex())
+	}
+
+	for _, user := range []caller{alice, bob} {
+		rec := env.as(user).request(http.MethodGet, "/user/recipes?sort=publishedAt", nil)
+		expectStatus(t, rec, http.StatusOK)
+		var recipes []models.Recipe
+		decodeBody(t, rec, &recipes)
+		ids := make([]string, 0, len(recipes))
+		for _, recipe := range recipes {
+			ids = append(ids, recipe.ID.Hex())
+		}
+		if !reflect.DeepEqual(ids, owned[user.Username]) {
+			t.Errorf("%s lists %v, want %v", user.Username, ids, owned[user.Username])
+		}
+	}
+}
+
+func TestListUserRecipesTakesTheListParameters(t *testing.T) {
+	env := memoryEnv(t)
+	alice := newCaller("alice")
+	for _, name := range []string{"Pad thai", "Green curry", "Lasagna"} {
+		recipe := publishedRecipe(name, alice.ID)
+		if name != "Lasagna" {
+			recipe.Metadata = map[string]string{"cuisine": "thai"}
+		}
+		env.seed(t, recipe)
+	}
+
+	for _, target := range []string{"/recipes", "/user/recipes"} {
+		rec := env.as(alice).request(http.MethodGet, target+"?metadata.cuisine=thai&sort=name", nil)
+		expectStatus(t, rec, http.StatusOK)
+		if names := namesOf(t, rec); !reflect.DeepEqual(names, []string{"Green curry", "Pad thai"}) {
+			t.Errorf("%s: names = %v, want the thai recipes in a bare array", target, names)
+		}
+
+		page := pageOf(t, env, target+"?metadata.cuisine=thai&sort=name&envelope=true&limit=1")
+		if page.Total != 2 || len(page.Recipes) != 1 || page.Recipes[0].Name != "Green curry" {
+			t.Errorf("%s: envelope = %+v, want the first of 2 thai recipes", target, page)
+		}
+	}
+
+	rec := env.as(alice).request(http.MethodGet, "/user/recipes?metadata.cuisine.name=thai", nil)
+	expectStatus(t, rec, http.StatusBadRequest)
+}
+
+func TestPatchOnlyUpdatesTheGivenFields(t *testing.T) {
+	env := memoryEnv(t)
+	alice := newCaller("alice")
+	recipe := publishedRecipe("Pancakes", alice.ID)
+	recipe.Tags = []string{"breakfast", "sweet"}
+	recipe.Ingredients = []string{"flour", "milk", "eggs"}
+	recipe = env.seed(t, recipe)
+
+	rec := env.as(alice).request(http.MethodPatch, "/recipes/"+recipe.ID.Hex(), map[string]string{"name": "Crepes"})
+	expectStatus(t, rec, http.StatusOK)
+
+	patched, _ := env.store.GetByID(context.Background(), recipe.ID)
+	if patched.Name != "Crepes" {
+		t.Errorf("name = %q, want Crepes", patched.Name)
+	}
+	if !reflect.DeepEqual(patched.Tags, recipe.Tags) {
+		t.Errorf("tags = %v, want them unchanged as %v", patched.Tags, recipe.Tags)
+	}
+	if !reflect.DeepEqual(patched.Ingredients, recipe.Ingredients) {
+		t.Errorf("ingredients = %v, want them unchanged as %v", patched.Ingredients, recipe.Ingredients)
+	}
+}
+
+func TestCloneRecipe(t *testing.T) {
+	env := memoryEnv(t)
+	alice, bob := newCaller("alice"), newCaller("bob")
+	source := publishedRecipe("Pancakes", alice.ID)
+	source.Tags = []string{"breakfast"}
+	source = env.seed(t, source)
+
+	rec := env.as(bob).request(http.MethodPost, "/recipes/"+source.ID.Hex()+"/clone", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var clone models.Recipe
+	decodeBody(t, rec, &clone)
+	if clone.ID.IsZero() || clone.ID == source.ID {
+		t.Errorf("id = %s, want a new ID", clone.ID.Hex())
+	}
+	if clone.UserID != bob.ID {
+		t.Errorf("owner = %s, want the caller %s", clone.UserID.Hex(), bob.ID.Hex())
+	}
+	if clone.Name != "Pancakes (copy)" {
+		t.Errorf("name = %q, want %q", clone.Name, "Pancakes (copy)")
+	}
+	if clone.Status != models.StatusDraft {
+		t.Errorf("status = %q, want a draft", clone.Status)
+	}
+	if !reflect.DeepEqual(clone.Ingredients, source.Ingredients) || !reflect.DeepEqual(clone.Tags, source.Tags) {
+		t.Errorf("ingredients, tags = %v, %v, want those of the source", clone.Ingredients, clone.Tags)
+	}
+
+	stored, err := env.store.GetByID(context.Background(), clone.ID)
+	if err != nil || stored.UserID != bob.ID {
+		t.Errorf("stored clone = %+v, %v", stored, err)
+	}
+	if original, _ := env.store.GetByID(context.Background(), source.ID); original.Name != "Pancakes" || original.UserID != alice.ID {
+		t.Errorf("the source became %q owned by %s", original.Name, original.UserID.Hex())
+	}
+}
+
+func TestCloneUnknownOrHiddenRecipe(t *testing.T) {
+	env := memoryEnv(t)
+	alice, bob := newCaller("alice"), newCaller("bob")
+	draft := publishedRecipe("Pancakes", alice.ID)
+	draft.Status = models.StatusDraft
+	draft = env.seed(t, draft)
+	private := publishedRecipe("Waffles", alice.ID)
+	private.Visibility = models.VisibilityPrivate
+	private = env.seed(t, private)
+
+	env.as(bob)
+	for _, id := range []string{primitive.NewObjectID().Hex(), draft.ID.Hex(), private.ID.Hex()} {
+		rec := env.request(http.MethodPost, "/recipes/"+id+"/clone", nil)
+		expectCode(t, rec, http.StatusNotFound, msgRecipeNotFound)
+	}
+	expectCode(t, env.request(http.MethodPost, "/recipes/not-an-id/clone", nil), http.StatusNotFound, msgInvalidRecipeID)
+
+	if recipes, _ := env.store.List(context.Background(), store.ListFilter{UserID: bob.ID}); len(recipes) != 0 {
+		t.Errorf("bob owns %d recipes, want no clone", len(recipes))
+	}
+}
+
+func TestBulkDeleteMixedBatch(t *testing.T) {
+	env := memoryEnv(t)
+	alice, bob := newCaller("alice"), newCaller("bob")
+	pancakes := env.seed(t, publishedRecipe("Pancakes", alice.ID))
+	crepes := env.seed(t, publishedRecipe("Crepes", alice.ID))
+	waffles := env.seed(t, publishedRecipe("Waffles", bob.ID))
+
+	rec := env.as(alice).request(http.MethodPost, "/recipes/bulk-delete", models.BulkDeleteRequest{
+		IDs: []string{pancakes.ID.Hex(), "not-an-id", waffles.ID.Hex(), crepes.ID.Hex(), "123"},
+	})
+	expectStatus(t, rec, http.StatusOK)
+	var body struct {
+		DeletedCount int      `json:"deletedCount"`
+		InvalidIDs   []string `json:"invalidIds"`
+	}
+	decodeBody(t, rec, &body)
+	if body.DeletedCount != 2 {
+		t.Errorf("deletedCount = %d, want alice's 2 recipes", body.DeletedCount)
+	}
+	if !reflect.DeepEqual(body.InvalidIDs, []string{"not-an-id", "123"}) {
+		t.Errorf("invalidIds = %v, want the malformed IDs", body.InvalidIDs)
+	}
+
+	for _, deleted := range []models.Recipe{pancakes, crepes} {
+		if _, err := env.store.GetByID(context.Background(), deleted.ID); err != store.ErrNotFound {
+			t.Errorf("%s: err = %v, want it deleted", deleted.Name, err)
+		}
+	}
+	if kept, err := env.store.GetByID(context.Background(), waffles.ID); err != nil || kept.UserID != bob.ID {
+		t.Errorf("bob's recipe = %+v, %v, want it kept", kept, err)
+	}
+}
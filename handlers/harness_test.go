@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gabrielsscti/Recipes-API/audit"
+	"github.com/gabrielsscti/Recipes-API/cache"
+	"github.com/gabrielsscti/Recipes-API/events"
+	"github.com/gabrielsscti/Recipes-API/mail"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gabrielsscti/Recipes-API/views"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// caller is the authenticated user a test request is made as, the zero
+// value being an anonymous request
+type caller struct {
+	ID       primitive.ObjectID
+	Username string
+	Role     string
+}
+
+func newCaller(username string) caller {
+	return caller{ID: primitive.NewObjectID(), Username: username, Role: models.RoleUser}
+}
+
+// testEnv serves a RecipesHandler over httptest, with its recipes kept in
+// store and its cache in an in-process Redis
+type testEnv struct {
+	handler *RecipesHandler
+	store   store.RecipeStore
+	redis   *miniredis.Miniredis
+	router  *gin.Engine
+	caller  caller
+}
+
+func newTestEnv(t *testing.T, recipeStore store.RecipeStore) *testEnv {
+	t.Helper()
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("starting miniredis: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	env := &testEnv{
+		store: recipeStore,
+		redis: server,
+		handler: NewRecipesHandler(context.Background(), recipeStore, cache.NewRedisCache(client),
+			events.NewNoopPublisher(), audit.NewNoopRecorder(), views.NewNoopCounter()),
+	}
+	env.router = gin.New()
+	env.router.Use(func(c *gin.Context) {
+		if !env.caller.ID.IsZero() {
+			c.Set("username", env.caller.Username)
+			c.Set("userID", env.caller.ID)
+			c.Set("role", env.caller.Role)
+		}
+	})
+	env.router.Use(TimeZoneMiddleware())
+	env.routes()
+	return env
+}
+
+// memoryEnv is a testEnv over an empty in-memory store
+func memoryEnv(t *testing.T) *testEnv {
+	return newTestEnv(t, store.NewMemoryStore())
+}
+
+// routes registers the recipes routes the way main does, minus the
+// authentication and rate limiting middleware
+func (env *testEnv) routes() {
+	h := env.handler
+	r := env.router
+	r.GET("/recipes", h.ListRecipesHandler)
+	r.GET("/recipes/tags", h.ListTagsHandler)
+	r.GET("/recipes/random", h.RandomRecipeHandler)
+	r.GET("/recipes/recent", h.RecentRecipesHandler)
+	r.GET("/recipes/trending", h.TrendingRecipesHandler)
+	r.GET("/recipes/stats", h.RecipeStatsHandler)
+	r.GET("/recipes/shared", h.GetSharedRecipeHandler)
+	r.POST("/recipes", h.NewRecipeHandler)
+	r.POST("/recipes/validate", h.ValidateRecipeHandler)
+	r.GET("/recipes/search", h.SearchRecipeHandler)
+	r.GET("/recipes/by-ingredients", h.SearchByIngredientsHandler)
+	r.GET("/recipes/slug/:slug", h.GetRecipeBySlugHandler)
+	r.GET("/recipes/:id", h.GetRecipeHandler)
+	r.PUT("/recipes/:id", h.UpdateRecipeHandler)
+	r.PATCH("/recipes/:id", h.PatchRecipeHandler)
+	r.DELETE("/recipes/:id", h.DeleteRecipeHandler)
+	r.POST("/recipes/:id/clone", h.CloneRecipeHandler)
+	r.POST("/recipes/:id/publish", h.PublishRecipeHandler)
+	r.PUT("/recipes/:id/visibility", h.SetVisibilityHandler)
+	r.POST("/recipes/:id/share", h.ShareRecipeHandler)
+	r.GET("/recipes/:id/nutrition", h.GetRecipeNutritionHandler)
+	r.GET("/recipes/:id/related", h.RelatedRecipesHandler)
+	r.GET("/recipes/:id/similar", h.SimilarRecipesHandler)
+	r.GET("/recipes/:id/print", h.PrintRecipeHandler)
+	r.PUT("/recipes/:id/tags", h.ReplaceTagsHandler)
+	r.POST("/recipes/:id/tags", h.AddTagHandler)
+	r.DELETE("/recipes/:id/tags/:tag", h.RemoveTagHandler)
+	r.POST("/recipes/bulk-delete", h.BulkDeleteRecipesHandler)
+	r.GET("/user/recipes", h.ListUserRecipesHandler)
+	r.POST("/shopping-list", h.ShoppingListHandler)
+	r.POST("/admin/tags/rename", h.RenameTagHandler)
+	r.POST("/admin/cache/warm", h.WarmCacheHandler)
+	r.POST("/admin/cache/flush", h.FlushCacheHandler)
+}
+
+// as makes the next requests on behalf of user
+func (env *testEnv) as(user caller) *testEnv {
+	env.caller = user
+	return env
+}
+
+// request serves method on target, sending body as JSON unless it is nil
+func (env *testEnv) request(method, target string, body interface{}) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, target, bytes.NewReader(data))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return env.serve(req)
+}
+
+func (env *testEnv) serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	env.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// seed stores recipe as is, filling in an ID when it has none
+func (env *testEnv) seed(t *testing.T, recipe models.Recipe) models.Recipe {
+	t.Helper()
+	if recipe.ID.IsZero() {
+		recipe.ID = primitive.NewObjectID()
+	}
+	if err := env.store.Create(context.Background(), recipe); err != nil {
+		t.Fatalf("seeding %q: %v", recipe.Name, err)
+	}
+	return recipe
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d; body: %s", rec.Code, want, rec.Body.String())
+	}
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+	}
+}
+
+// namesOf decodes a list of recipes and returns their names in order
+func namesOf(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var recipes []models.Recipe
+	decodeBody(t, rec, &recipes)
+	names := make([]string, 0, len(recipes))
+	for _, recipe := range recipes {
+		names = append(names, recipe.Name)
+	}
+	return names
+}
+
+// fakeCache is a Cache recording the keys set and deleted, failing every
+// call with err when it is set
+type fakeCache struct {
+	mu      sync.Mutex
+	entries map[string]string
+	sets    []string
+	dels    []string
+	err     error
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{entries: make(map[string]string)}
+}
+
+func (fake *fakeCache) Get(key string) (string, error) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.err != nil {
+		return "", fake.err
+	}
+	val, ok := fake.entries[key]
+	if !ok {
+		return "", cache.ErrMiss
+	}
+	return val, nil
+}
+
+func (fake *fakeCache) Set(key string, value string, ttl time.Duration) error {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.err != nil {
+		return fake.err
+	}
+	fake.sets = append(fake.sets, key)
+	fake.entries[key] = value
+	return nil
+}
+
+func (fake *fakeCache) Del(keys ...string) error {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.err != nil {
+		return fake.err
+	}
+	for _, key := range keys {
+		fake.dels = append(fake.dels, key)
+		delete(fake.entries, key)
+	}
+	return nil
+}
+
+func (fake *fakeCache) Lock(key string, ttl time.Duration) (func(), error) {
+	if fake.err != nil {
+		return nil, fake.err
+	}
+	return func() {}, nil
+}
+
+// withCache replaces the cache of the handler served by env
+func (env *testEnv) withCache(recipesCache cache.Cache) *testEnv {
+	env.handler.cache = recipesCache
+	return env
+}
+
+// authEnv serves an AuthHandler over httptest. Its users and invites are
+// usually the mocked collection of an mtest.T, or nil for tests that never
+// reach them.
+type authEnv struct {
+	handler *AuthHandler
+	router  *gin.Engine
+	// clock is the time the handler issues and validates tokens at
+	clock time.Time
+}
+
+func newAuthEnv(t *testing.T, users Collection, invites Collection) *authEnv {
+	t.Helper()
+	signer := &TokenSigner{method: jwt.SigningMethodHS256, signKey: []byte("secret"), verifyKey: []byte("secret")}
+	env := &authEnv{
+		handler: NewAuthHandler(context.Background(), users, invites, signer, audit.NewNoopRecorder(),
+			cache.NewMemoryCache(100, time.Hour), mail.NewLogMailer()),
+		router: gin.New(),
+		clock:  time.Now(),
+	}
+	env.handler.now = func() time.Time { return env.clock }
+
+	h := env.handler
+	env.router.POST("/signin", h.SignInHandler)
+	env.router.POST("/signup", h.SignUpHandler)
+	env.router.POST("/refresh", h.RefreshHandler)
+	env.router.GET("/verify-email", h.VerifyEmailHandler)
+	authorized := env.router.Group("/", h.AuthMiddleware())
+	authorized.GET("/whoami", h.WhoAmIHandler)
+	authorized.GET("/user/:username", h.GetUserHandler)
+	admin := env.router.Group("/", h.AuthMiddleware(), h.AdminMiddleware())
+	admin.GET("/users", h.ListUsersHandler)
+	admin.POST("/admin/invites", h.GenerateInvitesHandler)
+	return env
+}
+
+// token signs an access token for user, valid for ten minutes
+func (env *authEnv) token(t *testing.T, user caller) string {
+	return env.sign(t, user, tokenTypeAccess, env.clock.Add(10*time.Minute))
+}
+
+// sign signs a token of tokenType for user, expiring at expires, in a
+// session started when it was issued
+func (env *authEnv) sign(t *testing.T, user caller, tokenType string, expires time.Time) string {
+	t.Helper()
+	claims := &Claims{
+		Username:     user.Username,
+		UserID:       user.ID,
+		Role:         user.Role,
+		SessionStart: env.clock.Unix(),
+		Type:         tokenType,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expires.Unix(),
+			IssuedAt:  env.clock.Unix(),
+		},
+	}
+	token, err := env.handler.signer.Sign(claims)
+	if err != nil {
+		t.Fatalf("signing a token: %v", err)
+	}
+	return token
+}
+
+// request serves method on target with the Authorization header set to
+// token when it is not empty
+func (env *authEnv) request(method, target string, body interface{}, token string) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, target, bytes.NewReader(data))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	rec := httptest.NewRecorder()
+	env.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// cacheProfile caches the profile of user, so that looking it up does not
+// reach the users collection
+func (env *authEnv) cacheProfile(t *testing.T, user caller, email string) {
+	t.Helper()
+	data, _ := json.Marshal(models.UserProfile{ID: user.ID, Username: user.Username, Email: email, Role: user.Role})
+	if err := env.handler.cache.Set("user:"+user.Username, string(data), time.Hour); err != nil {
+		t.Fatalf("caching %s: %v", user.Username, err)
+	}
+}
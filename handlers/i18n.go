@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Codes of the error messages translated according to Accept-Language.
+// Responses carry the code next to the message, so clients can match on it.
+const (
+	msgInvalidRecipe      = "invalid_recipe"
+	msgInvalidRecipeID    = "invalid_recipe_id"
+	msgRecipeNotFound     = "recipe_not_found"
+	msgSlugNotFound       = "slug_not_found"
+	msgNoRecipeMatches    = "no_recipe_matches"
+	msgInsertRecipeFailed = "insert_recipe_failed"
+	msgNoFieldsToUpdate   = "no_fields_to_update"
+	msgInvalidStatus      = "invalid_status"
+	msgNotOwner           = "not_owner"
+	msgNotOwnerEdit       = "not_owner_edit"
+	msgNotOwnerDelete     = "not_owner_delete"
+	msgNotOwnerShare      = "not_owner_share"
+	msgTooManyTags        = "too_many_tags"
+	msgInvalidMealPlanID  = "invalid_meal_plan_id"
+	msgMealPlanNotFound   = "meal_plan_not_found"
+	msgInvalidCredentials = "invalid_credentials"
+	msgInvalidToken       = "invalid_token"
+	msgRefreshExpired     = "refresh_expired"
+	msgSessionExpired     = "session_expired"
+	msgInvalidUsername    = "invalid_username"
+	msgUsernameTaken      = "username_taken"
+	msgEmailTaken         = "email_taken"
+	msgSignupDisabled     = "signup_disabled"
+	msgInviteRequired     = "invite_required"
+	msgInvalidInvite      = "invalid_invite"
+	msgEmailRequired      = "email_required"
+	msgEmailNotVerified   = "email_not_verified"
+	msgVerificationFailed = "invalid_verification"
+	msgUserNotFound       = "user_not_found"
+	msgAdminRequired      = "admin_required"
+	msgInvalidTimeZone    = "invalid_time_zone"
+	msgDuplicateRecipe    = "duplicate_recipe"
+	msgRecipeLimit        = "recipe_limit_reached"
+	msgInvalidShareLink   = "invalid_share_link"
+	msgShareLinkExpired   = "share_link_expired"
+)
+
+const defaultLanguage = "en"
+
+// translations maps a language, then a message code, to a fmt format
+var translations = map[string]map[string]string{
+	"en": {
+		msgInvalidRecipe:      "Invalid recipe",
+		msgInvalidRecipeID:    "Invalid recipe ID",
+		msgRecipeNotFound:     "No match was found for ID %s",
+		msgSlugNotFound:       "No match was found for slug %s",
+		msgNoRecipeMatches:    "No recipe matches the given filter",
+		msgInsertRecipeFailed: "Error while inserting a new recipe",
+		msgNoFieldsToUpdate:   "No fields to update",
+		msgInvalidStatus:      "Status must be either draft or published",
+		msgNotOwner:           "Only the owner can publish this recipe",
+		msgNotOwnerEdit:       "Only the owner can edit this recipe",
+		msgNotOwnerDelete:     "Only the owner can delete this recipe",
+		msgNotOwnerShare:      "Only the owner can share this recipe",
+		msgTooManyTags:        "At most %d tags are allowed",
+		msgInvalidMealPlanID:  "Invalid meal plan ID",
+		msgMealPlanNotFound:   "No match was found for ID %s",
+		msgInvalidCredentials: "Invalid username or password",
+		msgInvalidToken:       "Invalid token",
+		msgRefreshExpired:     "Refresh token has expired, sign in again",
+		msgSessionExpired:     "Session has expired, sign in again",
+		msgInvalidUsername:    "Username must be 3 to 30 characters long and contain only letters, digits, underscores or hyphens",
+		msgUsernameTaken:      "Username already exists",
+		msgEmailTaken:         "Email already in use",
+		msgSignupDisabled:     "Sign up is disabled",
+		msgInviteRequired:     "An invite code is required to sign up",
+		msgInvalidInvite:      "The invite code is invalid, already used or expired",
+		msgEmailRequired:      "An email is required to sign up",
+		msgEmailNotVerified:   "Verify your email with the link sent to it before signing in",
+		msgVerificationFailed: "The verification link is invalid or expired",
+		msgUserNotFound:       "User not found!",
+		msgAdminRequired:      "Admin privileges required",
+		msgInvalidTimeZone:    "Unknown time zone %s, expected an IANA name such as America/Sao_Paulo",
+		msgDuplicateRecipe:    "You already have a recipe named %s, pass allowDuplicate=true to create it anyway",
+		msgRecipeLimit:        "You have reached the limit of %d recipes",
+		msgInvalidShareLink:   "The share link is invalid",
+		msgShareLinkExpired:   "The share link has expired",
+	},
+	"pt": {
+		msgInvalidRecipe:      "Receita inválida",
+		msgInvalidRecipeID:    "ID de receita inválido",
+		msgRecipeNotFound:     "Nenhuma receita encontrada com o ID %s",
+		msgSlugNotFound:       "Nenhuma receita encontrada com o slug %s",
+		msgNoRecipeMatches:    "Nenhuma receita corresponde ao filtro informado",
+		msgInsertRecipeFailed: "Erro ao inserir a receita",
+		msgNoFieldsToUpdate:   "Nenhum campo para atualizar",
+		msgInvalidStatus:      "O status deve ser draft ou published",
+		msgNotOwner:           "Apenas o dono pode publicar esta receita",
+		msgNotOwnerEdit:       "Apenas o dono pode editar esta receita",
+		msgNotOwnerDelete:     "Apenas o dono pode excluir esta receita",
+		msgNotOwnerShare:      "Apenas o dono pode compartilhar esta receita",
+		msgTooManyTags:        "São permitidas no máximo %d tags",
+		msgInvalidMealPlanID:  "ID de plano de refeições inválido",
+		msgMealPlanNotFound:   "Nenhum plano de refeições encontrado com o ID %s",
+		msgInvalidCredentials: "Usuário ou senha inválidos",
+		msgInvalidToken:       "Token inválido",
+		msgRefreshExpired:     "O token de renovação expirou, entre novamente",
+		msgSessionExpired:     "A sessão expirou, entre novamente",
+		msgInvalidUsername:    "O nome de usuário deve ter de 3 a 30 caracteres e conter apenas letras, números, sublinhados ou hífens",
+		msgUsernameTaken:      "O nome de usuário já existe",
+		msgEmailTaken:         "O email já está em uso",
+		msgSignupDisabled:     "O cadastro está desativado",
+		msgInviteRequired:     "É necessário um código de convite para se cadastrar",
+		msgInvalidInvite:      "O código de convite é inválido, já foi usado ou expirou",
+		msgEmailRequired:      "É necessário um email para se cadastrar",
+		msgEmailNotVerified:   "Verifique seu email com o link enviado antes de entrar",
+		msgVerificationFailed: "O link de verificação é inválido ou expirou",
+		msgUserNotFound:       "Usuário não encontrado!",
+		msgAdminRequired:      "Privilégios de administrador necessários",
+		msgInvalidTimeZone:    "Fuso horário %s desconhecido, use um nome IANA como America/Sao_Paulo",
+		msgDuplicateRecipe:    "Você já tem uma receita chamada %s, passe allowDuplicate=true para criá-la mesmo assim",
+		msgRecipeLimit:        "Você atingiu o limite de %d receitas",
+		msgInvalidShareLink:   "O link de compartilhamento é inválido",
+		msgShareLinkExpired:   "O link de compartilhamento expirou",
+	},
+}
+
+// errorBody builds the error envelope, with the message translated to the
+// language preferred by the client
+func errorBody(c *gin.Context, code string, args ...interface{}) gin.H {
+	return gin.H{
+		"error": translate(preferredLanguage(c.GetHeader("Accept-Language")), code, args...),
+		"code":  code,
+	}
+}
+
+func translate(language string, code string, args ...interface{}) string {
+	format, ok := translations[language][code]
+	if !ok {
+		format = translations[defaultLanguage][code]
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// preferredLanguage picks the supported language with the highest quality
+// in an Accept-Language header such as "pt-BR,pt;q=0.9,en;q=0.8"
+func preferredLanguage(header string) string {
+	type candidate struct {
+		language string
+		quality  float64
+	}
+	candidates := make([]candidate, 0)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		language := strings.ToLower(strings.SplitN(fields[0], "-", 2)[0])
+		if _, ok := translations[language]; !ok {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			if value := strings.TrimSpace(param); strings.HasPrefix(value, "q=") {
+				if parsed, err := strconv.ParseFloat(value[2:], 64); err == nil {
+					quality = parsed
+				}
+			}
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{language, quality})
+		}
+	}
+	if len(candidates) == 0 {
+		return defaultLanguage
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+	return candidates[0].language
+}
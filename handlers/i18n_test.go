@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorsFollowAcceptLanguage(t *testing.T) {
+	env := memoryEnv(t)
+	id := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", "No match was found for ID " + id},
+		{"en-US", "No match was found for ID " + id},
+		{"pt-BR,pt;q=0.9,en;q=0.8", "Nenhuma receita encontrada com o ID " + id},
+		{"fr-FR, en;q=0.5, pt;q=0.7", "Nenhuma receita encontrada com o ID " + id},
+		{"fr, de", "No match was found for ID " + id},
+		{"pt;q=0, en", "No match was found for ID " + id},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/recipes/"+id+"/related", nil)
+		req.Header.Set("Accept-Language", test.acceptLanguage)
+		rec := env.serve(req)
+		expectStatus(t, rec, http.StatusNotFound)
+		var body map[string]string
+		decodeBody(t, rec, &body)
+		if body["error"] != test.want || body["code"] != msgRecipeNotFound {
+			t.Errorf("%q: body = %v, want %q with code %s", test.acceptLanguage, body, test.want, msgRecipeNotFound)
+		}
+	}
+}
+
+func TestEveryMessageIsTranslated(t *testing.T) {
+	for language, messages := range translations {
+		for code := range translations[defaultLanguage] {
+			if messages[code] == "" {
+				t.Errorf("%s has no %s translation", code, language)
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// recipeJSON is a recipe with the given raw instructions
+func recipeJSON(instructions string) json.RawMessage {
+	return json.RawMessage(`{"name": "Pancakes", "ingredients": ["flour", "milk"], "instructions": ` + instructions + `}`)
+}
+
+func TestCreateRecipeInstructionShapes(t *testing.T) {
+	tests := []struct {
+		name         string
+		instructions string
+		want         models.Instructions
+	}{
+		{"legacy", `["mix", "rest", "fry"]`, models.Instructions{"mix", "rest", "fry"}},
+		{"structured", `[{"number": 3, "text": "fry"}, {"number": 1, "text": "mix"}, {"number": 2, "text": "rest"}]`,
+			models.Instructions{"mix", "rest", "fry"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := memoryEnv(t).as(newCaller("alice"))
+			rec := env.request(http.MethodPost, "/recipes", recipeJSON(test.instructions))
+			expectStatus(t, rec, http.StatusOK)
+			var created models.Recipe
+			decodeBody(t, rec, &created)
+			if !reflect.DeepEqual(created.Instructions, test.want) {
+				t.Errorf("instructions = %q, want %q", created.Instructions, test.want)
+			}
+			// Instructions are always answered as a list of strings
+			var raw struct {
+				Instructions []string `json:"instructions"`
+			}
+			decodeBody(t, rec, &raw)
+			if len(raw.Instructions) != len(test.want) {
+				t.Errorf("the answer %s does not list the instructions as strings", rec.Body)
+			}
+		})
+	}
+}
+
+func TestCreateRecipeRejectsInvalidSteps(t *testing.T) {
+	tests := []struct {
+		name         string
+		instructions string
+		want         string
+	}{
+		{"empty legacy step", `["mix", "  ", "fry"]`, "step 2 is empty"},
+		{"empty structured step", `[{"number": 1, "text": "mix"}, {"number": 2, "text": ""}]`, "step 2 is empty"},
+		{"duplicate numbers", `[{"number": 1, "text": "mix"}, {"number": 1, "text": "fry"}]`, ""},
+		{"zero number", `[{"number": 0, "text": "mix"}]`, ""},
+		{"unknown step field", `[{"number": 1, "text": "mix", "minutes": 5}]`, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := memoryEnv(t).as(newCaller("alice"))
+			rec := env.request(http.MethodPost, "/recipes", recipeJSON(test.instructions))
+			expectStatus(t, rec, http.StatusBadRequest)
+			if test.want == "" {
+				return
+			}
+			var body struct {
+				Fields map[string]string `json:"fields"`
+			}
+			decodeBody(t, rec, &body)
+			if body.Fields["instructions"] != test.want {
+				t.Errorf("fields = %v, want instructions: %s", body.Fields, test.want)
+			}
+		})
+	}
+}
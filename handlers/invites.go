@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	signupModeOpen   = "open"
+	signupModeInvite = "invite"
+)
+
+// maxInvites bounds the invites generated by a single request
+const maxInvites = 100
+
+// loadInviteOnly reads SIGNUP_MODE: open, the default, lets anyone sign up,
+// and invite requires an unused invite code
+func loadInviteOnly() bool {
+	switch mode := config.String("SIGNUP_MODE", signupModeOpen); mode {
+	case signupModeOpen:
+		return false
+	case signupModeInvite:
+		return true
+	default:
+		log.Fatalf("Invalid SIGNUP_MODE %q, expected %s or %s", mode, signupModeOpen, signupModeInvite)
+		return false
+	}
+}
+
+// swagger:operation POST /admin/invites auth generateInvites
+// Generates single-use invite codes for signing up when SIGNUP_MODE is invite. Admin only
+// ---
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: The generated invites
+//     '400':
+//         description: Invalid count or expiresIn
+//     '403':
+//         description: Caller is not an admin
+func (handler *AuthHandler) GenerateInvitesHandler(c *gin.Context) {
+	var request models.InviteRequest
+	if err := c.ShouldBindJSON(&request); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if request.Count == 0 {
+		request.Count = 1
+	}
+	if request.Count < 0 || request.Count > maxInvites {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "count must be between 1 and 100"})
+		return
+	}
+
+	now := handler.now()
+	var expiresAt *time.Time
+	if request.ExpiresIn != "" {
+		expiresIn, err := time.ParseDuration(request.ExpiresIn)
+		if err != nil || expiresIn <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expiresIn must be a positive duration such as 72h"})
+			return
+		}
+		expiry := now.Add(expiresIn)
+		expiresAt = &expiry
+	}
+
+	invites := make([]models.Invite, 0, request.Count)
+	for i := 0; i < request.Count; i++ {
+		code, err := newInviteCode()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		invite := models.Invite{
+			ID:        primitive.NewObjectID(),
+			Code:      code,
+			CreatedBy: c.GetString("username"),
+			CreatedAt: now,
+			ExpiresAt: expiresAt,
+		}
+		if _, err := handler.invites.InsertOne(c.Request.Context(), invite); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		invites = append(invites, invite)
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
+
+// newInviteCode returns a random code, long enough not to be guessed
+func newInviteCode() (string, error) {
+	code := make([]byte, 12)
+	if _, err := rand.Read(code); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(code), nil
+}
+
+// claimInvite marks the invite as used by username, and reports false when
+// the code does not exist, was already used or has expired
+func (handler *AuthHandler) claimInvite(ctx context.Context, code string, username string) (bool, error) {
+	now := handler.now()
+	result, err := handler.invites.UpdateOne(ctx, bson.M{
+		"code":   strings.TrimSpace(code),
+		"usedAt": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"expiresAt": bson.M{"$exists": false}},
+			bson.M{"expiresAt": bson.M{"$gt": now}},
+		},
+	}, bson.M{"$set": bson.M{"usedBy": username, "usedAt": now}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// releaseInvite makes an invite claimed by username usable again, when the
+// sign up it was claimed for did not create the account
+func (handler *AuthHandler) releaseInvite(ctx context.Context, code string, username string) {
+	_, err := handler.invites.UpdateOne(ctx, bson.M{
+		"code":   strings.TrimSpace(code),
+		"usedBy": username,
+	}, bson.M{"$unset": bson.M{"usedBy": "", "usedAt": ""}})
+	if err != nil {
+		log.Printf("Warning: could not release the invite claimed by %s: %v", username, err)
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// modified answers an update modifying n documents
+func modified(n int) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "n", Value: n}, bson.E{Key: "nModified", Value: n})
+}
+
+func TestInviteOnlySignUp(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	inviteEnv := func(mt *mtest.T) *authEnv {
+		env := newAuthEnv(mt.T, mt.Coll, mt.DB.Collection("invites"))
+		env.handler.inviteOnly = true
+		env.handler.detailedSignupErrors = true
+		return env
+	}
+	signUp := func(env *authEnv, code string) *httptest.ResponseRecorder {
+		return env.request(http.MethodPost, "/signup", gin.H{"username": "alice", "password": "correct horse", "inviteCode": code}, "")
+	}
+
+	mt.Run("valid code", func(mt *mtest.T) {
+		env := inviteEnv(mt)
+		mt.AddMockResponses(modified(1), cursorOf(mt), mtest.CreateSuccessResponse())
+
+		expectStatus(mt.T, signUp(env, " WELCOME "), http.StatusOK)
+		claim := mt.GetStartedEvent()
+		if claim.CommandName != "update" {
+			mt.Fatalf("first command = %s, want the invite claimed", claim.CommandName)
+		}
+		update := claim.Command.Lookup("updates").Array().Index(0).Value().Document()
+		filter, set := update.Lookup("q").String(), update.Lookup("u").String()
+		for _, want := range []string{`"code": "WELCOME"`, `"usedAt": {"$exists": false}`, `"expiresAt": {"$gt"`} {
+			if !strings.Contains(filter, want) {
+				mt.Errorf("claim filter = %s, want %s", filter, want)
+			}
+		}
+		if !strings.Contains(set, `"usedBy": "alice"`) {
+			mt.Errorf("claim update = %s, want the invite marked used by alice", set)
+		}
+	})
+
+	// The claim only matches unused, unexpired codes, so the database
+	// modifies nothing for either; check each is excluded by the filter
+	rejected := map[string]func(mt *mtest.T, env *authEnv, filter bson.Raw){
+		"reused code": func(mt *mtest.T, env *authEnv, filter bson.Raw) {
+			if exists := filter.Lookup("usedAt", "$exists"); exists.Type != bson.TypeBoolean || exists.Boolean() {
+				mt.Errorf("claim filter = %s, want used codes excluded", filter)
+			}
+		},
+		"expired code": func(mt *mtest.T, env *authEnv, filter bson.Raw) {
+			unexpired := filter.Lookup("$or").Array().Index(1).Value().Document()
+			if after := unexpired.Lookup("expiresAt", "$gt").Time(); !after.Equal(env.clock.Truncate(time.Millisecond)) {
+				mt.Errorf("claim filter = %s, want codes expiring before %v excluded", filter, env.clock)
+			}
+		},
+	}
+	for name, check := range rejected {
+		check := check
+		mt.Run(name, func(mt *mtest.T) {
+			env := inviteEnv(mt)
+			mt.AddMockResponses(modified(0))
+
+			rec := signUp(env, "WELCOME")
+			expectStatus(mt.T, rec, http.StatusForbidden)
+			var body map[string]string
+			decodeBody(mt.T, rec, &body)
+			if body["code"] != msgInvalidInvite {
+				mt.Errorf("body = %v, want %s", body, msgInvalidInvite)
+			}
+			events := mt.GetAllStartedEvents()
+			if len(events) != 1 {
+				mt.Fatalf("%d commands, want only the claim", len(events))
+			}
+			check(mt, env, events[0].Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("q").Document())
+		})
+	}
+
+	mt.Run("missing code", func(mt *mtest.T) {
+		expectStatus(mt.T, signUp(inviteEnv(mt), ""), http.StatusForbidden)
+	})
+
+	mt.Run("code released when the username is taken", func(mt *mtest.T) {
+		env := inviteEnv(mt)
+		mt.AddMockResponses(modified(1), cursorOf(mt, userDoc(primitive.NewObjectID(), "alice", "something else")), modified(1))
+
+		expectStatus(mt.T, signUp(env, "WELCOME"), http.StatusBadRequest)
+		events := mt.GetAllStartedEvents()
+		release := events[len(events)-1]
+		if release.CommandName != "update" || !strings.Contains(release.Command.String(), `"$unset"`) {
+			mt.Errorf("last command = %s, want the invite released", release.Command)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"os"
+	"strings"
+)
+
+// TokenSigner issues and verifies JWTs with a single, configured algorithm
+type TokenSigner struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// LoadTokenSigner configures the signer from the environment. JWT_ALG selects
+// the algorithm (HS256 by default). HS256 uses JWT_SECRET, RS256 uses the
+// JWT_PRIVATE_KEY and JWT_PUBLIC_KEY PEM blocks. A service that only verifies
+// tokens may leave JWT_PRIVATE_KEY unset.
+func LoadTokenSigner() (*TokenSigner, error) {
+	switch alg := os.Getenv("JWT_ALG"); alg {
+	case "", jwt.SigningMethodHS256.Alg():
+		secret := []byte(os.Getenv("JWT_SECRET"))
+		return &TokenSigner{
+			method:    jwt.SigningMethodHS256,
+			signKey:   secret,
+			verifyKey: secret,
+		}, nil
+	case jwt.SigningMethodRS256.Alg():
+		signer := &TokenSigner{method: jwt.SigningMethodRS256}
+
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM(pemFromEnv("JWT_PUBLIC_KEY"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_PUBLIC_KEY: %w", err)
+		}
+		signer.verifyKey = publicKey
+
+		if privatePEM := pemFromEnv("JWT_PRIVATE_KEY"); len(privatePEM) > 0 {
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JWT_PRIVATE_KEY: %w", err)
+			}
+			signer.signKey = privateKey
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALG %q", alg)
+	}
+}
+
+// Sign returns the signed token for claims
+func (signer *TokenSigner) Sign(claims jwt.Claims) (string, error) {
+	if signer.signKey == nil {
+		return "", errors.New("token signing is not configured")
+	}
+	return jwt.NewWithClaims(signer.method, claims).SignedString(signer.signKey)
+}
+
+// Parse verifies the signature of tokenValue and decodes it into claims. Tokens
+// signed with any algorithm other than the configured one are rejected, which
+// prevents algorithm confusion attacks such as an HS256 token signed with the
+// RSA public key. The expiry is left to callers, which check it against their clock.
+func (signer *TokenSigner) Parse(tokenValue string, claims jwt.Claims) (*jwt.Token, error) {
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	return parser.ParseWithClaims(tokenValue, claims, func(token *jwt.Token) (interface{}, error) {
+		if !signer.expectsMethod(token.Method) {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return signer.verifyKey, nil
+	})
+}
+
+// expectsMethod checks both the type and the name of the token's signing
+// method, and never accepts unsigned "none" tokens
+func (signer *TokenSigner) expectsMethod(method jwt.SigningMethod) bool {
+	if method == nil || method == jwt.SigningMethodNone {
+		return false
+	}
+	switch signer.method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if _, ok := method.(*jwt.SigningMethodHMAC); !ok {
+			return false
+		}
+	case *jwt.SigningMethodRSA:
+		if _, ok := method.(*jwt.SigningMethodRSA); !ok {
+			return false
+		}
+	default:
+		return false
+	}
+	return method.Alg() == signer.method.Alg()
+}
+
+// pemFromEnv reads a PEM block from the environment, accepting escaped
+// newlines since multi-line values are awkward to pass in most deployments
+func pemFromEnv(key string) []byte {
+	return []byte(strings.ReplaceAll(os.Getenv(key), `\n`, "\n"))
+}
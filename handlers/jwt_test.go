@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/dgrijalva/jwt-go"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testClaims() *Claims {
+	return &Claims{
+		Username:       "alice",
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()},
+	}
+}
+
+func rsaSigner(t *testing.T) (*TokenSigner, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating a key: %v", err)
+	}
+	return &TokenSigner{method: jwt.SigningMethodRS256, signKey: key, verifyKey: &key.PublicKey}, key
+}
+
+func TestTokenSignerRoundTrip(t *testing.T) {
+	rs256, _ := rsaSigner(t)
+	signers := map[string]*TokenSigner{
+		"HS256": {method: jwt.SigningMethodHS256, signKey: []byte("secret"), verifyKey: []byte("secret")},
+		"RS256": rs256,
+	}
+	for alg, signer := range signers {
+		t.Run(alg, func(t *testing.T) {
+			token, err := signer.Sign(testClaims())
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			claims := &Claims{}
+			parsed, err := signer.Parse(token, claims)
+			if err != nil || !parsed.Valid {
+				t.Fatalf("Parse: %v", err)
+			}
+			if parsed.Header["alg"] != alg || claims.Username != "alice" {
+				t.Errorf("alg, username = %v, %q", parsed.Header["alg"], claims.Username)
+			}
+		})
+	}
+}
+
+func TestTokenSignerRejectsNone(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, testClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("signing with none: %v", err)
+	}
+	signer := &TokenSigner{method: jwt.SigningMethodHS256, signKey: []byte("secret"), verifyKey: []byte("secret")}
+	if _, err := signer.Parse(token, &Claims{}); err == nil {
+		t.Error("a token signed with none was accepted")
+	}
+}
+
+func TestTokenSignerRejectsPublicKeyAsHMACSecret(t *testing.T) {
+	signer, key := rsaSigner(t)
+	publicDER, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims()).SignedString(publicPEM)
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+	if _, err := signer.Parse(token, &Claims{}); err == nil {
+		t.Error("an HS256 token signed with the public key was accepted by an RS256 signer")
+	}
+}
+
+func TestLoadTokenSignerRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating a key: %v", err)
+	}
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicDER, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	t.Setenv("JWT_ALG", "RS256")
+	t.Setenv("JWT_PRIVATE_KEY", strings.ReplaceAll(string(privatePEM), "\n", `\n`))
+	t.Setenv("JWT_PUBLIC_KEY", string(publicPEM))
+	signer, err := LoadTokenSigner()
+	if err != nil {
+		t.Fatalf("LoadTokenSigner: %v", err)
+	}
+	token, err := signer.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if _, err := signer.Parse(token, &Claims{}); err != nil {
+		t.Errorf("Parse: %v", err)
+	}
+
+	t.Setenv("JWT_PRIVATE_KEY", "")
+	verifier, err := LoadTokenSigner()
+	if err != nil {
+		t.Fatalf("LoadTokenSigner without a private key: %v", err)
+	}
+	if _, err := verifier.Sign(testClaims()); err == nil {
+		t.Error("a signer without a private key signed a token")
+	}
+}
+
+func TestLoadTokenSignerRejectsUnknownAlgorithm(t *testing.T) {
+	t.Setenv("JWT_ALG", "none")
+	if _, err := LoadTokenSigner(); err == nil {
+		t.Error("JWT_ALG=none was accepted")
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// markdownEscaper backslash-escapes the characters Markdown treats as markup
+// anywhere in a line, including < so that no raw HTML gets through
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`<`, `\<`, `>`, `\>`, `!`, `\!`, `|`, `\|`, `~`, `\~`, `#`, `\#`,
+)
+
+// markdownBlockStart matches what turns the start of a line into a list, a
+// heading underline or a numbered item, such as "- ", "=" or "2. "
+var markdownBlockStart = regexp.MustCompile(`^([-+=]|\d+[.)])`)
+
+// escapeMarkdown returns text as a single line of literal Markdown text
+func escapeMarkdown(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	text = markdownEscaper.Replace(text)
+	if match := markdownBlockStart.FindString(text); match != "" {
+		text = match[:len(match)-1] + `\` + match[len(match)-1:] + text[len(match):]
+	}
+	return text
+}
+
+// markdownRecipe returns the recipe as a Markdown document, with the name as
+// title, the ingredients as bullets and the instructions as numbered steps
+func markdownRecipe(recipe models.Recipe) string {
+	var doc strings.Builder
+	doc.WriteString("# " + escapeMarkdown(recipe.Name) + "\n\n")
+
+	meta := []string{"Published " + recipe.PublishedAt.Format("January 2, 2006")}
+	if recipe.Servings > 0 {
+		meta = append(meta, strconv.Itoa(recipe.Servings)+" servings")
+	}
+	if recipe.Yield != "" {
+		meta = append(meta, "makes "+escapeMarkdown(recipe.Yield))
+	}
+	if len(recipe.Tags) > 0 {
+		tags := make([]string, len(recipe.Tags))
+		for i, tag := range recipe.Tags {
+			tags[i] = escapeMarkdown(tag)
+		}
+		meta = append(meta, strings.Join(tags, ", "))
+	}
+	doc.WriteString(strings.Join(meta, " · ") + "\n")
+
+	doc.WriteString("\n## Ingredients\n\n")
+	for _, ingredient := range recipe.Ingredients {
+		doc.WriteString("- " + escapeMarkdown(ingredient) + "\n")
+	}
+
+	doc.WriteString("\n## Instructions\n\n")
+	for i, step := range recipe.Instructions {
+		doc.WriteString(strconv.Itoa(i+1) + ". " + escapeMarkdown(step) + "\n")
+	}
+	return doc.String()
+}
+
+// renderMarkdown writes the recipe as text/markdown
+func renderMarkdown(c *gin.Context, status int, recipe models.Recipe) {
+	c.Data(status, "text/markdown; charset=utf-8", []byte(markdownRecipe(recipe)))
+}
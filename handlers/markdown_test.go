@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRecipeAsMarkdown(t *testing.T) {
+	env := memoryEnv(t)
+	recipe := publishedRecipe("Pancakes", newCaller("alice").ID)
+	recipe.PublishedAt = time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	recipe.Servings = 4
+	recipe.Tags = []string{"breakfast", "sweet"}
+	recipe.Ingredients = []string{"200 g flour", "2 eggs"}
+	recipe.Instructions = []string{"Mix everything", "Fry in *hot* butter"}
+	recipe = env.seed(t, recipe)
+
+	rec := env.request(http.MethodGet, "/recipes/"+recipe.ID.Hex()+"?format=markdown", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if contentType := rec.Header().Get("Content-Type"); contentType != "text/markdown; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/markdown", contentType)
+	}
+	want := "# Pancakes\n\n" +
+		"Published March 1, 2022 · 4 servings · breakfast, sweet\n" +
+		"\n## Ingredients\n\n" +
+		"- 200 g flour\n" +
+		"- 2 eggs\n" +
+		"\n## Instructions\n\n" +
+		"1. Mix everything\n" +
+		"2. Fry in \\*hot\\* butter\n"
+	if body := rec.Body.String(); body != want {
+		t.Errorf("body =\n%s\nwant\n%s", body, want)
+	}
+
+	expectStatus(t, env.request(http.MethodGet, "/recipes/"+recipe.ID.Hex()+"?format=html", nil), http.StatusBadRequest)
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	tests := map[string]string{
+		"plain text":                   "plain text",
+		"<script>alert(1)</script>":    `\<script\>alert(1)\</script\>`,
+		"[click](javascript:alert(1))": `\[click\](javascript:alert(1))`,
+		"![img](x)":                    `\!\[img\](x)`,
+		"# not a heading":              `\# not a heading`,
+		"- not a bullet":               `\- not a bullet`,
+		"2. not a step":                `2\. not a step`,
+		"=====":                        `\=====`,
+		"line\nbreak\n\n# heading":     `line break \# heading`,
+		"a_b*c`d|e~f\\g":               "a\\_b\\*c\\`d\\|e\\~f\\\\g",
+	}
+	for text, want := range tests {
+		if got := escapeMarkdown(text); got != want {
+			t.Errorf("escapeMarkdown(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+	"sort"
+	"time"
+)
+
+type MealPlansHandler struct {
+	collection Collection
+	recipes    store.RecipeStore
+	ctx        context.Context
+}
+
+func NewMealPlansHandler(ctx context.Context, collection Collection, recipes store.RecipeStore) *MealPlansHandler {
+	return &MealPlansHandler{
+		collection: collection,
+		recipes:    recipes,
+		ctx:        ctx,
+	}
+}
+
+// swagger:operation POST /mealplans mealplans newMealPlan
+// Create a meal plan for the authenticated user
+// ---
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid input or unknown recipe
+func (handler *MealPlansHandler) NewMealPlanHandler(c *gin.Context) {
+	var plan models.MealPlan
+	if !handler.bindMealPlan(c, &plan) {
+		return
+	}
+
+	plan.ID = primitive.NewObjectID()
+	plan.UserID = currentUserID(c)
+	plan.UpdatedAt = time.Now()
+	if _, err := handler.collection.InsertOne(c.Request.Context(), plan); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error while inserting a new meal plan"})
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// swagger:operation GET /mealplans/{id} mealplans getMealPlan
+// Returns one of the authenticated user's meal plans
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the meal plan
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// - application/yaml
+// responses:
+//     '200':
+//         description: Successful operation
+//     '404':
+//         description: Invalid meal plan ID
+func (handler *MealPlansHandler) GetMealPlanHandler(c *gin.Context) {
+	id := c.Param("id")
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorBody(c, msgInvalidMealPlanID))
+		return
+	}
+
+	var plan models.MealPlan
+	err = handler.collection.FindOne(c.Request.Context(), bson.M{
+		"_id":    objectId,
+		"userId": currentUserID(c),
+	}).Decode(&plan)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, errorBody(c, msgMealPlanNotFound, id))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	render(c, http.StatusOK, plan)
+}
+
+// swagger:operation PUT /mealplans/{id} mealplans updateMealPlan
+// Replace the meals of one of the authenticated user's meal plans
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the meal plan
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid input or unknown recipe
+//     '404':
+//         description: Invalid meal plan ID
+func (handler *MealPlansHandler) UpdateMealPlanHandler(c *gin.Context) {
+	id := c.Param("id")
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorBody(c, msgInvalidMealPlanID))
+		return
+	}
+
+	var plan models.MealPlan
+	if !handler.bindMealPlan(c, &plan) {
+		return
+	}
+	plan.ID = objectId
+	plan.UserID = currentUserID(c)
+	plan.UpdatedAt = time.Now()
+
+	result, err := handler.collection.UpdateOne(c.Request.Context(), bson.M{
+		"_id":    objectId,
+		"userId": plan.UserID,
+	}, bson.D{{Key: "$set", Value: bson.D{
+		{Key: "name", Value: plan.Name},
+		{Key: "meals", Value: plan.Meals},
+		{Key: "updatedAt", Value: plan.UpdatedAt},
+	}}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, errorBody(c, msgMealPlanNotFound, id))
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// swagger:operation GET /mealplans/week mealplans mealPlanWeek
+// Returns the meals the authenticated user planned over the seven days from start
+// ---
+// parameters:
+// - name: start
+//   in: query
+//   description: first day of the week, formatted YYYY-MM-DD
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// - application/yaml
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid start date
+func (handler *MealPlansHandler) WeekHandler(c *gin.Context) {
+	start, err := time.Parse(models.MealPlanDateLayout, c.Query("start"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start must be a date formatted YYYY-MM-DD"})
+		return
+	}
+	from := start.Format(models.MealPlanDateLayout)
+	to := start.AddDate(0, 0, 7).Format(models.MealPlanDateLayout)
+
+	// Dates are stored as YYYY-MM-DD, so they compare in calendar order
+	inWeek := bson.M{"$gte": from, "$lt": to}
+	cur, err := handler.collection.Find(c.Request.Context(), bson.M{
+		"userId":     currentUserID(c),
+		"meals.date": inWeek,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer cur.Close(c.Request.Context())
+
+	plans := make([]models.MealPlan, 0)
+	if err := cur.All(c.Request.Context(), &plans); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	meals := make([]models.PlannedMeal, 0)
+	for _, plan := range plans {
+		for _, meal := range plan.Meals {
+			if meal.Date >= from && meal.Date < to {
+				meals = append(meals, meal)
+			}
+		}
+	}
+	sort.SliceStable(meals, func(i, j int) bool {
+		return meals[i].Date < meals[j].Date
+	})
+
+	render(c, http.StatusOK, models.MealPlanWeek{
+		Start: from,
+		End:   start.AddDate(0, 0, 6).Format(models.MealPlanDateLayout),
+		Meals: meals,
+	})
+}
+
+// bindMealPlan decodes and validates a meal plan, checking that every
+// recipe it references exists and is visible to the caller
+func (handler *MealPlansHandler) bindMealPlan(c *gin.Context, plan *models.MealPlan) bool {
+	if err := c.ShouldBindJSON(plan); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	checked := make(map[primitive.ObjectID]bool)
+	for i, meal := range plan.Meals {
+		if _, err := time.Parse(models.MealPlanDateLayout, meal.Date); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("meal %d: date must be formatted YYYY-MM-DD", i+1)})
+			return false
+		}
+		if checked[meal.RecipeID] {
+			continue
+		}
+
+		recipe, err := handler.recipes.GetByID(c.Request.Context(), meal.RecipeID)
+		if err == nil && !canView(c, recipe) {
+			err = store.ErrNotFound
+		}
+		if err == store.ErrNotFound {
+			c.JSON(http.StatusBadRequest, errorBody(c, msgRecipeNotFound, meal.RecipeID.Hex()))
+			return false
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return false
+		}
+		checked[meal.RecipeID] = true
+	}
+	return true
+}
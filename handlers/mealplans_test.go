@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"github.com/gabrielsscti/Recipes-API/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mealPlansEnv serves the meal plan routes next to the recipe routes of a
+// memory testEnv, with the plans kept in the mocked collection of mt
+func mealPlansEnv(mt *mtest.T) *testEnv {
+	env := memoryEnv(mt.T)
+	h := NewMealPlansHandler(mt.Context(), mt.Coll, env.store)
+	env.router.POST("/mealplans", h.NewMealPlanHandler)
+	env.router.GET("/mealplans/week", h.WeekHandler)
+	env.router.GET("/mealplans/:id", h.GetMealPlanHandler)
+	env.router.PUT("/mealplans/:id", h.UpdateMealPlanHandler)
+	return env
+}
+
+func TestMealPlans(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	alice := newCaller("alice")
+
+	mt.Run("create", func(mt *mtest.T) {
+		env := mealPlansEnv(mt).as(alice)
+		pancakes := env.seed(mt.T, publishedRecipe("Pancakes", alice.ID))
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		rec := env.request(http.MethodPost, "/mealplans", models.MealPlan{
+			Name:  "Week 1",
+			Meals: []models.PlannedMeal{{Date: "2026-10-12", Slot: "breakfast", RecipeID: pancakes.ID}},
+		})
+		expectStatus(mt.T, rec, http.StatusOK)
+		var plan models.MealPlan
+		decodeBody(mt.T, rec, &plan)
+		if plan.ID.IsZero() || plan.UserID != alice.ID {
+			mt.Errorf("plan = %+v, want an ID and the owner set", plan)
+		}
+		insert := mt.GetStartedEvent()
+		if insert.CommandName != "insert" || !strings.Contains(insert.Command.String(), pancakes.ID.Hex()) {
+			mt.Errorf("command = %s, want the plan inserted", insert.Command)
+		}
+	})
+
+	mt.Run("nonexistent recipe", func(mt *mtest.T) {
+		env := mealPlansEnv(mt).as(alice)
+		bob := newCaller("bob")
+		draft := publishedRecipe("Secret", bob.ID)
+		draft.Status = models.StatusDraft
+		env.seed(mt.T, draft)
+
+		for _, recipeID := range []primitive.ObjectID{primitive.NewObjectID(), draft.ID} {
+			rec := env.request(http.MethodPost, "/mealplans", models.MealPlan{
+				Meals: []models.PlannedMeal{{Date: "2026-10-12", RecipeID: recipeID}},
+			})
+			expectStatus(mt.T, rec, http.StatusBadRequest)
+		}
+		if events := mt.GetAllStartedEvents(); len(events) != 0 {
+			mt.Errorf("%d commands were sent for an invalid plan", len(events))
+		}
+	})
+
+	mt.Run("invalid date", func(mt *mtest.T) {
+		env := mealPlansEnv(mt).as(alice)
+		pancakes := env.seed(mt.T, publishedRecipe("Pancakes", alice.ID))
+		rec := env.request(http.MethodPost, "/mealplans", models.MealPlan{
+			Meals: []models.PlannedMeal{{Date: "12/10/2026", RecipeID: pancakes.ID}},
+		})
+		expectStatus(mt.T, rec, http.StatusBadRequest)
+	})
+
+	mt.Run("too many meals", func(mt *mtest.T) {
+		env := mealPlansEnv(mt).as(alice)
+		pancakes := env.seed(mt.T, publishedRecipe("Pancakes", alice.ID))
+		meals := make([]models.PlannedMeal, 101)
+		for i := range meals {
+			meals[i] = models.PlannedMeal{Date: "2026-10-12", RecipeID: pancakes.ID}
+		}
+
+		rec := env.request(http.MethodPost, "/mealplans", models.MealPlan{Meals: meals})
+		expectStatus(mt.T, rec, http.StatusBadRequest)
+		if events := mt.GetAllStartedEvents(); len(events) != 0 {
+			mt.Errorf("%d commands were sent for a plan of 101 meals", len(events))
+		}
+	})
+
+	mt.Run("week", func(mt *mtest.T) {
+		env := mealPlansEnv(mt).as(alice)
+		recipeID := primitive.NewObjectID()
+		meal := func(date string) bson.D {
+			return bson.D{{Key: "date", Value: date}, {Key: "recipeId", Value: recipeID}}
+		}
+		plans := []bson.D{
+			{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "userId", Value: alice.ID},
+				{Key: "meals", Value: bson.A{meal("2026-10-18"), meal("2026-10-19"), meal("2026-10-05")}}},
+			{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "userId", Value: alice.ID},
+				{Key: "meals", Value: bson.A{meal("2026-10-12")}}},
+		}
+		mt.AddMockResponses(cursorOf(mt, plans...))
+
+		rec := env.request(http.MethodGet, "/mealplans/week?start=2026-10-12", nil)
+		expectStatus(mt.T, rec, http.StatusOK)
+		var week models.MealPlanWeek
+		decodeBody(mt.T, rec, &week)
+		dates := make([]string, 0)
+		for _, meal := range week.Meals {
+			dates = append(dates, meal.Date)
+		}
+		if week.Start != "2026-10-12" || week.End != "2026-10-18" || !reflect.DeepEqual(dates, []string{"2026-10-12", "2026-10-18"}) {
+			mt.Errorf("week = %+v, want the meals of October 12 to 18 in order", week)
+		}
+		find := mt.GetStartedEvent()
+		if filter := find.Command.Lookup("filter").String(); !strings.Contains(filter, `"$gte": "2026-10-12"`) ||
+			!strings.Contains(filter, `"$lt": "2026-10-19"`) || !strings.Contains(filter, alice.ID.Hex()) {
+			mt.Errorf("filter = %s, want the meals of the week of the user", filter)
+		}
+
+		expectStatus(mt.T, env.request(http.MethodGet, "/mealplans/week?start=next+week", nil), http.StatusBadRequest)
+	})
+
+	mt.Run("fetch", func(mt *mtest.T) {
+		env := mealPlansEnv(mt).as(alice)
+		expectStatus(mt.T, env.request(http.MethodGet, "/mealplans/not-an-id", nil), http.StatusNotFound)
+
+		mt.AddMockResponses(cursorOf(mt))
+		expectStatus(mt.T, env.request(http.MethodGet, "/mealplans/"+primitive.NewObjectID().Hex(), nil), http.StatusNotFound)
+	})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// metadataFilterPrefix marks the query parameters filtering on metadata, as in metadata.cuisine=thai
+const metadataFilterPrefix = "metadata."
+
+// Limits on the custom metadata of a recipe
+var (
+	maxMetadataKeys        = config.Int("MAX_METADATA_KEYS", 20)
+	maxMetadataValueLength = config.Int("MAX_METADATA_VALUE_LENGTH", 256)
+)
+
+// metadataKeyPattern keeps keys usable as a path in MongoDB filters, hence no dots or dollars
+var metadataKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// validateMetadata reports the first problem with the metadata under errs["metadata"]
+func validateMetadata(metadata map[string]string, errs fieldErrors) {
+	if len(metadata) > maxMetadataKeys {
+		errs["metadata"] = fmt.Sprintf("at most %d metadata keys are allowed", maxMetadataKeys)
+		return
+	}
+	for _, key := range sortedKeys(metadata) {
+		if !metadataKeyPattern.MatchString(key) {
+			errs["metadata"] = fmt.Sprintf("metadata key %q must be 1 to 64 letters, digits, - or _", key)
+			return
+		}
+		if len([]rune(metadata[key])) > maxMetadataValueLength {
+			errs["metadata"] = fmt.Sprintf("metadata %q must be at most %d characters", key, maxMetadataValueLength)
+			return
+		}
+	}
+}
+
+// parseMetadataFilter reads the metadata.<key>=<value> query parameters.
+// Only the first value of a repeated key is used.
+func parseMetadataFilter(c *gin.Context) (map[string]string, error) {
+	var filter map[string]string
+	for param, values := range c.Request.URL.Query() {
+		if !strings.HasPrefix(param, metadataFilterPrefix) || len(values) == 0 {
+			continue
+		}
+		key := strings.TrimPrefix(param, metadataFilterPrefix)
+		if !metadataKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid metadata key %q", key)
+		}
+		if filter == nil {
+			filter = make(map[string]string)
+		}
+		filter[key] = values[0]
+	}
+	return filter, nil
+}
+
+// filterByMetadata keeps the recipes having every key and value of filter
+func filterByMetadata(recipes []models.Recipe, filter map[string]string) []models.Recipe {
+	if len(filter) == 0 {
+		return recipes
+	}
+	matched := make([]models.Recipe, 0, len(recipes))
+	for _, recipe := range recipes {
+		if store.MatchMetadata(recipe.Metadata, filter) {
+			matched = append(matched, recipe)
+		}
+	}
+	return matched
+}
+
+// sortedKeys returns the keys of m in a stable order, for cache keys and error messages
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
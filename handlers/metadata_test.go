@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecipeMetadataIsStored(t *testing.T) {
+	alice := newCaller("alice")
+	env := memoryEnv(t).as(alice)
+	metadata := map[string]string{"cuisine": "thai", "equipment": "wok"}
+	rec := env.request(http.MethodPost, "/recipes", gin.H{
+		"name":         "Pad thai",
+		"ingredients":  []string{"noodles"},
+		"instructions": []string{"fry"},
+		"metadata":     metadata,
+	})
+	expectStatus(t, rec, http.StatusOK)
+	var created models.Recipe
+	decodeBody(t, rec, &created)
+
+	rec = env.request(http.MethodGet, "/recipes/"+created.ID.Hex(), nil)
+	expectStatus(t, rec, http.StatusOK)
+	var got models.Recipe
+	decodeBody(t, rec, &got)
+	if !reflect.DeepEqual(got.Metadata, metadata) {
+		t.Errorf("metadata = %v, want %v", got.Metadata, metadata)
+	}
+}
+
+func TestRecipeMetadataLimits(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= maxMetadataKeys; i++ {
+		tooMany[fmt.Sprintf("key%d", i)] = "value"
+	}
+	tests := map[string]map[string]string{
+		"too many keys": tooMany,
+		"dotted key":    {"a.b": "value"},
+		"operator key":  {"$where": "value"},
+		"long value":    {"notes": strings.Repeat("a", maxMetadataValueLength+1)},
+		"empty key":     {"": "value"},
+	}
+	env := memoryEnv(t).as(newCaller("alice"))
+	for name, metadata := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := env.request(http.MethodPost, "/recipes", gin.H{
+				"name":         "Pad thai",
+				"ingredients":  []string{"noodles"},
+				"instructions": []string{"fry"},
+				"metadata":     metadata,
+			})
+			expectStatus(t, rec, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestRecipesFilteredByMetadata(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	for name, metadata := range map[string]map[string]string{
+		"Pad thai":    {"cuisine": "thai", "equipment": "wok"},
+		"Green curry": {"cuisine": "thai"},
+		"Fried rice":  {"cuisine": "chinese", "equipment": "wok"},
+		"Toast":       nil,
+	} {
+		recipe := publishedRecipe(name, owner)
+		recipe.Metadata = metadata
+		env.seed(t, recipe)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"metadata.cuisine=thai", []string{"Green curry", "Pad thai"}},
+		{"metadata.cuisine=thai&metadata.equipment=wok", []string{"Pad thai"}},
+		{"metadata.equipment=wok", []string{"Fried rice", "Pad thai"}},
+		{"metadata.cuisine=french", []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			rec := env.request(http.MethodGet, "/recipes?sort=name&"+test.query, nil)
+			expectStatus(t, rec, http.StatusOK)
+			if names := namesOf(t, rec); !reflect.DeepEqual(names, test.want) {
+				t.Errorf("names = %v, want %v", names, test.want)
+			}
+		})
+	}
+
+	rec := env.request(http.MethodGet, "/recipes?metadata.$where=1", nil)
+	expectStatus(t, rec, http.StatusBadRequest)
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultPage  = 1
+	defaultLimit = 20
+)
+
+// maxPageSize caps the limit clients may request, which bounds the size of
+// every page. Listings paged by the store, such as GET /user/recipes, also
+// read no more recipes than that, whereas GET /recipes pages the list it
+// caches whole.
+var maxPageSize = loadMaxPageSize()
+
+func loadMaxPageSize() int {
+	value := config.Int("MAX_PAGE_SIZE", 100)
+	if value < 1 {
+		log.Printf("Invalid MAX_PAGE_SIZE %d, using 100", value)
+		return 100
+	}
+	return value
+}
+
+// ListOptions holds the paging and ordering requested by the client
+type ListOptions struct {
+	Page  int
+	Limit int
+	Sort  string
+	// Cursor is set when the client pages with cursor instead of page
+	Cursor *pageCursor
+}
+
+// RecipesPage is the envelope returned by the paginated recipe listings
+type RecipesPage struct {
+	Recipes []models.Recipe `json:"recipes"`
+	// Page is left out when paging with a cursor
+	Page  int `json:"page,omitempty"`
+	Limit int `json:"limit"`
+	Total int `json:"total"`
+	// NextCursor fetches the following page in cursor mode, empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+var recipeSorters = map[string]func(a, b models.Recipe) bool{
+	"name": func(a, b models.Recipe) bool {
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	},
+	"publishedAt": func(a, b models.Recipe) bool {
+		return a.PublishedAt.Before(b.PublishedAt)
+	},
+}
+
+// defaultSort is used when the client does not pass sort, set with DEFAULT_SORT
+var defaultSort = loadDefaultSort()
+
+func loadDefaultSort() string {
+	value := config.String("DEFAULT_SORT", "-publishedAt")
+	if _, ok := recipeSorters[strings.TrimPrefix(value, "-")]; !ok {
+		log.Printf("Invalid DEFAULT_SORT %q, using -publishedAt", value)
+		return "-publishedAt"
+	}
+	return value
+}
+
+// parseListOptions reads the page, limit and sort query parameters,
+// falling back to the defaults when they are missing.
+func parseListOptions(c *gin.Context) (ListOptions, error) {
+	page, limit, err := parsePageParams(c)
+	opts := ListOptions{
+		Page:  page,
+		Limit: limit,
+		Sort:  c.DefaultQuery("sort", defaultSort),
+	}
+	if err != nil {
+		return opts, err
+	}
+
+	if _, ok := recipeSorters[strings.TrimPrefix(opts.Sort, "-")]; !ok {
+		return opts, errors.New("sort must be one of name, publishedAt (prefix with - for descending)")
+	}
+
+	if value, ok := c.GetQuery("cursor"); ok {
+		cursor, err := parseCursor(value, opts.Sort)
+		if err != nil {
+			return opts, err
+		}
+		opts.Cursor = &cursor
+	}
+
+	return opts, nil
+}
+
+// parsePageParams reads the page and limit query parameters shared by every
+// paginated listing. Limits above maxPageSize are clamped, and the effective
+// limit is echoed back in the response envelope. Pages starting past
+// math.MaxInt32 are rejected so that computing the offset cannot overflow.
+func parsePageParams(c *gin.Context) (int, int, error) {
+	page, limit := defaultPage, defaultLimit
+
+	if value := c.Query("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return page, limit, errors.New("page must be a positive integer")
+		}
+		page = parsed
+	}
+
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return page, limit, errors.New("limit must be a positive integer")
+		}
+		limit = parsed
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	if page > math.MaxInt32/limit {
+		return page, limit, errors.New("page is too large")
+	}
+
+	return page, limit, nil
+}
+
+// listPage reads the page of the recipes matching filter requested by opts,
+// having the store sort and skip rather than loading every recipe
+func (handler *RecipesHandler) listPage(ctx context.Context, filter store.ListFilter, opts ListOptions) (RecipesPage, error) {
+	total, err := handler.store.Count(ctx, filter)
+	if err != nil {
+		return RecipesPage{}, err
+	}
+
+	// One more recipe than the page tells whether another page follows
+	request := store.PageRequest{Sort: opts.Sort, Limit: int64(opts.Limit) + 1}
+	if opts.Cursor == nil {
+		request.Skip = int64((opts.Page - 1) * opts.Limit)
+	} else if !opts.Cursor.first() {
+		last := opts.Cursor.recipe()
+		request.After = &last
+	}
+	recipes, err := handler.store.ListPage(ctx, filter, request)
+	if err != nil {
+		return RecipesPage{}, err
+	}
+
+	more := len(recipes) > opts.Limit
+	if more {
+		recipes = recipes[:opts.Limit]
+	}
+	page := RecipesPage{
+		Recipes: recipes,
+		Page:    opts.Page,
+		Limit:   opts.Limit,
+		Total:   int(total),
+	}
+	if opts.Cursor != nil {
+		page.Page = 0
+		if more {
+			page.NextCursor = newCursor(recipes[len(recipes)-1], opts.Sort).encode()
+		}
+	}
+	return page, nil
+}
+
+// paginateRecipes sorts the recipes according to opts and returns the requested
+// page, starting right after the cursor when the client pages with one
+func paginateRecipes(recipes []models.Recipe, opts ListOptions) RecipesPage {
+	less := recipeLess(opts.Sort)
+	sorted := make([]models.Recipe, len(recipes))
+	copy(sorted, recipes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+
+	start := (opts.Page - 1) * opts.Limit
+	if opts.Cursor != nil && opts.Cursor.first() {
+		start = 0
+	} else if opts.Cursor != nil {
+		last := opts.Cursor.recipe()
+		start = sort.Search(len(sorted), func(i int) bool {
+			return less(last, sorted[i])
+		})
+	}
+	if start < 0 {
+		start = 0
+	} else if start > len(sorted) {
+		start = len(sorted)
+	}
+	end := start + opts.Limit
+	if end > len(sorted) {
+		end = len(sorted)
+	}
+
+	page := RecipesPage{
+		Recipes: sorted[start:end],
+		Page:    opts.Page,
+		Limit:   opts.Limit,
+		Total:   len(sorted),
+	}
+	if opts.Cursor != nil {
+		page.Page = 0
+		if end < len(sorted) {
+			page.NextCursor = newCursor(sorted[end-1], opts.Sort).encode()
+		}
+	}
+	return page
+}
+
+// recipeLess orders recipes by the sort parameter. Ties are broken on the ID,
+// in the same direction, so that pages do not overlap or skip recipes sharing
+// the sort value.
+func recipeLess(order string) func(a, b models.Recipe) bool {
+	less := recipeSorters[strings.TrimPrefix(order, "-")]
+	descending := strings.HasPrefix(order, "-")
+	return func(a, b models.Recipe) bool {
+		if descending {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.ID.Hex() < b.ID.Hex()
+	}
+}
+
+// wantsEnvelope reports whether a listing should answer a RecipesPage, asked
+// with envelope=true, rather than the bare array GET /recipes clients have
+// always read
+func wantsEnvelope(c *gin.Context) bool {
+	envelope, _ := strconv.ParseBool(c.Query("envelope"))
+	return envelope
+}
+
+// setPageHeaders sets the pagination headers matching how page was requested
+func setPageHeaders(c *gin.Context, page RecipesPage) {
+	if page.Page == 0 {
+		setCursorHeaders(c, page)
+		return
+	}
+	setPaginationHeaders(c, page.Page, page.Limit, page.Total)
+}
+
+// setPaginationHeaders sets X-Total-Count and an RFC 5988 Link header with the
+// first, prev, next and last pages, matching the envelope of the response
+func setPaginationHeaders(c *gin.Context, page, limit, total int) {
+	c.Header("X-Total-Count", strconv.Itoa(total))
+
+	last := (total + limit - 1) / limit
+	if last < 1 {
+		last = 1
+	}
+
+	pageURL := func(page int) string {
+		u := *c.Request.URL
+		query := u.Query()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(limit))
+		u.RawQuery = query.Encode()
+		return u.RequestURI()
+	}
+
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, pageURL(1))}
+	if page > 1 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(minInt(page-1, last))))
+	}
+	if page < last {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(page+1)))
+	}
+	links = append(links, fmt.Sprintf(`<%s>; rel="last"`, pageURL(last)))
+	c.Header("Link", strings.Join(links, ", "))
+}
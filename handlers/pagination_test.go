@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListRecipesClampsLimit(t *testing.T) {
+	defer func(size int) { maxPageSize = size }(maxPageSize)
+	maxPageSize = 5
+
+	env := memoryEnv(t)
+	alice := newCaller("alice")
+	for i := 0; i < 8; i++ {
+		env.seed(t, publishedRecipe(fmt.Sprintf("Recipe %d", i), alice.ID))
+	}
+
+	for _, target := range []string{"/recipes", "/user/recipes"} {
+		rec := env.as(alice).request(http.MethodGet, target+"?limit=10000&envelope=true", nil)
+		expectStatus(t, rec, http.StatusOK)
+		var page RecipesPage
+		decodeBody(t, rec, &page)
+		if page.Limit != 5 || len(page.Recipes) != 5 || page.Total != 8 {
+			t.Errorf("%s: limit, recipes, total = %d, %d, %d, want 5, 5, 8", target, page.Limit, len(page.Recipes), page.Total)
+		}
+		if link := rec.Header().Get("Link"); !strings.Contains(link, "limit=5") {
+			t.Errorf("%s: Link = %q, want the clamped limit", target, link)
+		}
+	}
+}
+
+// pagedOnlyStore fails List, for listings that must page in the store
+type pagedOnlyStore struct {
+	*store.MemoryStore
+}
+
+func (pagedOnlyStore) List(ctx context.Context, filter store.ListFilter) ([]models.Recipe, error) {
+	return nil, errors.New("List loads every recipe")
+}
+
+func TestUserRecipesArePagedInTheStore(t *testing.T) {
+	env := newTestEnv(t, pagedOnlyStore{store.NewMemoryStore()})
+	alice := newCaller("alice")
+	published := time.Now().UTC().Truncate(time.Millisecond)
+	for _, name := range []string{"banana bread", "Apple pie", "Carrot cake", "apple pie", "Donuts"} {
+		recipe := publishedRecipe(name, alice.ID)
+		recipe.PublishedAt = published
+		env.seed(t, recipe)
+	}
+	env.seed(t, publishedRecipe("Eclairs", newCaller("bob").ID))
+
+	var names []string
+	for cursor, pages := "", 0; pages == 0 || cursor != ""; pages++ {
+		page := pageOf(t, env.as(alice), "/user/recipes?sort=-name&limit=2&envelope=true&cursor="+cursor)
+		if page.Total != 5 || len(page.Recipes) > 2 {
+			t.Fatalf("page %d = %+v, want at most 2 of 5 recipes", pages+1, page)
+		}
+		for _, recipe := range page.Recipes {
+			names = append(names, strings.ToLower(recipe.Name))
+		}
+		cursor = page.NextCursor
+	}
+	if want := []string{"donuts", "carrot cake", "banana bread", "apple pie", "apple pie"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+
+	page := pageOf(t, env, "/user/recipes?sort=name&limit=2&page=3&envelope=true")
+	if page.Page != 3 || len(page.Recipes) != 1 || page.Recipes[0].Name != "Donuts" {
+		t.Errorf("third page = %+v, want Donuts alone", page)
+	}
+}
+
+func TestListRecipesRejectsInvalidPaging(t *testing.T) {
+	env := memoryEnv(t)
+	for _, query := range []string{"page=0", "page=-1", "limit=0", "limit=abc", "sort=views"} {
+		rec := env.request(http.MethodGet, "/recipes?"+query, nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestListRecipesRejectsOversizedPage(t *testing.T) {
+	env := memoryEnv(t)
+	env.seed(t, publishedRecipe("Pancakes", newCaller("alice").ID))
+	for _, target := range []string{"/recipes", "/user/recipes"} {
+		rec := env.as(newCaller("alice")).request(http.MethodGet, target+"?page=150000000000000000&limit=100", nil)
+		expectStatus(t, rec, http.StatusBadRequest)
+	}
+
+	rec := env.request(http.MethodGet, fmt.Sprintf("/recipes?page=%d&limit=1", math.MaxInt32), nil)
+	expectStatus(t, rec, http.StatusOK)
+	if names := namesOf(t, rec); len(names) != 0 {
+		t.Errorf("last allowed page = %v, want no recipes", names)
+	}
+}
+
+func TestListRecipesPagingHeaders(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	for i := 0; i < 5; i++ {
+		env.seed(t, publishedRecipe(fmt.Sprintf("Recipe %d", i), owner))
+	}
+
+	rec := env.request(http.MethodGet, "/recipes?page=2&limit=2", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if total := rec.Header().Get("X-Total-Count"); total != "5" {
+		t.Errorf("X-Total-Count = %q, want 5", total)
+	}
+	want := `</recipes?limit=2&page=1>; rel="first", </recipes?limit=2&page=1>; rel="prev", ` +
+		`</recipes?limit=2&page=3>; rel="next", </recipes?limit=2&page=3>; rel="last"`
+	if link := rec.Header().Get("Link"); link != want {
+		t.Errorf("Link = %q, want %q", link, want)
+	}
+}
+
+func TestPagingHeadersMatchTheEnvelope(t *testing.T) {
+	env := memoryEnv(t)
+	alice := newCaller("alice")
+	for i := 0; i < 7; i++ {
+		env.seed(t, publishedRecipe(fmt.Sprintf("Recipe %d", i), alice.ID))
+	}
+
+	for _, target := range []string{"/recipes", "/user/recipes"} {
+		rec := env.as(alice).request(http.MethodGet, target+"?page=2&limit=3&envelope=true&sort=name", nil)
+		expectStatus(t, rec, http.StatusOK)
+		var page RecipesPage
+		decodeBody(t, rec, &page)
+		if page.Page != 2 || page.Limit != 3 || page.Total != 7 || len(page.Recipes) != 3 {
+			t.Fatalf("%s: page, limit, total, recipes = %d, %d, %d, %d", target, page.Page, page.Limit, page.Total, len(page.Recipes))
+		}
+		if total := rec.Header().Get("X-Total-Count"); total != "7" {
+			t.Errorf("%s: X-Total-Count = %q, want 7", target, total)
+		}
+		want := fmt.Sprintf(`<%[1]s?envelope=true&limit=3&page=1&sort=name>; rel="first", `+
+			`<%[1]s?envelope=true&limit=3&page=1&sort=name>; rel="prev", `+
+			`<%[1]s?envelope=true&limit=3&page=3&sort=name>; rel="next", `+
+			`<%[1]s?envelope=true&limit=3&page=3&sort=name>; rel="last"`, target)
+		if link := rec.Header().Get("Link"); link != want {
+			t.Errorf("%s: Link = %q, want %q", target, link, want)
+		}
+	}
+}
+
+func TestListRecipesOrdersTiesStably(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	published := time.Now().UTC().Truncate(time.Millisecond)
+	seeded := make(map[string]bool)
+	for i := 0; i < 6; i++ {
+		recipe := publishedRecipe(fmt.Sprintf("Recipe %d", i), owner)
+		recipe.PublishedAt = published
+		seeded[env.seed(t, recipe).Name] = true
+	}
+
+	fetch := func() []string {
+		names := make([]string, 0)
+		for page := 1; page <= 2; page++ {
+			rec := env.request(http.MethodGet, fmt.Sprintf("/recipes?page=%d&limit=3", page), nil)
+			expectStatus(t, rec, http.StatusOK)
+			names = append(names, namesOf(t, rec)...)
+		}
+		return names
+	}
+	first := fetch()
+	if second := fetch(); !reflect.DeepEqual(first, second) {
+		t.Errorf("the order changed between fetches: %v then %v", first, second)
+	}
+	listed := make(map[string]bool)
+	for _, name := range first {
+		listed[name] = true
+	}
+	if len(first) != 6 || !reflect.DeepEqual(listed, seeded) {
+		t.Errorf("two pages listed %v, want every recipe once", first)
+	}
+}
+
+func TestListRecipesDefaultSort(t *testing.T) {
+	defer func(sort string) { defaultSort = sort }(defaultSort)
+
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	published := time.Now().UTC().Truncate(time.Millisecond)
+	for i, name := range []string{"banana bread", "Apple pie", "Carrot cake"} {
+		recipe := publishedRecipe(name, owner)
+		recipe.PublishedAt = published.Add(time.Duration(i) * time.Minute)
+		env.seed(t, recipe)
+	}
+
+	if names := listNames(t, env); !reflect.DeepEqual(names, []string{"Carrot cake", "Apple pie", "banana bread"}) {
+		t.Errorf("default order = %v, want the latest published first", names)
+	}
+	defaultSort = "name"
+	if names := listNames(t, env); !reflect.DeepEqual(names, []string{"Apple pie", "banana bread", "Carrot cake"}) {
+		t.Errorf("order with DEFAULT_SORT=name = %v, want case-insensitive names", names)
+	}
+}
+
+func TestLoadDefaultSort(t *testing.T) {
+	t.Setenv("DEFAULT_SORT", "-name")
+	if sort := loadDefaultSort(); sort != "-name" {
+		t.Errorf("DEFAULT_SORT=-name loaded %q", sort)
+	}
+	t.Setenv("DEFAULT_SORT", "views")
+	if sort := loadDefaultSort(); sort != "-publishedAt" {
+		t.Errorf("an invalid DEFAULT_SORT loaded %q, want -publishedAt", sort)
+	}
+}
+
+func TestLoadMaxPageSize(t *testing.T) {
+	t.Setenv("MAX_PAGE_SIZE", "50")
+	if size := loadMaxPageSize(); size != 50 {
+		t.Errorf("MAX_PAGE_SIZE=50 loaded %d", size)
+	}
+	for _, value := range []string{"0", "-5"} {
+		t.Setenv("MAX_PAGE_SIZE", value)
+		if size := loadMaxPageSize(); size != 100 {
+			t.Errorf("MAX_PAGE_SIZE=%s loaded %d, want 100", value, size)
+		}
+	}
+}
+
+// pageOf fetches target as an envelope
+func pageOf(t *testing.T, env *testEnv, target string) RecipesPage {
+	t.Helper()
+	rec := env.request(http.MethodGet, target, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var page RecipesPage
+	decodeBody(t, rec, &page)
+	return page
+}
+
+func TestCursorPagingSurvivesDeletions(t *testing.T) {
+	alice := newCaller("alice")
+	env := memoryEnv(t)
+	ids := make(map[string]string)
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		ids[name] = env.seed(t, publishedRecipe(name, alice.ID)).ID.Hex()
+	}
+
+	first := pageOf(t, env, "/recipes?sort=name&limit=2&cursor=&envelope=true")
+	if first.NextCursor == "" || len(first.Recipes) != 2 {
+		t.Fatalf("first page = %+v, want two recipes and a cursor", first)
+	}
+
+	// Deleting a recipe already seen shifts the offsets of the following ones
+	rec := env.as(alice).request(http.MethodDelete, "/recipes/"+ids["B"], nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	var names []string
+	for _, recipe := range first.Recipes {
+		names = append(names, recipe.Name)
+	}
+	for cursor := first.NextCursor; cursor != ""; {
+		page := pageOf(t, env, "/recipes?sort=name&limit=2&envelope=true&cursor="+cursor)
+		for _, recipe := range page.Recipes {
+			names = append(names, recipe.Name)
+		}
+		cursor = page.NextCursor
+	}
+	if want := []string{"A", "B", "C", "D", "E"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v with nothing skipped", names, want)
+	}
+
+	// while paging by offset skips C, this being what cursors are for
+	second := pageOf(t, env, "/recipes?sort=name&limit=2&page=2&envelope=true")
+	if len(second.Recipes) == 0 || second.Recipes[0].Name != "D" {
+		t.Errorf("second page by offset = %+v, expected to start at D", second.Recipes)
+	}
+}
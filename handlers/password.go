@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"unicode"
+)
+
+// dummyPasswordHash is checked against when the user does not exist, so that
+// sign in takes as long for unknown usernames as for wrong passwords
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// PasswordPolicy lists the rules a new password must follow
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// LoadPasswordPolicy reads the policy from PASSWORD_MIN_LENGTH (default 8)
+// and the PASSWORD_REQUIRE_UPPER, _LOWER, _DIGIT and _SYMBOL flags
+func LoadPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:     config.Int("PASSWORD_MIN_LENGTH", 8),
+		RequireUpper:  config.Bool("PASSWORD_REQUIRE_UPPER", false),
+		RequireLower:  config.Bool("PASSWORD_REQUIRE_LOWER", false),
+		RequireDigit:  config.Bool("PASSWORD_REQUIRE_DIGIT", false),
+		RequireSymbol: config.Bool("PASSWORD_REQUIRE_SYMBOL", false),
+	}
+}
+
+// Check returns the first rule password breaks, or an empty string when it is acceptable
+func (policy PasswordPolicy) Check(password string) string {
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Sprintf("Password must be at least %d characters long", policy.MinLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	switch {
+	case policy.RequireUpper && !upper:
+		return "Password must contain an uppercase letter"
+	case policy.RequireLower && !lower:
+		return "Password must contain a lowercase letter"
+	case policy.RequireDigit && !digit:
+		return "Password must contain a digit"
+	case policy.RequireSymbol && !symbol:
+		return "Password must contain a symbol"
+	}
+	return ""
+}
+
+// hashPassword hashes a password for storage
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// checkPassword reports whether password matches the stored hash, and
+// whether the hash uses the legacy sha256 scheme and should be replaced.
+// Pass an empty hash for unknown users: the work done is the same either way.
+func checkPassword(hash string, password string) (ok bool, legacy bool) {
+	if !strings.HasPrefix(hash, "$2") {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		if hash == "" {
+			return false, false
+		}
+		h := sha256.New()
+		ok = subtle.ConstantTimeCompare([]byte(hash), h.Sum([]byte(password))) == 1
+		return ok, ok
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil, false
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"testing"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	strict := PasswordPolicy{MinLength: 10, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+	tests := []struct {
+		password string
+		want     string
+	}{
+		{"Ab1!", "Password must be at least 10 characters long"},
+		{"correct horse 1!", "Password must contain an uppercase letter"},
+		{"CORRECT HORSE 1!", "Password must contain a lowercase letter"},
+		{"Correct horse!", "Password must contain a digit"},
+		{"Correct horse 1", "Password must contain a symbol"},
+		{"Correct horse 1!", ""},
+		// Length is counted in characters, not bytes
+		{"Äbcdéfgh1!", ""},
+	}
+	for _, test := range tests {
+		if got := strict.Check(test.password); got != test.want {
+			t.Errorf("Check(%q) = %q, want %q", test.password, got, test.want)
+		}
+	}
+
+	if got := (PasswordPolicy{MinLength: 8}).Check("password"); got != "" {
+		t.Errorf("the default policy rejected a long enough password: %q", got)
+	}
+}
+
+func TestLoadPasswordPolicy(t *testing.T) {
+	want := PasswordPolicy{MinLength: 8}
+	if policy := LoadPasswordPolicy(); policy != want {
+		t.Errorf("default policy = %+v, want %+v", policy, want)
+	}
+
+	t.Setenv("PASSWORD_MIN_LENGTH", "12")
+	t.Setenv("PASSWORD_REQUIRE_UPPER", "true")
+	t.Setenv("PASSWORD_REQUIRE_DIGIT", "1")
+	want = PasswordPolicy{MinLength: 12, RequireUpper: true, RequireDigit: true}
+	if policy := LoadPasswordPolicy(); policy != want {
+		t.Errorf("policy = %+v, want %+v", policy, want)
+	}
+}
+
+func TestSignUpEnforcesPasswordPolicy(t *testing.T) {
+	env := newAuthEnv(t, nil, nil)
+	env.handler.passwordPolicy = PasswordPolicy{MinLength: 8, RequireDigit: true}
+
+	rec := env.request(http.MethodPost, "/signup", gin.H{"username": "alice", "password": "correct horse"}, "")
+	expectStatus(t, rec, http.StatusBadRequest)
+	var body map[string]string
+	decodeBody(t, rec, &body)
+	if body["error"] != "Password must contain a digit" {
+		t.Errorf("error = %q, want the broken rule", body["error"])
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"html/template"
+	"net/http"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+var printTemplates = template.Must(template.ParseFS(templateFiles, "templates/*.html"))
+
+// swagger:operation GET /recipes/{id}/print recipes printRecipe
+// Returns the recipe as a printable HTML page
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the recipe
+//   required: true
+//   type: string
+// produces:
+// - text/html
+// responses:
+//     '200':
+//         description: Successful operation
+//     '404':
+//         description: Recipe not found
+func (handler *RecipesHandler) PrintRecipeHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		renderHTML(c, http.StatusNotFound, "not_found.html", id)
+		return
+	}
+
+	recipe, err := handler.store.GetByID(c.Request.Context(), objectId)
+	if err == nil && !canView(c, recipe) {
+		err = store.ErrNotFound
+	}
+	if err == store.ErrNotFound {
+		renderHTML(c, http.StatusNotFound, "not_found.html", id)
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	renderHTML(c, http.StatusOK, "print.html", localize(c, recipe))
+}
+
+// renderHTML executes the named template, escaping the data it is given
+func renderHTML(c *gin.Context, status int, name string, data interface{}) {
+	var page bytes.Buffer
+	if err := printTemplates.ExecuteTemplate(&page, name, data); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(status, "text/html; charset=utf-8", page.Bytes())
+}
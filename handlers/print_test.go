@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPrintRecipeEscapesUserContent(t *testing.T) {
+	env := memoryEnv(t)
+	recipe := publishedRecipe(`Pancakes <script>alert("name")</script>`, newCaller("alice").ID)
+	recipe.Ingredients = []string{"2 eggs", `<img src=x onerror="alert(1)">`}
+	recipe.Tags = []string{"breakfast"}
+	recipe = env.seed(t, recipe)
+
+	rec := env.request(http.MethodGet, "/recipes/"+recipe.ID.Hex()+"/print", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if contentType := rec.Header().Get("Content-Type"); contentType != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want HTML", contentType)
+	}
+	page := rec.Body.String()
+	for _, want := range []string{
+		"<h1>Pancakes &lt;script&gt;alert(&#34;name&#34;)&lt;/script&gt;</h1>",
+		"<li>2 eggs</li>",
+		"<li>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</li>",
+		"<li>bake</li>",
+		"breakfast",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("the page does not contain %s:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "<script>") || strings.Contains(page, "<img") {
+		t.Errorf("the page contains unescaped user content:\n%s", page)
+	}
+}
+
+func TestPrintRecipeNotFound(t *testing.T) {
+	env := memoryEnv(t)
+	rec := env.request(http.MethodGet, "/recipes/000000000000000000000000/print", nil)
+	expectStatus(t, rec, http.StatusNotFound)
+	if !strings.Contains(rec.Body.String(), "No recipe was found for ID 000000000000000000000000.") {
+		t.Errorf("the not found page does not name the ID:\n%s", rec.Body)
+	}
+
+	rec = env.request(http.MethodGet, "/recipes/%3Cb%3Enope/print", nil)
+	expectStatus(t, rec, http.StatusNotFound)
+	if !strings.Contains(rec.Body.String(), "&lt;b&gt;nope") {
+		t.Errorf("the not found page does not contain the escaped ID:\n%s", rec.Body)
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"github.com/gabrielsscti/Recipes-API/config"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// maxRecipesPerUser caps the recipes a user may own, to limit abuse of
+// public instances. 0, the default, means no limit. Admins are not limited.
+//
+// It is a soft limit: the recipes are counted before inserting, so requests
+// creating recipes concurrently may each pass the check and go over it by a
+// few. That is enough to stop abuse without a counter kept in step with every
+// insert and delete.
+var maxRecipesPerUser = config.Int("MAX_RECIPES_PER_USER", 0)
+
+// checkRecipeLimit replies with 403 and reports false when the caller may not
+// create another recipe, going by the recipes they own at this time
+func (handler *RecipesHandler) checkRecipeLimit(c *gin.Context) bool {
+	if maxRecipesPerUser <= 0 || isAdmin(c) {
+		return true
+	}
+
+	count, err := handler.store.Count(c.Request.Context(), store.ListFilter{UserID: currentUserID(c)})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if count >= int64(maxRecipesPerUser) {
+		c.JSON(http.StatusForbidden, errorBody(c, msgRecipeLimit, maxRecipesPerUser))
+		return false
+	}
+	return true
+}
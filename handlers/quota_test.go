@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"net/http"
+	"testing"
+)
+
+func TestRecipeLimitPerUser(t *testing.T) {
+	defer func(limit int) { maxRecipesPerUser = limit }(maxRecipesPerUser)
+	maxRecipesPerUser = 2
+
+	alice := newCaller("alice")
+	env := memoryEnv(t).as(alice)
+	var first models.Recipe
+	for i := 0; i < 2; i++ {
+		rec := env.request(http.MethodPost, "/recipes", newRecipe(fmt.Sprintf("Recipe %d", i)))
+		expectStatus(t, rec, http.StatusOK)
+		if i == 0 {
+			decodeBody(t, rec, &first)
+		}
+	}
+
+	rec := env.request(http.MethodPost, "/recipes", newRecipe("One too many"))
+	expectCode(t, rec, http.StatusForbidden, msgRecipeLimit)
+
+	// The limit is per user, and admins have none
+	expectStatus(t, env.as(newCaller("bob")).request(http.MethodPost, "/recipes", newRecipe("Bob's")), http.StatusOK)
+	admin := newCaller("root")
+	admin.Role = models.RoleAdmin
+	for i := 0; i < 3; i++ {
+		expectStatus(t, env.as(admin).request(http.MethodPost, "/recipes", newRecipe(fmt.Sprintf("Admin's %d", i))), http.StatusOK)
+	}
+
+	// Deleting a recipe makes room for another
+	env.as(alice)
+	expectStatus(t, env.request(http.MethodDelete, "/recipes/"+first.ID.Hex(), nil), http.StatusOK)
+	expectStatus(t, env.request(http.MethodPost, "/recipes", newRecipe("Replacement")), http.StatusOK)
+}
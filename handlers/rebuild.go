@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gabrielsscti/Recipes-API/cache"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"log"
+	"time"
+)
+
+// A cache rebuild holds its lock for at most CACHE_LOCK_TTL, while the other
+// requests missing the same key wait up to CACHE_LOCK_WAIT for it, polling
+// every cacheLockPoll, before querying the store themselves
+var (
+	cacheLockTTL  = config.Duration("CACHE_LOCK_TTL", 10*time.Second)
+	cacheLockWait = config.Duration("CACHE_LOCK_WAIT", 2*time.Second)
+)
+
+const cacheLockPoll = 50 * time.Millisecond
+
+// getOrRebuild returns the cached value for key, or rebuilds and caches it.
+// Only the request holding the lock:<key> lock rebuilds it, so that a missing
+// key under load does not send every request to MongoDB at once.
+func (handler *RecipesHandler) getOrRebuild(ctx context.Context, key string, ttl time.Duration, rebuild func() (string, error)) (string, error) {
+	if val, ok := handler.getCached(key); ok {
+		return val, nil
+	}
+
+	unlock, err := handler.cache.Lock("lock:"+key, cacheLockTTL)
+	if err == cache.ErrLocked {
+		if val, ok := handler.waitForCache(ctx, key); ok {
+			return val, nil
+		}
+		log.Printf("Warning: gave up waiting for %s to be rebuilt", key)
+		return rebuild()
+	} else if err != nil {
+		log.Printf("Warning: could not lock %s, rebuilding it anyway: %v", key, err)
+		return handler.rebuildCached(key, ttl, rebuild)
+	}
+	defer unlock()
+
+	// Another request may have rebuilt it between the miss and the lock
+	if val, ok := handler.getCached(key); ok {
+		return val, nil
+	}
+	return handler.rebuildCached(key, ttl, rebuild)
+}
+
+func (handler *RecipesHandler) rebuildCached(key string, ttl time.Duration, rebuild func() (string, error)) (string, error) {
+	val, err := rebuild()
+	if err != nil {
+		return "", err
+	}
+	handler.setCached(key, val, ttl)
+	return val, nil
+}
+
+// waitForCache polls key until the request holding its lock caches it
+func (handler *RecipesHandler) waitForCache(ctx context.Context, key string) (string, bool) {
+	ticker := time.NewTicker(cacheLockPoll)
+	defer ticker.Stop()
+	deadline := time.After(cacheLockWait)
+	for {
+		select {
+		case <-ctx.Done():
+			return "", false
+		case <-deadline:
+			return "", false
+		case <-ticker.C:
+			if val, ok := handler.getCached(key); ok {
+				return val, true
+			}
+		}
+	}
+}
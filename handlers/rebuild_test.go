@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestListRecipesCachesOnMiss(t *testing.T) {
+	fake := newFakeCache()
+	env := memoryEnv(t).withCache(fake)
+	env.seed(t, publishedRecipe("Pancakes", newCaller("alice").ID))
+
+	for i := 0; i < 2; i++ {
+		rec := env.request(http.MethodGet, "/recipes", nil)
+		expectStatus(t, rec, http.StatusOK)
+	}
+	if !reflect.DeepEqual(fake.sets, []string{"recipes"}) {
+		t.Errorf("sets = %v, want the list cached once", fake.sets)
+	}
+}
+
+func TestRecipeWritesClearTheCache(t *testing.T) {
+	fake := newFakeCache()
+	env := memoryEnv(t).withCache(fake).as(newCaller("alice"))
+
+	rec := env.request(http.MethodPost, "/recipes", newRecipe("Pancakes"))
+	expectStatus(t, rec, http.StatusOK)
+	var created models.Recipe
+	decodeBody(t, rec, &created)
+
+	rec = env.request(http.MethodPut, "/recipes/"+created.ID.Hex(), newRecipe("Crepes"))
+	expectStatus(t, rec, http.StatusOK)
+	rec = env.request(http.MethodDelete, "/recipes/"+created.ID.Hex(), nil)
+	expectStatus(t, rec, http.StatusOK)
+
+	deletes := 0
+	for _, key := range fake.dels {
+		if key == "recipes" {
+			deletes++
+		}
+	}
+	if deletes != 3 {
+		t.Errorf("dels = %v, want recipes cleared by each of the 3 writes", fake.dels)
+	}
+}
+
+func TestListRecipesServesFromStoreWhenCacheIsDown(t *testing.T) {
+	fake := newFakeCache()
+	fake.err = errors.New("dial tcp 127.0.0.1:6379: connect: connection refused")
+	env := memoryEnv(t).withCache(fake)
+	env.seed(t, publishedRecipe("Pancakes", newCaller("alice").ID))
+
+	rec := env.request(http.MethodGet, "/recipes", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var recipes []models.Recipe
+	decodeBody(t, rec, &recipes)
+	if len(recipes) != 1 || recipes[0].Name != "Pancakes" {
+		t.Errorf("list = %+v, want the stored recipe", recipes)
+	}
+}
+
+func TestListRecipesServesFromStoreWhenRedisIsDown(t *testing.T) {
+	env := memoryEnv(t)
+	env.seed(t, publishedRecipe("Pancakes", newCaller("alice").ID))
+	env.redis.Close()
+
+	rec := env.request(http.MethodGet, "/recipes", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var recipes []models.Recipe
+	decodeBody(t, rec, &recipes)
+	if len(recipes) != 1 {
+		t.Errorf("got %d recipes, want 1", len(recipes))
+	}
+}
+
+// slowListStore counts the listings of an in-memory store, each taking delay
+// so that concurrent requests overlap
+type slowListStore struct {
+	*store.MemoryStore
+	delay time.Duration
+	lists int32
+}
+
+func (slow *slowListStore) List(ctx context.Context, filter store.ListFilter) ([]models.Recipe, error) {
+	atomic.AddInt32(&slow.lists, 1)
+	time.Sleep(slow.delay)
+	return slow.MemoryStore.List(ctx, filter)
+}
+
+func TestConcurrentMissesRebuildTheCacheOnce(t *testing.T) {
+	recipes := &slowListStore{MemoryStore: store.NewMemoryStore(), delay: 100 * time.Millisecond}
+	env := newTestEnv(t, recipes)
+	env.seed(t, publishedRecipe("Pancakes", newCaller("alice").ID))
+
+	var wg sync.WaitGroup
+	recs := make([]*httptest.ResponseRecorder, 20)
+	for i := range recs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			recs[i] = env.request(http.MethodGet, "/recipes", nil)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, rec := range recs {
+		expectStatus(t, rec, http.StatusOK)
+		if names := namesOf(t, rec); !reflect.DeepEqual(names, []string{"Pancakes"}) {
+			t.Errorf("names = %v, want Pancakes", names)
+		}
+	}
+	if lists := atomic.LoadInt32(&recipes.lists); lists != 1 {
+		t.Errorf("the store was listed %d times, want once", lists)
+	}
+}
+
+func TestMissesStopWaitingForAStuckRebuild(t *testing.T) {
+	defer func(wait time.Duration) { cacheLockWait = wait }(cacheLockWait)
+	cacheLockWait = 100 * time.Millisecond
+
+	recipes := &slowListStore{MemoryStore: store.NewMemoryStore()}
+	env := newTestEnv(t, recipes)
+	env.seed(t, publishedRecipe("Pancakes", newCaller("alice").ID))
+	// A rebuild that never finishes holds the lock
+	if _, err := env.handler.cache.Lock("lock:recipes", time.Minute); err != nil {
+		t.Fatalf("taking the lock: %v", err)
+	}
+
+	start := time.Now()
+	rec := env.request(http.MethodGet, "/recipes", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if elapsed := time.Since(start); elapsed < cacheLockWait {
+		t.Errorf("answered after %v, want the request to wait %v first", elapsed, cacheLockWait)
+	}
+	if lists := atomic.LoadInt32(&recipes.lists); lists != 1 {
+		t.Errorf("the store was listed %d times, want once after giving up", lists)
+	}
+}
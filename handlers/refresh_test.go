@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"github.com/dgrijalva/jwt-go"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// refresh exchanges token at /refresh, decoding the new tokens when it succeeds
+func (env *authEnv) refresh(t *testing.T, token string) (*httptest.ResponseRecorder, JWTOutput) {
+	t.Helper()
+	rec := env.request(http.MethodPost, "/refresh", nil, token)
+	var output JWTOutput
+	if rec.Code == http.StatusOK {
+		decodeBody(t, rec, &output)
+	}
+	return rec, output
+}
+
+// expectCode checks the status of rec and the code of the error it carries
+func expectCode(t *testing.T, rec *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+	expectStatus(t, rec, status)
+	var body map[string]string
+	decodeBody(t, rec, &body)
+	if body["code"] != code {
+		t.Errorf("body = %v, want code %s", body, code)
+	}
+}
+
+func TestRefreshUntilTheRefreshTokenExpires(t *testing.T) {
+	tests := []struct {
+		name   string
+		expiry time.Duration
+		status int
+		code   string
+	}{
+		{"just issued", 24 * time.Hour, http.StatusOK, ""},
+		{"about to expire", time.Second, http.StatusOK, ""},
+		{"expired", -time.Second, http.StatusUnauthorized, msgRefreshExpired},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := newAuthEnv(t, nil, nil)
+			token := env.sign(t, newCaller("alice"), tokenTypeRefresh, env.clock.Add(test.expiry))
+
+			rec, output := env.refresh(t, token)
+			if test.status != http.StatusOK {
+				expectCode(t, rec, test.status, test.code)
+				return
+			}
+			if output.Token == "" || output.RefreshToken == "" {
+				t.Errorf("output = %+v, want new tokens", output)
+			}
+			if want := env.clock.Add(10 * time.Minute); output.Expires.Unix() != want.Unix() {
+				t.Errorf("expires = %v, want %v", output.Expires, want)
+			}
+		})
+	}
+}
+
+func TestAccessTokensExpireBeforeRefreshTokens(t *testing.T) {
+	env := newAuthEnv(t, nil, nil)
+	alice := newCaller("alice")
+	env.cacheProfile(t, alice, "alice@example.com")
+	rec, output := env.refresh(t, env.sign(t, alice, tokenTypeRefresh, env.clock.Add(time.Hour)))
+	expectStatus(t, rec, http.StatusOK)
+
+	expiries := make(map[string]time.Time)
+	for token, tokenType := range map[string]string{output.Token: tokenTypeAccess, output.RefreshToken: tokenTypeRefresh} {
+		claims := &Claims{}
+		if _, err := env.handler.signer.Parse(token, claims); err != nil {
+			t.Fatalf("parsing the %s token: %v", tokenType, err)
+		}
+		expiries[tokenType] = time.Unix(claims.ExpiresAt, 0)
+	}
+	if want := env.clock.Add(10 * time.Minute); expiries[tokenTypeAccess].Unix() != want.Unix() {
+		t.Errorf("access token expires at %v, want %v", expiries[tokenTypeAccess], want)
+	}
+	if want := env.clock.Add(24 * time.Hour); expiries[tokenTypeRefresh].Unix() != want.Unix() {
+		t.Errorf("refresh token expires at %v, want %v", expiries[tokenTypeRefresh], want)
+	}
+
+	// Once the access token expired, the refresh token still gets a new one
+	env.clock = env.clock.Add(time.Hour)
+	expectStatus(t, env.request(http.MethodGet, "/whoami", nil, output.Token), http.StatusUnauthorized)
+	rec, refreshed := env.refresh(t, output.RefreshToken)
+	expectStatus(t, rec, http.StatusOK)
+	expectStatus(t, env.request(http.MethodGet, "/whoami", nil, refreshed.Token), http.StatusOK)
+}
+
+func TestRefreshRejectsAccessTokens(t *testing.T) {
+	env := newAuthEnv(t, nil, nil)
+	token := env.sign(t, newCaller("alice"), tokenTypeAccess, env.clock.Add(10*time.Second))
+	rec, _ := env.refresh(t, token)
+	expectCode(t, rec, http.StatusUnauthorized, msgInvalidToken)
+}
+
+func TestRefreshStopsAtTheSessionLimit(t *testing.T) {
+	env := newAuthEnv(t, nil, nil)
+	env.handler.sessionMax = 25 * time.Minute
+	signedIn := env.clock
+	sessionEnd := signedIn.Add(25 * time.Minute)
+	token := env.sign(t, newCaller("alice"), tokenTypeRefresh, signedIn.Add(24*time.Hour))
+
+	// Refreshing as each access token expires keeps the session going, the
+	// last token being cut short to end with it
+	wantExpiries := []time.Time{
+		signedIn.Add(10 * time.Minute),
+		signedIn.Add(20 * time.Minute),
+		sessionEnd,
+	}
+	for i, want := range wantExpiries {
+		env.clock = signedIn.Add(time.Duration(i) * 10 * time.Minute)
+		rec, output := env.refresh(t, token)
+		expectStatus(t, rec, http.StatusOK)
+		if output.Expires.Unix() != want.Unix() {
+			t.Errorf("refresh %d expires at %v, want %v", i+1, output.Expires, want)
+		}
+		token = output.RefreshToken
+	}
+
+	env.clock = sessionEnd
+	rec, _ := env.refresh(t, token)
+	expectCode(t, rec, http.StatusUnauthorized, msgSessionExpired)
+}
+
+func TestRefreshRequiresASessionStart(t *testing.T) {
+	env := newAuthEnv(t, nil, nil)
+	claims := &Claims{
+		Username: "alice",
+		Type:     tokenTypeRefresh,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: env.clock.Add(10 * time.Second).Unix(),
+			IssuedAt:  env.clock.Unix(),
+		},
+	}
+	token, err := env.handler.signer.Sign(claims)
+	if err != nil {
+		t.Fatalf("signing a token: %v", err)
+	}
+	rec, _ := env.refresh(t, token)
+	expectCode(t, rec, http.StatusUnauthorized, msgSessionExpired)
+}
+
+func TestRefreshTokensAreNotAccessTokens(t *testing.T) {
+	env := newAuthEnv(t, nil, nil)
+	alice := newCaller("alice")
+	env.cacheProfile(t, alice, "alice@example.com")
+
+	refreshToken := env.sign(t, alice, tokenTypeRefresh, env.clock.Add(10*time.Minute))
+	expectStatus(t, env.request(http.MethodGet, "/whoami", nil, refreshToken), http.StatusUnauthorized)
+	expectStatus(t, env.request(http.MethodGet, "/user/alice", nil, refreshToken), http.StatusUnauthorized)
+
+	// Tokens issued before the type claim existed have none and remain access tokens
+	legacy := env.sign(t, alice, "", env.clock.Add(10*time.Minute))
+	expectStatus(t, env.request(http.MethodGet, "/whoami", nil, legacy), http.StatusOK)
+	rec, _ := env.refresh(t, legacy)
+	expectCode(t, rec, http.StatusUnauthorized, msgInvalidToken)
+}
+
+func TestIssuedTokensCarryTheirType(t *testing.T) {
+	env := newAuthEnv(t, nil, nil)
+	alice := newCaller("alice")
+	env.cacheProfile(t, alice, "alice@example.com")
+
+	rec, output := env.refresh(t, env.sign(t, alice, tokenTypeRefresh, env.clock.Add(10*time.Second)))
+	expectStatus(t, rec, http.StatusOK)
+	for token, want := range map[string]string{output.Token: tokenTypeAccess, output.RefreshToken: tokenTypeRefresh} {
+		claims := &Claims{}
+		if _, err := env.handler.signer.Parse(token, claims); err != nil || claims.Type != want {
+			t.Errorf("type = %q, %v, want %s", claims.Type, err, want)
+		}
+	}
+	expectStatus(t, env.request(http.MethodGet, "/whoami", nil, output.Token), http.StatusOK)
+	expectStatus(t, env.request(http.MethodGet, "/whoami", nil, output.RefreshToken), http.StatusUnauthorized)
+}
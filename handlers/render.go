@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v2"
+	"net/http"
+	"strings"
+)
+
+// render writes obj as YAML when the client accepts application/yaml, and
+// as JSON otherwise
+func render(c *gin.Context, status int, obj interface{}) {
+	if !acceptsYAML(c.GetHeader("Accept")) {
+		c.JSON(status, obj)
+		return
+	}
+
+	// Go through JSON so the YAML document uses the same field names and
+	// value formats (hex IDs, RFC 3339 timestamps) as the JSON responses
+	data, err := json.Marshal(obj)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var generic interface{}
+	json.Unmarshal(data, &generic)
+
+	out, err := yaml.Marshal(generic)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(status, "application/yaml; charset=utf-8", out)
+}
+
+func acceptsYAML(accept string) bool {
+	for _, mediaType := range strings.Split(accept, ",") {
+		mediaType = strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0])
+		if mediaType == "application/yaml" || mediaType == "application/x-yaml" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"gopkg.in/yaml.v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetRecipeNegotiatesFormat(t *testing.T) {
+	env := memoryEnv(t)
+	recipe := env.seed(t, publishedRecipe("Pancakes", newCaller("alice").ID))
+
+	tests := []struct {
+		accept      string
+		contentType string
+		decode      func([]byte, interface{}) error
+	}{
+		{"application/json", "application/json", json.Unmarshal},
+		{"", "application/json", json.Unmarshal},
+		{"application/yaml", "application/yaml", yaml.Unmarshal},
+		{"text/html, application/x-yaml;q=0.9", "application/yaml", yaml.Unmarshal},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/recipes/"+recipe.ID.Hex(), nil)
+		req.Header.Set("Accept", test.accept)
+		rec := env.serve(req)
+		expectStatus(t, rec, http.StatusOK)
+
+		if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, test.contentType) {
+			t.Errorf("Accept %q: Content-Type = %q, want %s", test.accept, contentType, test.contentType)
+		}
+		var body map[string]interface{}
+		if err := test.decode(rec.Body.Bytes(), &body); err != nil {
+			t.Errorf("Accept %q: decoding %s: %v", test.accept, rec.Body.String(), err)
+			continue
+		}
+		if body["name"] != "Pancakes" || body["id"] != recipe.ID.Hex() {
+			t.Errorf("Accept %q: body = %v, want the JSON field names and a hex ID", test.accept, body)
+		}
+	}
+}
+
+func TestListRecipesAsYAML(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	env.seed(t, publishedRecipe("Pancakes", owner))
+	env.seed(t, publishedRecipe("Waffles", owner))
+
+	req := httptest.NewRequest(http.MethodGet, "/recipes", nil)
+	req.Header.Set("Accept", "application/yaml")
+	rec := env.serve(req)
+	expectStatus(t, rec, http.StatusOK)
+
+	var recipes []map[string]interface{}
+	if err := yaml.Unmarshal(rec.Body.Bytes(), &recipes); err != nil || len(recipes) != 2 {
+		t.Fatalf("decoded %d recipes from %s: %v", len(recipes), rec.Body.String(), err)
+	}
+	if recipes[0]["status"] != models.StatusPublished {
+		t.Errorf("recipe = %v, want the JSON field names", recipes[0])
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchCacheTTL bounds how long a cached search is kept. Searches are keyed
+// by their encoded parameters, which cannot be listed to delete them, so
+// writes move on to a new searchGenerationKey instead, leaving the old
+// results to expire.
+const searchCacheTTL = 30 * time.Second
+
+// searchGenerationKey holds the generation search results are cached under
+const searchGenerationKey = "search:generation"
+
+const (
+	matchAny = "any"
+	matchAll = "all"
+)
+
+// fuzzyCandidateLimit bounds the number of recipes scored in Go by a fuzzy
+// search. Fuzzy search is best effort: only recipes sharing two consecutive
+// letters with the query are candidates, and past the limit the oldest win.
+const fuzzyCandidateLimit = 500
+
+type searchQuery struct {
+	Tags   []string
+	Q      string
+	Source string
+	Match  string
+	Fuzzy  bool
+	// Metadata holds the metadata.<key>=<value> filters
+	Metadata map[string]string
+	// Viewer is the caller of a fuzzy search, whose hidden recipes are the
+	// only ones among its candidates
+	Viewer primitive.ObjectID
+}
+
+// parseSearchQuery reads and normalizes the tag, q, source and match query parameters
+// so that equivalent searches share the same cache key.
+func parseSearchQuery(c *gin.Context) (searchQuery, error) {
+	query := searchQuery{
+		Q:      strings.ToLower(strings.TrimSpace(c.Query("q"))),
+		Source: strings.ToLower(strings.TrimSpace(c.Query("source"))),
+		Match:  strings.ToLower(strings.TrimSpace(c.DefaultQuery("match", matchAny))),
+	}
+	if query.Match != matchAny && query.Match != matchAll {
+		return query, errors.New("match must be either any or all")
+	}
+
+	if fuzzy := c.Query("fuzzy"); fuzzy != "" {
+		value, err := strconv.ParseBool(fuzzy)
+		if err != nil {
+			return query, errors.New("fuzzy must be a boolean")
+		}
+		query.Fuzzy = value
+		if value {
+			query.Viewer = currentUserID(c)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, tag := range c.QueryArray("tag") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		query.Tags = append(query.Tags, tag)
+	}
+	sort.Strings(query.Tags)
+
+	metadata, err := parseMetadataFilter(c)
+	if err != nil {
+		return query, err
+	}
+	query.Metadata = metadata
+
+	if len(query.Tags) == 0 && query.Q == "" && query.Source == "" && len(query.Metadata) == 0 {
+		return query, errors.New("at least one tag, q, source or metadata filter must be given")
+	}
+
+	return query, nil
+}
+
+// cacheKey encodes the normalized parameters and generation in the key, such
+// as search:fuzzy=false&generation=...&match=any&q=pancakes&source=&tag=
+func (query searchQuery) cacheKey(generation string) string {
+	values := url.Values{}
+	values.Set("generation", generation)
+	values.Set("tag", strings.Join(query.Tags, ","))
+	values.Set("q", query.Q)
+	values.Set("source", query.Source)
+	values.Set("match", query.Match)
+	values.Set("fuzzy", strconv.FormatBool(query.Fuzzy))
+	if query.Fuzzy {
+		values.Set("viewer", query.Viewer.Hex())
+	}
+	for _, key := range sortedKeys(query.Metadata) {
+		values.Set(metadataFilterPrefix+key, query.Metadata[key])
+	}
+	return "search:" + values.Encode()
+}
+
+// criteria converts the query into store search criteria. Fuzzy searches
+// fetch a bounded set of candidates and match names in Go instead, leaving
+// out the recipes hidden from the viewer so that they do not take the place
+// of visible ones under the limit.
+func (query searchQuery) criteria() store.SearchCriteria {
+	criteria := store.SearchCriteria{
+		Tags:           query.Tags,
+		MatchAllTags:   query.Match == matchAll,
+		SourceContains: query.Source,
+		Metadata:       query.Metadata,
+	}
+	if query.Fuzzy {
+		criteria.NameContainsAny = bigrams(query.Q)
+		criteria.Limit = fuzzyCandidateLimit
+		criteria.PublicOnly = true
+		criteria.OwnedBy = query.Viewer
+	} else {
+		criteria.NameContains = query.Q
+	}
+	return criteria
+}
+
+// fuzzyMatch reports whether every word of the query is within a small edit
+// distance of some word in the recipe name, so "chiken" still finds "Chicken".
+func (query searchQuery) fuzzyMatch(name string) bool {
+	nameWords := strings.Fields(strings.ToLower(name))
+	for _, word := range strings.Fields(query.Q) {
+		maxDistance := 1 + len([]rune(word))/5
+		found := false
+		for _, nameWord := range nameWords {
+			if levenshtein(word, nameWord) <= maxDistance {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// bigrams returns the pairs of consecutive letters of the words of q, or the
+// words themselves when they are a single letter. A name within the edit
+// distance of fuzzyMatch usually keeps one of them.
+func bigrams(q string) []string {
+	pairs := make([]string, 0)
+	for _, word := range strings.Fields(q) {
+		runes := []rune(word)
+		if len(runes) == 1 {
+			pairs = append(pairs, word)
+		}
+		for i := 0; i+1 < len(runes); i++ {
+			pairs = append(pairs, string(runes[i:i+2]))
+		}
+	}
+	return pairs
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got := normalizeTags([]string{" Italian", "PASTA", "italian", "", "  ", "Quick Meals"})
+	want := []string{"italian", "pasta", "quick meals"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeTags = %q, want %q", got, want)
+	}
+}
+
+func TestSearchMatchesTagsCaseInsensitively(t *testing.T) {
+	env := memoryEnv(t).as(newCaller("alice"))
+	recipe := newRecipe("Carbonara")
+	recipe.Tags = []string{"Italian", " PASTA "}
+	recipe.Status = models.StatusPublished
+	rec := env.request(http.MethodPost, "/recipes", recipe)
+	expectStatus(t, rec, http.StatusOK)
+	var created models.Recipe
+	decodeBody(t, rec, &created)
+	if !reflect.DeepEqual(created.Tags, []string{"italian", "pasta"}) {
+		t.Fatalf("tags = %q, want them normalized", created.Tags)
+	}
+
+	for _, query := range []string{"tag=ITALIAN", "tag=%20Pasta%20", "tag=Italian&tag=pAsTa&match=all"} {
+		rec := env.request(http.MethodGet, "/recipes/search?"+query, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var recipes []models.Recipe
+		decodeBody(t, rec, &recipes)
+		if len(recipes) != 1 || recipes[0].ID != created.ID {
+			t.Errorf("%s: got %d recipes, want the carbonara", query, len(recipes))
+		}
+	}
+}
+
+func TestSearchByIngredients(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	seed := func(name string, ingredients ...string) {
+		recipe := publishedRecipe(name, owner)
+		recipe.Ingredients = ingredients
+		env.seed(t, recipe)
+	}
+	seed("Omelette", "3 Eggs", "butter", "salt")
+	seed("Pancakes", "2 eggs", "flour", "milk")
+	seed("Bread", "flour", "water", "yeast")
+	seed("Salad", "lettuce", "olive oil")
+	draft := publishedRecipe("Quiche", owner)
+	draft.Ingredients = []string{"eggs", "flour"}
+	draft.Status = models.StatusDraft
+	env.seed(t, draft)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"ingredient=egg", []string{"Omelette", "Pancakes"}},
+		{"ingredient=EGG&ingredient=yeast", []string{"Bread", "Omelette", "Pancakes"}},
+		{"ingredient=egg&ingredient=flour&match=all", []string{"Pancakes"}},
+		{"ingredient=egg&ingredient=yeast&match=ALL", []string{}},
+		{"ingredient=+flour+&match=any", []string{"Bread", "Pancakes"}},
+	}
+	for _, test := range tests {
+		rec := env.request(http.MethodGet, "/recipes/by-ingredients?"+test.query, nil)
+		expectStatus(t, rec, http.StatusOK)
+		names := namesOf(t, rec)
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("%s: recipes = %v, want %v", test.query, names, test.want)
+		}
+	}
+
+	for _, query := range []string{"", "ingredient=+", "ingredient=egg&match=most"} {
+		rec := env.request(http.MethodGet, "/recipes/by-ingredients?"+query, nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+// countingSearchStore counts the searches reaching an in-memory store
+type countingSearchStore struct {
+	*store.MemoryStore
+	searches int
+}
+
+func (counting *countingSearchStore) Search(ctx context.Context, criteria store.SearchCriteria) ([]models.Recipe, error) {
+	counting.searches++
+	return counting.MemoryStore.Search(ctx, criteria)
+}
+
+func TestRepeatedSearchesAreServedFromTheCache(t *testing.T) {
+	recipes := &countingSearchStore{MemoryStore: store.NewMemoryStore()}
+	env := newTestEnv(t, recipes)
+	owner := newCaller("alice").ID
+	carbonara := publishedRecipe("Carbonara", owner)
+	carbonara.Tags = []string{"italian"}
+	env.seed(t, carbonara)
+	tacos := publishedRecipe("Tacos", owner)
+	tacos.Tags = []string{"mexican"}
+	env.seed(t, tacos)
+
+	search := func(query string) []string {
+		t.Helper()
+		rec := env.request(http.MethodGet, "/recipes/search?"+query, nil)
+		expectStatus(t, rec, http.StatusOK)
+		return namesOf(t, rec)
+	}
+
+	first := search("tag=italian")
+	if recipes.searches != 1 {
+		t.Fatalf("the first search reached the store %d times, want 1", recipes.searches)
+	}
+	cached := 0
+	for _, key := range env.redis.Keys() {
+		if strings.HasPrefix(key, "search:") && key != searchGenerationKey {
+			cached++
+		}
+	}
+	if cached != 1 {
+		t.Errorf("Redis holds %d searches, want 1: %v", cached, env.redis.Keys())
+	}
+
+	// Equivalent parameters share the cache key
+	for _, query := range []string{"tag=italian", "tag=%20ITALIAN&match=any"} {
+		if names := search(query); !reflect.DeepEqual(names, first) {
+			t.Errorf("%s: cached search = %v, want %v", query, names, first)
+		}
+	}
+	if recipes.searches != 1 {
+		t.Errorf("identical searches reached the store %d times, want once", recipes.searches)
+	}
+
+	if names := search("tag=mexican"); !reflect.DeepEqual(names, []string{"Tacos"}) {
+		t.Errorf("tag=mexican = %v, want Tacos", names)
+	}
+	if recipes.searches != 2 {
+		t.Errorf("a search for another tag reached the store %d times in all, want a miss", recipes.searches)
+	}
+}
+
+func TestFuzzySearchToleratesATypo(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	for _, name := range []string{"Chicken", "Beef Stew", "Chickpea Salad", "Kitchen Sink Cookies"} {
+		env.seed(t, publishedRecipe(name, owner))
+	}
+
+	rec := env.request(http.MethodGet, "/recipes/search?q=chiken&fuzzy=true", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if names := namesOf(t, rec); !reflect.DeepEqual(names, []string{"Chicken"}) {
+		t.Errorf("fuzzy q=chiken = %v, want only Chicken", names)
+	}
+
+	rec = env.request(http.MethodGet, "/recipes/search?q=chiken", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if names := namesOf(t, rec); len(names) != 0 {
+		t.Errorf("q=chiken = %v, want no match without fuzzy", names)
+	}
+}
+
+func TestFuzzySearchOnlyScoresNamesSharingLetters(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	for i := 0; i < fuzzyCandidateLimit; i++ {
+		env.seed(t, publishedRecipe(fmt.Sprintf("Waffles %d", i), owner))
+	}
+	env.seed(t, publishedRecipe("Chicken", owner))
+
+	rec := env.request(http.MethodGet, "/recipes/search?q=chiken&fuzzy=true", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if names := namesOf(t, rec); !reflect.DeepEqual(names, []string{"Chicken"}) {
+		t.Errorf("fuzzy q=chiken = %v, want Chicken past %d older recipes", names, fuzzyCandidateLimit)
+	}
+}
+
+func TestFuzzySearchLeavesHiddenRecipesOutOfTheCandidates(t *testing.T) {
+	env := memoryEnv(t)
+	alice, bob := newCaller("alice"), newCaller("bob")
+	for i := 0; i < fuzzyCandidateLimit; i++ {
+		draft := publishedRecipe(fmt.Sprintf("Chicken %d", i), bob.ID)
+		draft.Status = models.StatusDraft
+		env.seed(t, draft)
+	}
+	env.seed(t, publishedRecipe("Chicken Curry", alice.ID))
+
+	rec := env.as(alice).request(http.MethodGet, "/recipes/search?q=chiken&fuzzy=true", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if names := namesOf(t, rec); !reflect.DeepEqual(names, []string{"Chicken Curry"}) {
+		t.Errorf("fuzzy q=chiken = %v, want Chicken Curry past %d drafts of bob", names, fuzzyCandidateLimit)
+	}
+
+	rec = env.as(bob).request(http.MethodGet, "/recipes/search?q=chiken&fuzzy=true", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if names := namesOf(t, rec); len(names) != fuzzyCandidateLimit {
+		t.Errorf("bob found %d recipes, want his %d drafts", len(names), fuzzyCandidateLimit)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"chiken", "chicken", 1},
+		{"chicken", "chicken", 0},
+		{"", "stew", 4},
+		{"crêpe", "crepe", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, test := range tests {
+		if got := levenshtein(test.a, test.b); got != test.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
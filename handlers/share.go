@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Share links last SHARE_TTL unless the caller asks otherwise, and at most SHARE_MAX_TTL
+var (
+	shareTTL       = config.Duration("SHARE_TTL", 24*time.Hour)
+	shareMaxTTL    = config.Duration("SHARE_MAX_TTL", 7*24*time.Hour)
+	sharePublicURL = config.String("PUBLIC_URL", "http://localhost:8080")
+)
+
+// shareSecret signs the share links, set with SHARE_SECRET
+var shareSecret = loadShareSecret()
+
+var (
+	errInvalidShareToken = errors.New("invalid share token")
+	errShareTokenExpired = errors.New("share token expired")
+)
+
+// loadShareSecret falls back to JWT_SECRET, then to a random secret, in which
+// case links stop working when the API restarts
+func loadShareSecret() []byte {
+	if secret := os.Getenv("SHARE_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	log.Printf("Warning: SHARE_SECRET is not set, share links will not survive a restart")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatal(err)
+	}
+	return secret
+}
+
+// signShareToken returns a token naming the recipe and the expiry, followed
+// by their HMAC, so that neither can be changed without the secret
+func signShareToken(id primitive.ObjectID, expires time.Time) string {
+	payload := make([]byte, len(id)+8)
+	copy(payload, id[:])
+	binary.BigEndian.PutUint64(payload[len(id):], uint64(expires.Unix()))
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(shareMAC(payload))
+}
+
+// verifyShareToken returns the recipe named by a token signed by signShareToken
+func verifyShareToken(token string, now time.Time) (primitive.ObjectID, error) {
+	var id primitive.ObjectID
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return id, errInvalidShareToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(payload) != len(id)+8 {
+		return id, errInvalidShareToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(mac, shareMAC(payload)) {
+		return id, errInvalidShareToken
+	}
+
+	copy(id[:], payload)
+	expires := time.Unix(int64(binary.BigEndian.Uint64(payload[len(id):])), 0)
+	if !now.Before(expires) {
+		return id, errShareTokenExpired
+	}
+	return id, nil
+}
+
+func shareMAC(payload []byte) []byte {
+	mac := hmac.New(sha256.New, shareSecret)
+	mac.Write([]byte("share:"))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// swagger:operation POST /recipes/{id}/share recipes shareRecipe
+// Returns a signed link giving anyone access to the recipe, even private or
+// draft, until it expires. Send {"expiresIn": "48h"} to choose how long
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the recipe
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: The share link
+//     '400':
+//         description: Invalid expiresIn
+//     '403':
+//         description: The recipe belongs to another user
+//     '404':
+//         description: Invalid recipe ID
+func (handler *RecipesHandler) ShareRecipeHandler(c *gin.Context) {
+	id := c.Param("id")
+
+	var request models.ShareRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	ttl := shareTTL
+	if request.ExpiresIn != "" {
+		parsed, err := time.ParseDuration(request.ExpiresIn)
+		if err != nil || parsed <= 0 || parsed > shareMaxTTL {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("expiresIn must be a positive duration of at most %s", shareMaxTTL)})
+			return
+		}
+		ttl = parsed
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorBody(c, msgInvalidRecipeID))
+		return
+	}
+
+	recipe, err := handler.store.GetByID(c.Request.Context(), objectId)
+	if err == nil && !canView(c, recipe) {
+		err = store.ErrNotFound
+	}
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgRecipeNotFound, id))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if recipe.UserID != currentUserID(c) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, errorBody(c, msgNotOwnerShare))
+		return
+	}
+
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	token := signShareToken(recipe.ID, expires)
+	c.JSON(http.StatusOK, models.ShareLink{
+		URL:       strings.TrimRight(sharePublicURL, "/") + "/recipes/shared?token=" + url.QueryEscape(token),
+		Token:     token,
+		ExpiresAt: expires.UTC(),
+	})
+}
+
+// swagger:operation GET /recipes/shared recipes getSharedRecipe
+// Returns the recipe of a share link, whatever its visibility
+// ---
+// parameters:
+// - name: token
+//   in: query
+//   description: token of the share link
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// - application/yaml
+// responses:
+//     '200':
+//         description: Successful operation
+//     '403':
+//         description: The token is invalid or has been tampered with
+//     '404':
+//         description: The recipe no longer exists
+//     '410':
+//         description: The link has expired
+func (handler *RecipesHandler) GetSharedRecipeHandler(c *gin.Context) {
+	objectId, err := verifyShareToken(c.Query("token"), time.Now())
+	if err == errShareTokenExpired {
+		c.JSON(http.StatusGone, errorBody(c, msgShareLinkExpired))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusForbidden, errorBody(c, msgInvalidShareLink))
+		return
+	}
+
+	recipe, err := handler.store.GetByID(c.Request.Context(), objectId)
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgRecipeNotFound, objectId.Hex()))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	render(c, http.StatusOK, localize(c, recipe))
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareLinks(t *testing.T) {
+	alice := newCaller("alice")
+	env := memoryEnv(t)
+	secret := publishedRecipe("Secret sauce", alice.ID)
+	secret.Status = models.StatusDraft
+	secret.Visibility = models.VisibilityPrivate
+	secret = env.seed(t, secret)
+
+	rec := env.as(alice).request(http.MethodPost, "/recipes/"+secret.ID.Hex()+"/share", gin.H{"expiresIn": "1h"})
+	expectStatus(t, rec, http.StatusOK)
+	var link models.ShareLink
+	decodeBody(t, rec, &link)
+	if until := time.Until(link.ExpiresAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("the link expires in %v, want an hour", until)
+	}
+	shared, err := url.Parse(link.URL)
+	if err != nil || shared.Path != "/recipes/shared" || shared.Query().Get("token") != link.Token {
+		t.Fatalf("URL = %q, want /recipes/shared with the token", link.URL)
+	}
+
+	// Anyone with the link sees the recipe, private and draft as it is
+	rec = env.as(caller{}).request(http.MethodGet, shared.RequestURI(), nil)
+	expectStatus(t, rec, http.StatusOK)
+	var got models.Recipe
+	decodeBody(t, rec, &got)
+	if got.ID != secret.ID {
+		t.Errorf("shared recipe = %s, want %s", got.ID.Hex(), secret.ID.Hex())
+	}
+	if rec := env.request(http.MethodGet, "/recipes/"+secret.ID.Hex(), nil); rec.Code == http.StatusOK {
+		t.Error("the private recipe is served without the link")
+	}
+}
+
+func TestShareLinkTokens(t *testing.T) {
+	env := memoryEnv(t)
+	recipe := env.seed(t, publishedRecipe("Pancakes", newCaller("alice").ID))
+	valid := signShareToken(recipe.ID, time.Now().Add(time.Hour))
+
+	// Pointing a valid signature at another recipe breaks it
+	other := primitive.NewObjectID()
+	payload := base64.RawURLEncoding.EncodeToString(append(other[:], make([]byte, 8)...))
+	tampered := payload + valid[strings.Index(valid, "."):]
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+		code   string
+	}{
+		{"valid", valid, http.StatusOK, ""},
+		{"expired", signShareToken(recipe.ID, time.Now().Add(-time.Second)), http.StatusGone, msgShareLinkExpired},
+		{"tampered", tampered, http.StatusForbidden, msgInvalidShareLink},
+		{"truncated signature", valid[:len(valid)-2], http.StatusForbidden, msgInvalidShareLink},
+		{"garbage", "not-a-token", http.StatusForbidden, msgInvalidShareLink},
+		{"missing", "", http.StatusForbidden, msgInvalidShareLink},
+		{"deleted recipe", signShareToken(other, time.Now().Add(time.Hour)), http.StatusNotFound, msgRecipeNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := env.request(http.MethodGet, "/recipes/shared?token="+url.QueryEscape(test.token), nil)
+			if test.status == http.StatusOK {
+				expectStatus(t, rec, http.StatusOK)
+				return
+			}
+			expectCode(t, rec, test.status, test.code)
+		})
+	}
+}
+
+func TestShareRecipeIsForTheOwner(t *testing.T) {
+	alice := newCaller("alice")
+	env := memoryEnv(t)
+	recipe := env.seed(t, publishedRecipe("Pancakes", alice.ID))
+	target := "/recipes/" + recipe.ID.Hex() + "/share"
+
+	expectCode(t, env.as(newCaller("bob")).request(http.MethodPost, target, nil), http.StatusForbidden, msgNotOwnerShare)
+	for _, expiresIn := range []string{"soon", "-1h", "0s", (shareMaxTTL + time.Hour).String()} {
+		rec := env.as(alice).request(http.MethodPost, target, gin.H{"expiresIn": expiresIn})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expiresIn %s: status = %d, want 400", expiresIn, rec.Code)
+		}
+	}
+}
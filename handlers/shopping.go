@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math/big"
+	"net/http"
+	"sort"
+)
+
+// swagger:operation POST /shopping-list recipes shoppingList
+// Combine the ingredients of several recipes into a single shopping list
+// ---
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation, unknown recipes are listed as missing
+//     '400':
+//         description: Invalid input
+//     '404':
+//         description: None of the recipes were found
+func (handler *RecipesHandler) ShoppingListHandler(c *gin.Context) {
+	var request models.ShoppingListRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(request.Recipes))
+	for _, requested := range request.Recipes {
+		if objectId, err := primitive.ObjectIDFromHex(requested.ID); err == nil {
+			ids = append(ids, objectId)
+		}
+	}
+	// An empty list of IDs would list every recipe
+	recipes := make([]models.Recipe, 0)
+	if len(ids) > 0 {
+		var err error
+		recipes, err = handler.store.List(c.Request.Context(), store.ListFilter{IDs: ids})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	byID := make(map[primitive.ObjectID]models.Recipe, len(recipes))
+	for _, recipe := range recipes {
+		if canView(c, recipe) {
+			byID[recipe.ID] = recipe
+		}
+	}
+
+	type itemKey struct{ name, unit string }
+	items := make(map[itemKey]*models.Ingredient)
+	missing := make([]string, 0)
+	for _, requested := range request.Recipes {
+		objectId, err := primitive.ObjectIDFromHex(requested.ID)
+		recipe, ok := byID[objectId]
+		if err != nil || !ok {
+			missing = append(missing, requested.ID)
+			continue
+		}
+
+		scale := big.NewRat(1, 1)
+		if requested.Servings > 0 && recipe.Servings > 0 {
+			scale = big.NewRat(int64(requested.Servings), int64(recipe.Servings))
+		}
+
+		for _, line := range recipe.Ingredients {
+			ingredient := models.ParseIngredient(line)
+			if ingredient.Name == "" {
+				continue
+			}
+			key := itemKey{ingredient.Name, ingredient.Unit}
+			item, ok := items[key]
+			if !ok {
+				item = &models.Ingredient{Name: ingredient.Name, Unit: ingredient.Unit}
+				items[key] = item
+			}
+			if amount := ingredient.Scaled(scale); amount != nil {
+				item.Quantity = item.Quantity.Plus(amount)
+			}
+		}
+	}
+
+	if len(missing) == len(request.Recipes) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "None of the recipes were found", "missing": missing})
+		return
+	}
+
+	list := models.ShoppingList{
+		Items:   make([]models.Ingredient, 0, len(items)),
+		Missing: missing,
+	}
+	for _, item := range items {
+		list.Items = append(list.Items, *item)
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		if list.Items[i].Name != list.Items[j].Name {
+			return list.Items[i].Name < list.Items[j].Name
+		}
+		return list.Items[i].Unit < list.Items[j].Unit
+	})
+
+	render(c, http.StatusOK, list)
+}
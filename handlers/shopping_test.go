@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// shoppingItem is a shopping list entry as a string, such as "1.5 cup flour"
+func shoppingItem(item models.Ingredient) string {
+	text := item.Name
+	if item.Unit != "" {
+		text = item.Unit + " " + text
+	}
+	if item.Quantity != nil {
+		value, _ := item.Quantity.Rat().Float64()
+		text = strconv.FormatFloat(value, 'f', -1, 64) + " " + text
+	}
+	return text
+}
+
+func TestShoppingListMergesDuplicateIngredients(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	pancakes := publishedRecipe("Pancakes", owner)
+	pancakes.Ingredients = []string{"1 cup flour", "2 eggs", "1/2 tsp salt", "milk"}
+	pancakes = env.seed(t, pancakes)
+	waffles := publishedRecipe("Waffles", owner)
+	waffles.Ingredients = []string{"1 1/2 cups Flour", "1 eggs", "2 tbsp salt", "milk"}
+	waffles = env.seed(t, waffles)
+
+	rec := env.request(http.MethodPost, "/shopping-list", models.ShoppingListRequest{Recipes: []models.ShoppingListRecipe{
+		{ID: pancakes.ID.Hex()},
+		{ID: waffles.ID.Hex()},
+		{ID: "unknown"},
+	}})
+	expectStatus(t, rec, http.StatusOK)
+	var list models.ShoppingList
+	decodeBody(t, rec, &list)
+
+	got := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		got = append(got, shoppingItem(item))
+	}
+	want := []string{"3 eggs", "2.5 cup flour", "milk", "2 tbsp salt", "0.5 tsp salt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("items = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(list.Missing, []string{"unknown"}) {
+		t.Errorf("missing = %q, want the unknown ID", list.Missing)
+	}
+}
+
+func TestShoppingListScalesServings(t *testing.T) {
+	env := memoryEnv(t)
+	recipe := publishedRecipe("Pancakes", newCaller("alice").ID)
+	recipe.Ingredients = []string{"2 cups flour"}
+	recipe.Servings = 4
+	recipe = env.seed(t, recipe)
+
+	rec := env.request(http.MethodPost, "/shopping-list", models.ShoppingListRequest{Recipes: []models.ShoppingListRecipe{
+		{ID: recipe.ID.Hex(), Servings: 6},
+	}})
+	expectStatus(t, rec, http.StatusOK)
+	var list models.ShoppingList
+	decodeBody(t, rec, &list)
+	if len(list.Items) != 1 || shoppingItem(list.Items[0]) != "3 cup flour" {
+		t.Errorf("items = %+v, want 3 cups of flour", list.Items)
+	}
+}
+
+func TestShoppingListScalesThirdsExactly(t *testing.T) {
+	defer func(format string) { models.QuantityFormat = format }(models.QuantityFormat)
+	models.QuantityFormat = models.QuantityFraction
+
+	env := memoryEnv(t)
+	recipe := publishedRecipe("Lemonade", newCaller("alice").ID)
+	recipe.Ingredients = []string{"1/3 cup sugar"}
+	recipe.Servings = 1
+	recipe = env.seed(t, recipe)
+
+	rec := env.request(http.MethodPost, "/shopping-list", models.ShoppingListRequest{Recipes: []models.ShoppingListRecipe{
+		{ID: recipe.ID.Hex(), Servings: 3},
+	}})
+	expectStatus(t, rec, http.StatusOK)
+	if body := rec.Body.String(); !strings.Contains(body, `"quantity":"1"`) {
+		t.Errorf("body = %s, want exactly 1 cup of sugar", body)
+	}
+}
+
+func TestShoppingListKeepsUnparsableQuantitiesInTheName(t *testing.T) {
+	env := memoryEnv(t)
+	recipe := publishedRecipe("Cake", newCaller("alice").ID)
+	recipe.Ingredients = []string{"0x10 g sugar", "1e400 g flour"}
+	recipe = env.seed(t, recipe)
+
+	rec := env.request(http.MethodPost, "/shopping-list", models.ShoppingListRequest{Recipes: []models.ShoppingListRecipe{{ID: recipe.ID.Hex()}}})
+	expectStatus(t, rec, http.StatusOK)
+	var list models.ShoppingList
+	decodeBody(t, rec, &list)
+	for _, item := range list.Items {
+		if item.Quantity != nil {
+			t.Errorf("%q has the quantity %s, want none", item.Name, item.Quantity)
+		}
+	}
+	if len(list.Items) != 2 {
+		t.Errorf("items = %v, want 0x10 g sugar and 1e400 g flour", list.Items)
+	}
+}
+
+func TestShoppingListWithoutKnownRecipes(t *testing.T) {
+	env := memoryEnv(t)
+	rec := env.request(http.MethodPost, "/shopping-list", models.ShoppingListRequest{Recipes: []models.ShoppingListRecipe{{ID: "unknown"}}})
+	expectStatus(t, rec, http.StatusNotFound)
+}
+
+// listOnlyStore fails every read but List, so recipes can only be read at once
+type listOnlyStore struct {
+	*store.MemoryStore
+	lists int
+}
+
+func (recipes *listOnlyStore) List(ctx context.Context, filter store.ListFilter) ([]models.Recipe, error) {
+	recipes.lists++
+	return recipes.MemoryStore.List(ctx, filter)
+}
+
+func (*listOnlyStore) GetByID(ctx context.Context, id primitive.ObjectID) (models.Recipe, error) {
+	return models.Recipe{}, errors.New("recipes must be read at once")
+}
+
+func TestShoppingListReadsTheRecipesAtOnce(t *testing.T) {
+	recipes := &listOnlyStore{MemoryStore: store.NewMemoryStore()}
+	env := newTestEnv(t, recipes)
+	owner := newCaller("alice").ID
+	requested := make([]models.ShoppingListRecipe, 0)
+	for _, name := range []string{"Pancakes", "Waffles", "Crepes"} {
+		recipe := publishedRecipe(name, owner)
+		recipe.Ingredients = []string{"1 cup flour"}
+		recipe = env.seed(t, recipe)
+		requested = append(requested, models.ShoppingListRecipe{ID: recipe.ID.Hex()})
+	}
+
+	rec := env.request(http.MethodPost, "/shopping-list", models.ShoppingListRequest{Recipes: requested})
+	expectStatus(t, rec, http.StatusOK)
+	var list models.ShoppingList
+	decodeBody(t, rec, &list)
+	if len(list.Items) != 1 || shoppingItem(list.Items[0]) != "3 cup flour" {
+		t.Errorf("items = %+v, want 3 cups of flour", list.Items)
+	}
+	if recipes.lists != 1 {
+		t.Errorf("recipes were listed %d times, want once", recipes.lists)
+	}
+}
+
+func TestShoppingListBoundsTheNumberOfRecipes(t *testing.T) {
+	env := memoryEnv(t)
+	for _, count := range []int{0, 51} {
+		requested := make([]models.ShoppingListRecipe, count)
+		for i := range requested {
+			requested[i].ID = primitive.NewObjectID().Hex()
+		}
+		rec := env.request(http.MethodPost, "/shopping-list", models.ShoppingListRequest{Recipes: requested})
+		expectStatus(t, rec, http.StatusBadRequest)
+	}
+}
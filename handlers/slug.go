@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"net/http"
+)
+
+// slugAttempts bounds the retries of a create racing another one for the same slug
+const slugAttempts = 3
+
+// regenerateSlugs is set with SLUG_ON_RENAME. preserve, the default, keeps the
+// slug of renamed recipes so that shared links keep working, and regenerate
+// derives a new slug from the new name.
+var regenerateSlugs = loadSlugPolicy()
+
+func loadSlugPolicy() bool {
+	switch value := config.String("SLUG_ON_RENAME", "preserve"); value {
+	case "preserve":
+		return false
+	case "regenerate":
+		return true
+	default:
+		log.Printf("Invalid SLUG_ON_RENAME %q, using preserve", value)
+		return false
+	}
+}
+
+// swagger:operation GET /recipes/slug/{slug} recipes getRecipeBySlug
+// Returns a recipe by its slug
+// ---
+// parameters:
+// - name: slug
+//   in: path
+//   description: slug of the recipe, such as pao-de-queijo
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// - application/yaml
+// responses:
+//     '200':
+//         description: Successful operation
+//     '404':
+//         description: No recipe has this slug
+func (handler *RecipesHandler) GetRecipeBySlugHandler(c *gin.Context) {
+	slug := c.Param("slug")
+
+	recipe, err := handler.store.GetBySlug(c.Request.Context(), slug)
+	if err == nil && !canView(c, recipe) {
+		err = store.ErrNotFound
+	}
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgSlugNotFound, slug))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	handler.countView(c, recipe)
+	render(c, http.StatusOK, localize(c, recipe))
+}
+
+// uniqueSlug derives a slug from name that no recipe other than exclude uses
+func (handler *RecipesHandler) uniqueSlug(ctx context.Context, name string, exclude primitive.ObjectID) (string, error) {
+	base := models.Slugify(name)
+	taken, err := handler.store.SlugsTaken(ctx, base, exclude)
+	if err != nil {
+		return "", err
+	}
+	return models.UniqueSlug(base, taken), nil
+}
+
+// createRecipe gives the recipe a unique slug and inserts it. The unique index
+// on slugs rejects a create racing another for the same slug, in which case
+// the next free slug is taken.
+func (handler *RecipesHandler) createRecipe(ctx context.Context, recipe *models.Recipe) error {
+	for attempt := 1; ; attempt++ {
+		slug, err := handler.uniqueSlug(ctx, recipe.Name, primitive.NilObjectID)
+		if err != nil {
+			return err
+		}
+		recipe.Slug = slug
+
+		err = handler.store.Create(ctx, *recipe)
+		if !mongo.IsDuplicateKeyError(err) || attempt == slugAttempts {
+			return err
+		}
+	}
+}
+
+// updateSlug sets a new slug on the patch of current when it renames the
+// recipe and SLUG_ON_RENAME is regenerate, or when the recipe has no slug
+// yet because it was created before slugs existed
+func (handler *RecipesHandler) updateSlug(ctx context.Context, current models.Recipe, patch *models.RecipePatch) error {
+	name := current.Name
+	if patch.Name != nil {
+		name = *patch.Name
+	}
+	if current.Slug != "" && (!regenerateSlugs || name == current.Name) {
+		return nil
+	}
+
+	slug, err := handler.uniqueSlug(ctx, name, current.ID)
+	if err != nil {
+		return err
+	}
+	if slug != current.Slug {
+		patch.Slug = &slug
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"github.com/gabrielsscti/Recipes-API/models"
+	"net/http"
+	"testing"
+)
+
+func TestRecipeSlugs(t *testing.T) {
+	alice, bob := newCaller("alice"), newCaller("bob")
+	env := memoryEnv(t)
+
+	create := func(user caller, name string) models.Recipe {
+		t.Helper()
+		recipe := newRecipe(name)
+		recipe.Status = models.StatusPublished
+		rec := env.as(user).request(http.MethodPost, "/recipes", recipe)
+		expectStatus(t, rec, http.StatusOK)
+		decodeBody(t, rec, &recipe)
+		return recipe
+	}
+	first := create(alice, "Pão de Queijo")
+	second := create(bob, "Pao de queijo!")
+	if first.Slug != "pao-de-queijo" || second.Slug != "pao-de-queijo-2" {
+		t.Fatalf("slugs = %q, %q, want pao-de-queijo and pao-de-queijo-2", first.Slug, second.Slug)
+	}
+
+	env.as(caller{})
+	for _, recipe := range []models.Recipe{first, second} {
+		rec := env.request(http.MethodGet, "/recipes/slug/"+recipe.Slug, nil)
+		expectStatus(t, rec, http.StatusOK)
+		var fetched models.Recipe
+		decodeBody(t, rec, &fetched)
+		if fetched.ID != recipe.ID {
+			t.Errorf("%s: fetched %s, want %s", recipe.Slug, fetched.ID.Hex(), recipe.ID.Hex())
+		}
+	}
+	expectStatus(t, env.request(http.MethodGet, "/recipes/slug/pao-de-queijo-3", nil), http.StatusNotFound)
+}
+
+func TestRecipeSlugOnRename(t *testing.T) {
+	defer func(regenerate bool) { regenerateSlugs = regenerate }(regenerateSlugs)
+
+	alice := newCaller("alice")
+	tests := []struct {
+		regenerate bool
+		want       string
+	}{
+		{false, "pancakes"},
+		{true, "fluffy-pancakes"},
+	}
+	for _, test := range tests {
+		regenerateSlugs = test.regenerate
+		env := memoryEnv(t).as(alice)
+		recipe := publishedRecipe("Pancakes", alice.ID)
+		recipe.Slug = "pancakes"
+		recipe = env.seed(t, recipe)
+
+		expectStatus(t, env.request(http.MethodPut, "/recipes/"+recipe.ID.Hex(), newRecipe("Fluffy Pancakes")), http.StatusOK)
+		rec := env.request(http.MethodGet, "/recipes/slug/"+test.want, nil)
+		expectStatus(t, rec, http.StatusOK)
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// statsCacheTTL bounds how long the statistics may lag behind writes
+const statsCacheTTL = 5 * time.Minute
+
+const defaultStatsTags = 10
+
+// swagger:operation GET /recipes/stats recipes recipeStats
+// Returns statistics on the published recipes: their total, the most used
+// tags, the average number of ingredients and the recipes published per month
+// ---
+// parameters:
+// - name: tags
+//   in: query
+//   description: number of tags returned, defaults to 10
+//   required: false
+//   type: integer
+// produces:
+// - application/json
+// - application/yaml
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid tags
+func (handler *RecipesHandler) RecipeStatsHandler(c *gin.Context) {
+	topTags := defaultStatsTags
+	if value := c.Query("tags"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tags must be a positive integer"})
+			return
+		}
+		topTags = minInt(parsed, maxPageSize)
+	}
+
+	key := fmt.Sprintf("stats:%d", topTags)
+	if val, ok := handler.getCached(key); ok {
+		log.Printf("Request to Redis")
+		var stats models.RecipeStats
+		json.Unmarshal([]byte(val), &stats)
+		render(c, http.StatusOK, stats)
+		return
+	}
+
+	log.Printf("Request to MongoDB")
+	stats, err := handler.store.Stats(c.Request.Context(), topTags)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, _ := json.Marshal(stats)
+	handler.setCached(key, string(data), statsCacheTTL)
+	render(c, http.StatusOK, stats)
+}
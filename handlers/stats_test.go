@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"github.com/gabrielsscti/Recipes-API/models"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecipeStats(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	seed := func(name string, published string, status string, ingredients int, tags ...string) {
+		recipe := publishedRecipe(name, owner)
+		recipe.PublishedAt, _ = time.Parse("2006-01-02", published)
+		recipe.Status = status
+		recipe.Tags = tags
+		recipe.Ingredients = make([]string, ingredients)
+		env.seed(t, recipe)
+	}
+	seed("Pancakes", "2022-01-10", models.StatusPublished, 3, "breakfast", "sweet")
+	seed("Waffles", "2022-01-20", models.StatusPublished, 4, "breakfast", "sweet")
+	seed("Omelette", "2022-02-01", models.StatusPublished, 2, "breakfast")
+	seed("Salad", "2022-03-15", models.StatusPublished, 5, "lunch")
+	seed("Secret sauce", "2022-03-16", models.StatusDraft, 10, "lunch", "secret")
+
+	rec := env.request(http.MethodGet, "/recipes/stats?tags=2", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var stats models.RecipeStats
+	decodeBody(t, rec, &stats)
+	want := models.RecipeStats{
+		Total:              4,
+		Tags:               []models.TagCount{{Tag: "breakfast", Count: 3}, {Tag: "sweet", Count: 2}},
+		AverageIngredients: 3.5,
+		PerMonth:           []models.MonthCount{{Month: "2022-01", Count: 2}, {Month: "2022-02", Count: 1}, {Month: "2022-03", Count: 1}},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+
+	// The stats are cached, so a new recipe only shows once they expire
+	seed("Toast", "2022-04-01", models.StatusPublished, 1)
+	rec = env.request(http.MethodGet, "/recipes/stats?tags=2", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decodeBody(t, rec, &stats)
+	if stats.Total != 4 {
+		t.Errorf("total = %d, want the cached 4", stats.Total)
+	}
+	env.redis.FastForward(statsCacheTTL)
+	rec = env.request(http.MethodGet, "/recipes/stats?tags=2", nil)
+	expectStatus(t, rec, http.StatusOK)
+	decodeBody(t, rec, &stats)
+	if stats.Total != 5 {
+		t.Errorf("total = %d, want 5 once the cache expired", stats.Total)
+	}
+
+	expectStatus(t, env.request(http.MethodGet, "/recipes/stats?tags=0", nil), http.StatusBadRequest)
+}
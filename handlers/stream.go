@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/gabrielsscti/Recipes-API/middleware"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+// streamFlushEvery is the number of recipes written between two flushes of a stream
+const streamFlushEvery = 100
+
+// streamRecipes writes every recipe visible to the caller as NDJSON, one per
+// line in ID order, reading them from a database cursor rather than the cache
+// so the whole set is never held in memory. Errors past the first line can
+// only end the stream early, since the status has been sent.
+func (handler *RecipesHandler) streamRecipes(c *gin.Context, fields []string, metadata map[string]string) {
+	c.Header("Content-Type", middleware.NDJSON)
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	written := 0
+	err := handler.store.Stream(c.Request.Context(), store.ListFilter{Fields: storedFields(fields)}, func(recipe models.Recipe) error {
+		if !canView(c, recipe) || !store.MatchMetadata(recipe.Metadata, metadata) {
+			return nil
+		}
+
+		recipe = localize(c, recipe)
+		var line interface{} = recipe
+		if len(fields) > 0 {
+			line = projectRecipe(recipe, fields)
+		}
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+		if written++; written%streamFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Warning: recipe stream ended after %d recipes: %v", written, err)
+	}
+}
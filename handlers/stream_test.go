@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/middleware"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// streamLines requests target as NDJSON and decodes each line of the answer
+func streamLines(t *testing.T, env *testEnv, target string) []map[string]interface{} {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Header.Set("Accept", middleware.NDJSON)
+	rec := env.serve(req)
+	expectStatus(t, rec, http.StatusOK)
+	if contentType := rec.Header().Get("Content-Type"); contentType != middleware.NDJSON {
+		t.Errorf("Content-Type = %q, want %s", contentType, middleware.NDJSON)
+	}
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %d is not a JSON object: %q", len(lines)+1, scanner.Text())
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestListRecipesStreamsNDJSON(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	// More than a flush apart, so the stream is written in several chunks
+	count := streamFlushEvery + 50
+	for i := 0; i < count; i++ {
+		recipe := publishedRecipe(fmt.Sprintf("Recipe %d", i), owner)
+		if i%2 == 0 {
+			recipe.Metadata = map[string]string{"cuisine": "thai"}
+		}
+		env.seed(t, recipe)
+	}
+	draft := publishedRecipe("Draft", owner)
+	draft.Status = models.StatusDraft
+	env.seed(t, draft)
+
+	if lines := streamLines(t, env, "/recipes"); len(lines) != count {
+		t.Errorf("%d lines, want one per visible recipe, %d", len(lines), count)
+	}
+	if lines := streamLines(t, env, "/recipes?metadata.cuisine=thai"); len(lines) != count/2 {
+		t.Errorf("%d lines with the metadata filter, want %d", len(lines), count/2)
+	}
+
+	lines := streamLines(t, env, "/recipes?fields=name")
+	for _, line := range lines {
+		if name, ok := line["name"].(string); len(line) != 1 || !ok || !strings.HasPrefix(name, "Recipe ") {
+			t.Fatalf("line = %v, want the name alone", line)
+		}
+	}
+	// The fields filtered on are read even when they are not returned
+	if lines := streamLines(t, env, "/recipes?fields=name&metadata.cuisine=thai"); len(lines) != count/2 {
+		t.Errorf("%d projected lines with the metadata filter, want %d", len(lines), count/2)
+	}
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/audit"
+	"github.com/gabrielsscti/Recipes-API/events"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const tagsCacheTTL = time.Minute
+
+// swagger:operation GET /recipes/tags recipes listTags
+// Returns the tags in use with the number of recipes for each, most used first
+// ---
+// produces:
+// - application/json
+// - application/yaml
+// responses:
+//     '200':
+//         description: Successful operation
+func (handler *RecipesHandler) ListTagsHandler(c *gin.Context) {
+	if val, ok := handler.getCached("tags"); ok {
+		log.Printf("Request to Redis")
+		tags := make([]models.TagCount, 0)
+		json.Unmarshal([]byte(val), &tags)
+		render(c, http.StatusOK, tags)
+		return
+	}
+
+	log.Printf("Request to MongoDB")
+	tags, err := handler.store.TagCounts(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, _ := json.Marshal(tags)
+	handler.setCached("tags", string(data), tagsCacheTTL)
+	render(c, http.StatusOK, tags)
+}
+
+// swagger:operation POST /admin/tags/rename recipes renameTag
+// Replace a tag, sent as {"from": "...", "to": "..."}, in every recipe. Admin only
+// ---
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation, returns the number of recipes modified
+//     '400':
+//         description: Invalid input
+//     '403':
+//         description: Caller is not an admin
+func (handler *RecipesHandler) RenameTagHandler(c *gin.Context) {
+	var rename models.TagRename
+	if err := c.ShouldBindJSON(&rename); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from := strings.ToLower(strings.TrimSpace(rename.From))
+	to := strings.ToLower(strings.TrimSpace(rename.To))
+	if from == "" || to == "" || from == to {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must be different, non-blank tags"})
+		return
+	}
+
+	modified, err := handler.store.RenameTag(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	handler.clearRecipesFromRedis()
+	if err := handler.cache.Del("tags"); err != nil {
+		log.Printf("Warning: could not remove the tags from the cache: %v", err)
+	}
+	entry := audit.NewEntry(c.GetString("username"), audit.TagRenamed, from)
+	if err := handler.audit.Record(c.Request.Context(), entry); err != nil {
+		log.Printf("Warning: could not audit the rename of tag %s: %v", from, err)
+	}
+	c.JSON(http.StatusOK, gin.H{"modified": modified})
+}
+
+// swagger:operation PUT /recipes/{id}/tags recipes replaceRecipeTags
+// Replace the tags of a recipe with the JSON array in the body
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the recipe
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation, returns the updated recipe
+//     '400':
+//         description: Invalid input
+//     '403':
+//         description: Caller does not own the recipe
+//     '404':
+//         description: Invalid recipe ID
+func (handler *RecipesHandler) ReplaceTagsHandler(c *gin.Context) {
+	var tags []string
+	if err := c.ShouldBindJSON(&tags); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	handler.updateTags(c, func([]string) []string {
+		return tags
+	})
+}
+
+// swagger:operation POST /recipes/{id}/tags recipes addRecipeTag
+// Add a tag, sent as {"tag": "..."}, to a recipe
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the recipe
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation, returns the updated recipe
+//     '400':
+//         description: Invalid input
+//     '403':
+//         description: Caller does not own the recipe
+//     '404':
+//         description: Invalid recipe ID
+func (handler *RecipesHandler) AddTagHandler(c *gin.Context) {
+	var body struct {
+		Tag string `json:"tag" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	handler.updateTags(c, func(tags []string) []string {
+		return append(tags, body.Tag)
+	})
+}
+
+// swagger:operation DELETE /recipes/{id}/tags/{tag} recipes removeRecipeTag
+// Remove a tag from a recipe
+// ---
+// parameters:
+// - name: id
+//   in: path
+//   description: ID of the recipe
+//   required: true
+//   type: string
+// - name: tag
+//   in: path
+//   description: tag to remove
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation, returns the updated recipe
+//     '403':
+//         description: Caller does not own the recipe
+//     '404':
+//         description: Invalid recipe ID
+func (handler *RecipesHandler) RemoveTagHandler(c *gin.Context) {
+	removed := strings.ToLower(strings.TrimSpace(c.Param("tag")))
+
+	handler.updateTags(c, func(tags []string) []string {
+		kept := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			if tag != removed {
+				kept = append(kept, tag)
+			}
+		}
+		return kept
+	})
+}
+
+// updateTags replaces the tags of the recipe in the path with the result of
+// change, once the caller is known to own it
+func (handler *RecipesHandler) updateTags(c *gin.Context, change func(tags []string) []string) {
+	id := c.Param("id")
+
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, errorBody(c, msgInvalidRecipeID))
+		return
+	}
+
+	recipe, err := handler.store.GetByID(c.Request.Context(), objectId)
+	if err == nil && !canView(c, recipe) {
+		err = store.ErrNotFound
+	}
+	if err == store.ErrNotFound {
+		c.JSON(http.StatusNotFound, errorBody(c, msgRecipeNotFound, id))
+		return
+	} else if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if recipe.UserID != currentUserID(c) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, errorBody(c, msgNotOwnerEdit))
+		return
+	}
+
+	tags := normalizeTags(change(recipe.Tags))
+	if len(tags) > maxTags {
+		c.JSON(http.StatusBadRequest, errorBody(c, msgTooManyTags, maxTags))
+		return
+	}
+
+	updated, err := handler.store.Update(c.Request.Context(), objectId, models.RecipePatch{
+		Tags: &tags,
+	})
+	if err != nil {
+		fmt.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	handler.clearRecipesFromRedis()
+	handler.publish(c, events.RecipeUpdated, updated)
+	c.JSON(http.StatusOK, updated)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRecipeTagsAreNormalizedBeforeStoring(t *testing.T) {
+	env := memoryEnv(t).as(newCaller("alice"))
+	raw := []string{"Vegan", " vegan ", "Quick"}
+	want := []string{"vegan", "quick"}
+
+	stored := func(id primitive.ObjectID) []string {
+		t.Helper()
+		recipe, err := env.store.GetByID(context.Background(), id)
+		if err != nil {
+			t.Fatalf("reading back %s: %v", id.Hex(), err)
+		}
+		return recipe.Tags
+	}
+
+	recipe := newRecipe("Salad")
+	recipe.Tags = raw
+	rec := env.request(http.MethodPost, "/recipes", recipe)
+	expectStatus(t, rec, http.StatusOK)
+	var created models.Recipe
+	decodeBody(t, rec, &created)
+	if tags := stored(created.ID); !reflect.DeepEqual(tags, want) {
+		t.Errorf("created with tags %q, want %q", tags, want)
+	}
+
+	recipe.Tags = []string{"QUICK", "Salad ", "quick"}
+	expectStatus(t, env.request(http.MethodPut, "/recipes/"+created.ID.Hex(), recipe), http.StatusOK)
+	if tags := stored(created.ID); !reflect.DeepEqual(tags, []string{"quick", "salad"}) {
+		t.Errorf("updated with tags %q, want [quick salad]", tags)
+	}
+
+	expectStatus(t, env.request(http.MethodPatch, "/recipes/"+created.ID.Hex(), map[string][]string{"tags": raw}), http.StatusOK)
+	if tags := stored(created.ID); !reflect.DeepEqual(tags, want) {
+		t.Errorf("patched with tags %q, want %q", tags, want)
+	}
+}
+
+func TestRecipeTagsAreCapped(t *testing.T) {
+	env := memoryEnv(t).as(newCaller("alice"))
+	recipe := newRecipe("Salad")
+	for i := 0; i <= maxTags; i++ {
+		recipe.Tags = append(recipe.Tags, fmt.Sprintf("tag %d", i))
+	}
+	rec := env.request(http.MethodPost, "/recipes", recipe)
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	// Duplicates do not count towards the cap
+	recipe.Tags = recipe.Tags[:maxTags]
+	recipe.Tags = append(recipe.Tags, "TAG 0")
+	expectStatus(t, env.request(http.MethodPost, "/recipes", recipe), http.StatusOK)
+}
+
+func TestRecipeTagOperations(t *testing.T) {
+	alice := newCaller("alice")
+	env := memoryEnv(t).as(alice)
+	recipe := publishedRecipe("Salad", alice.ID)
+	recipe.Tags = []string{"vegan"}
+	recipe = env.seed(t, recipe)
+	target := "/recipes/" + recipe.ID.Hex() + "/tags"
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   interface{}
+		want   []string
+	}{
+		{"add", http.MethodPost, target, map[string]string{"tag": " Quick "}, []string{"vegan", "quick"}},
+		{"add a duplicate", http.MethodPost, target, map[string]string{"tag": "VEGAN"}, []string{"vegan", "quick"}},
+		{"remove", http.MethodDelete, target + "/VEGAN", nil, []string{"quick"}},
+		{"remove a missing tag", http.MethodDelete, target + "/dessert", nil, []string{"quick"}},
+		{"replace", http.MethodPut, target, []string{"Summer", "salad", "summer"}, []string{"summer", "salad"}},
+		{"replace with none", http.MethodPut, target, []string{}, []string{}},
+	}
+	for _, test := range tests {
+		rec := env.request(test.method, test.target, test.body)
+		expectStatus(t, rec, http.StatusOK)
+		var updated models.Recipe
+		decodeBody(t, rec, &updated)
+		stored, _ := env.store.GetByID(context.Background(), recipe.ID)
+		if !reflect.DeepEqual(updated.Tags, test.want) || len(stored.Tags) != len(test.want) {
+			t.Errorf("%s: tags = %q, stored %q, want %q", test.name, updated.Tags, stored.Tags, test.want)
+		}
+		if stored.Name != "Salad" || len(stored.Ingredients) != 1 {
+			t.Errorf("%s: the rest of the recipe changed: %+v", test.name, stored)
+		}
+	}
+
+	expectStatus(t, env.request(http.MethodPost, target, map[string]string{}), http.StatusBadRequest)
+	expectStatus(t, env.as(newCaller("bob")).request(http.MethodPost, target, map[string]string{"tag": "mine"}), http.StatusForbidden)
+}
+
+func TestRenameTag(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	seeded := map[string][]string{
+		"Salad": {"veg", "quick"},
+		"Curry": {"veg", "vegetarian"},
+		"Steak": {"meat"},
+		"Plain": nil,
+	}
+	for name, tags := range seeded {
+		recipe := publishedRecipe(name, owner)
+		recipe.Tags = tags
+		env.seed(t, recipe)
+	}
+	// Cache the listing, the rename having to bust it
+	expectStatus(t, env.request(http.MethodGet, "/recipes", nil), http.StatusOK)
+
+	rec := env.request(http.MethodPost, "/admin/tags/rename", gin.H{"from": " Veg ", "to": "vegetarian"})
+	expectStatus(t, rec, http.StatusOK)
+	var body struct {
+		Modified int64 `json:"modified"`
+	}
+	decodeBody(t, rec, &body)
+	if body.Modified != 2 {
+		t.Errorf("modified = %d, want 2", body.Modified)
+	}
+
+	rec = env.request(http.MethodGet, "/recipes", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var recipes []models.Recipe
+	decodeBody(t, rec, &recipes)
+	want := map[string][]string{
+		"Salad": {"vegetarian", "quick"},
+		"Curry": {"vegetarian"},
+		"Steak": {"meat"},
+		"Plain": nil,
+	}
+	for _, recipe := range recipes {
+		if !reflect.DeepEqual(recipe.Tags, want[recipe.Name]) {
+			t.Errorf("%s tags = %v, want %v", recipe.Name, recipe.Tags, want[recipe.Name])
+		}
+	}
+}
+
+func TestRenameTagRejectsInvalidInput(t *testing.T) {
+	env := memoryEnv(t)
+	for _, body := range []gin.H{{"from": "veg"}, {"from": " ", "to": "vegetarian"}, {"from": "Veg", "to": "veg"}} {
+		rec := env.request(http.MethodPost, "/admin/tags/rename", body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+func TestListTagsCountsRecipesPerTag(t *testing.T) {
+	env := memoryEnv(t)
+	owner := newCaller("alice").ID
+	for i, tags := range [][]string{{"a", "b"}, {"a", "c"}, {"a", "b"}} {
+		recipe := publishedRecipe(fmt.Sprintf("Recipe %d", i+1), owner)
+		recipe.Tags = tags
+		env.seed(t, recipe)
+	}
+
+	rec := env.request(http.MethodGet, "/recipes/tags", nil)
+	expectStatus(t, rec, http.StatusOK)
+	var tags []models.TagCount
+	decodeBody(t, rec, &tags)
+	want := []models.TagCount{{Tag: "a", Count: 3}, {Tag: "b", Count: 2}, {Tag: "c", Count: 1}}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TimeZoneMiddleware validates the tz query parameter, an IANA time zone
+// name such as Europe/Lisbon, answering 400 for unknown zones. Recipes are
+// stored in UTC and only converted when written out, by localize.
+func TimeZoneMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := strings.TrimSpace(c.Query("tz"))
+		if name == "" {
+			c.Next()
+			return
+		}
+
+		location, err := time.LoadLocation(name)
+		if err != nil || name == "Local" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, errorBody(c, msgInvalidTimeZone, name))
+			return
+		}
+		c.Set("location", location)
+		c.Next()
+	}
+}
+
+// inTimeZone returns recipe with its timestamps in the time zone validated by
+// TimeZoneMiddleware, or untouched without tz
+func inTimeZone(c *gin.Context, recipe models.Recipe) models.Recipe {
+	if location, ok := c.Value("location").(*time.Location); ok {
+		recipe.PublishedAt = recipe.PublishedAt.In(location)
+	}
+	return recipe
+}
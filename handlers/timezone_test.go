@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecipeTimestampsInTimeZone(t *testing.T) {
+	env := memoryEnv(t)
+	recipe := publishedRecipe("Pancakes", newCaller("alice").ID)
+	recipe.PublishedAt = time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	recipe = env.seed(t, recipe)
+
+	tests := map[string]string{
+		"":                  `"publishedAt":"2022-03-01T12:00:00Z"`,
+		"America/Sao_Paulo": `"publishedAt":"2022-03-01T09:00:00-03:00"`,
+		"Asia/Tokyo":        `"publishedAt":"2022-03-01T21:00:00+09:00"`,
+	}
+	for zone, want := range tests {
+		for _, target := range []string{"/recipes/" + recipe.ID.Hex(), "/recipes"} {
+			rec := env.request(http.MethodGet, target+"?tz="+zone, nil)
+			expectStatus(t, rec, http.StatusOK)
+			if body := rec.Body.String(); !strings.Contains(body, want) {
+				t.Errorf("GET %s?tz=%s = %s, want %s", target, zone, body, want)
+			}
+		}
+	}
+
+	stored, err := env.store.GetByID(context.Background(), recipe.ID)
+	if err != nil || stored.PublishedAt.Location() != time.UTC {
+		t.Errorf("stored publishedAt = %v, %v, want it left in UTC", stored.PublishedAt, err)
+	}
+}
+
+func TestInvalidTimeZone(t *testing.T) {
+	env := memoryEnv(t)
+	for _, zone := range []string{"Mars/Olympus_Mons", "Local", "../etc/passwd"} {
+		rec := env.request(http.MethodGet, "/recipes?tz="+zone, nil)
+		expectCode(t, rec, http.StatusBadRequest, msgInvalidTimeZone)
+	}
+}
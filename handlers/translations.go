@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"regexp"
+	"strings"
+)
+
+// localePattern matches locales such as pt or pt-BR
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})?$`)
+
+// localize returns recipe in the language of the lang query parameter, with
+// its timestamps in the time zone of tz. The translation for the exact locale
+// is preferred, then the one for its base language, so pt-BR falls back to
+// pt, and finally the original content.
+func localize(c *gin.Context, recipe models.Recipe) models.Recipe {
+	recipe = inTimeZone(c, recipe)
+	lang := strings.TrimSpace(c.Query("lang"))
+	if lang == "" || len(recipe.Translations) == 0 {
+		return recipe
+	}
+
+	translation, ok := findTranslation(recipe.Translations, lang)
+	if !ok {
+		base := strings.SplitN(lang, "-", 2)[0]
+		translation, ok = findTranslation(recipe.Translations, base)
+	}
+	if !ok {
+		return recipe
+	}
+
+	if translation.Name != "" {
+		recipe.Name = translation.Name
+	}
+	if len(translation.Ingredients) > 0 {
+		recipe.Ingredients = translation.Ingredients
+	}
+	if len(translation.Instructions) > 0 {
+		recipe.Instructions = translation.Instructions
+	}
+	return recipe
+}
+
+// localizeAll localizes each of the recipes, see localize
+func localizeAll(c *gin.Context, recipes []models.Recipe) []models.Recipe {
+	if _, inTimeZone := c.Get("location"); c.Query("lang") == "" && !inTimeZone {
+		return recipes
+	}
+	localized := make([]models.Recipe, len(recipes))
+	for i, recipe := range recipes {
+		localized[i] = localize(c, recipe)
+	}
+	return localized
+}
+
+// findTranslation looks up locale ignoring case, since pt-BR and pt-br are the same locale
+func findTranslation(translations map[string]models.RecipeTranslation, locale string) (models.RecipeTranslation, bool) {
+	for key, translation := range translations {
+		if strings.EqualFold(key, locale) {
+			return translation, true
+		}
+	}
+	return models.RecipeTranslation{}, false
+}
+
+// validateTranslations applies the limits of the original content to each translation
+func validateTranslations(translations map[string]models.RecipeTranslation, errs fieldErrors) {
+	for locale, translation := range translations {
+		switch {
+		case !localePattern.MatchString(locale):
+			errs["translations"] = fmt.Sprintf("%q is not a locale such as pt or pt-br", locale)
+		case len([]rune(translation.Name)) > maxTextLength:
+			errs["translations"] = fmt.Sprintf("the %s name is longer than %d characters", locale, maxTextLength)
+		case len(translation.Ingredients) > maxIngredients:
+			errs["translations"] = fmt.Sprintf("at most %d %s ingredients are allowed", maxIngredients, locale)
+		case len(translation.Instructions) > maxInstructions:
+			errs["translations"] = fmt.Sprintf("at most %d %s instructions are allowed", maxInstructions, locale)
+		default:
+			for _, text := range append(append([]string{}, translation.Ingredients...), translation.Instructions...) {
+				if len([]rune(text)) > maxTextLength {
+					errs["translations"] = fmt.Sprintf("the %s ingredients and instructions must be at most %d characters", locale, maxTextLength)
+					break
+				}
+			}
+		}
+		if _, invalid := errs["translations"]; invalid {
+			return
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func translatedRecipe() models.Recipe {
+	recipe := publishedRecipe("Pancakes", newCaller("alice").ID)
+	recipe.Ingredients = []string{"flour", "milk"}
+	recipe.Instructions = []string{"mix", "fry"}
+	recipe.Translations = map[string]models.RecipeTranslation{
+		"pt": {
+			Name:         "Panquecas",
+			Ingredients:  []string{"farinha", "leite"},
+			Instructions: []string{"misture", "frite"},
+		},
+		"pt-BR": {Name: "Panquecas brasileiras"},
+	}
+	return recipe
+}
+
+func TestRecipeTranslations(t *testing.T) {
+	tests := []struct {
+		lang         string
+		name         string
+		ingredients  []string
+		instructions models.Instructions
+	}{
+		{"", "Pancakes", []string{"flour", "milk"}, models.Instructions{"mix", "fry"}},
+		{"pt", "Panquecas", []string{"farinha", "leite"}, models.Instructions{"misture", "frite"}},
+		// pt-BR only translates the name, the rest being the original content
+		{"pt-br", "Panquecas brasileiras", []string{"flour", "milk"}, models.Instructions{"mix", "fry"}},
+		{"pt-PT", "Panquecas", []string{"farinha", "leite"}, models.Instructions{"misture", "frite"}},
+		{"fr", "Pancakes", []string{"flour", "milk"}, models.Instructions{"mix", "fry"}},
+	}
+	env := memoryEnv(t)
+	recipe := env.seed(t, translatedRecipe())
+	for _, test := range tests {
+		t.Run("lang="+test.lang, func(t *testing.T) {
+			rec := env.request(http.MethodGet, "/recipes/"+recipe.ID.Hex()+"?lang="+test.lang, nil)
+			expectStatus(t, rec, http.StatusOK)
+			var got models.Recipe
+			decodeBody(t, rec, &got)
+			if got.Name != test.name || !reflect.DeepEqual(got.Ingredients, test.ingredients) ||
+				!reflect.DeepEqual(got.Instructions, test.instructions) {
+				t.Errorf("got %q %v %v, want %q %v %v", got.Name, got.Ingredients, got.Instructions,
+					test.name, test.ingredients, test.instructions)
+			}
+		})
+	}
+}
+
+func TestRecipeListsAreTranslated(t *testing.T) {
+	env := memoryEnv(t)
+	env.seed(t, translatedRecipe())
+	env.seed(t, publishedRecipe("Waffles", newCaller("bob").ID))
+
+	rec := env.request(http.MethodGet, "/recipes?lang=pt&sort=name", nil)
+	expectStatus(t, rec, http.StatusOK)
+	names := namesOf(t, rec)
+	if !reflect.DeepEqual(names, []string{"Panquecas", "Waffles"}) {
+		t.Errorf("names = %v, want Panquecas and the untranslated Waffles", names)
+	}
+}
+
+func TestRecipeTranslationsAreValidated(t *testing.T) {
+	env := memoryEnv(t).as(newCaller("alice"))
+	rec := env.request(http.MethodPost, "/recipes", gin.H{
+		"name":         "Pancakes",
+		"ingredients":  []string{"flour"},
+		"instructions": []string{"fry"},
+		"translations": gin.H{"not a locale": gin.H{"name": "Panquecas"}},
+	})
+	expectStatus(t, rec, http.StatusBadRequest)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// swagger:operation GET /users auth listUsers
+// Returns a page of users, optionally filtered by username or email. Admin only
+// ---
+// produces:
+// - application/json
+// parameters:
+//   - name: q
+//     in: query
+//     description: text contained in the username or email
+//     required: false
+//     type: string
+//   - name: page
+//     in: query
+//     description: page number, starting at 1
+//     required: false
+//     type: integer
+//   - name: limit
+//     in: query
+//     description: number of users per page
+//     required: false
+//     type: integer
+// responses:
+//     '200':
+//         description: Successful operation
+//     '400':
+//         description: Invalid pagination parameters
+//     '403':
+//         description: Caller is not an admin
+func (handler *AuthHandler) ListUsersHandler(c *gin.Context) {
+	page, limit, err := parsePageParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := bson.M{}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(q), "$options": "i"}
+		filter["$or"] = bson.A{
+			bson.M{"username": pattern},
+			bson.M{"email": pattern},
+		}
+	}
+
+	total, err := handler.collection.CountDocuments(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "username", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"password": 0})
+	cur, err := handler.collection.Find(c.Request.Context(), filter, findOptions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer cur.Close(c.Request.Context())
+
+	users := make([]models.UserProfile, 0)
+	if err := cur.All(c.Request.Context(), &users); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	setPaginationHeaders(c, page, limit, int(total))
+	c.JSON(http.StatusOK, models.UsersPage{
+		Users: users,
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	})
+}
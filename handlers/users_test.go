@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"github.com/gabrielsscti/Recipes-API/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestListUsersHandler(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	admin := newCaller("root")
+	admin.Role = models.RoleAdmin
+
+	mt.Run("non-admins are forbidden", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		rec := env.request(http.MethodGet, "/users", nil, env.token(mt.T, newCaller("alice")))
+		expectStatus(mt.T, rec, http.StatusForbidden)
+	})
+
+	mt.Run("invalid pages", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		for _, query := range []string{"page=0", "page=x", "limit=0", "limit=-5"} {
+			rec := env.request(http.MethodGet, "/users?"+query, nil, env.token(mt.T, admin))
+			if rec.Code != http.StatusBadRequest {
+				mt.Errorf("%s: status = %d, want 400", query, rec.Code)
+			}
+		}
+	})
+
+	mt.Run("limit is clamped", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		mt.AddMockResponses(cursorOf(mt, bson.D{{Key: "n", Value: 1}}), cursorOf(mt, userDoc(primitive.NewObjectID(), "alice", "x")))
+
+		rec := env.request(http.MethodGet, "/users?page=2&limit=10000", nil, env.token(mt.T, admin))
+		expectStatus(mt.T, rec, http.StatusOK)
+		var page models.UsersPage
+		decodeBody(mt.T, rec, &page)
+		if page.Limit != maxPageSize || page.Page != 2 || page.Total != 1 {
+			mt.Errorf("page, limit, total = %d, %d, %d, want 2, %d, 1", page.Page, page.Limit, page.Total, maxPageSize)
+		}
+		if len(page.Users) != 1 || page.Users[0].Username != "alice" {
+			mt.Errorf("users = %+v", page.Users)
+		}
+
+		mt.GetStartedEvent() // count
+		find := mt.GetStartedEvent().Command
+		if limit := find.Lookup("limit").AsInt64(); limit != int64(maxPageSize) {
+			mt.Errorf("find limit = %d, want %d", limit, maxPageSize)
+		}
+		if skip := find.Lookup("skip").AsInt64(); skip != int64(maxPageSize) {
+			mt.Errorf("find skip = %d, want %d", skip, maxPageSize)
+		}
+	})
+
+	mt.Run("search", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		mt.AddMockResponses(cursorOf(mt), cursorOf(mt))
+
+		rec := env.request(http.MethodGet, "/users?q=a.b", nil, env.token(mt.T, admin))
+		expectStatus(mt.T, rec, http.StatusOK)
+
+		mt.GetStartedEvent() // count
+		filter := mt.GetStartedEvent().Command.Lookup("filter").String()
+		for _, want := range []string{`"username"`, `"email"`, `a\\.b`, `"$options": "i"`} {
+			if !strings.Contains(filter, want) {
+				mt.Errorf("filter %s does not contain %s", filter, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"net/url"
+	"strings"
+)
+
+// fieldErrors maps a JSON field name to what is wrong with it
+type fieldErrors map[string]string
+
+// Limits protecting the database and the renderers from oversized recipes
+var (
+	// maxTags caps the number of distinct tags of a recipe
+	maxTags         = config.Int("MAX_TAGS", 20)
+	maxIngredients  = config.Int("MAX_INGREDIENTS", 100)
+	maxInstructions = config.Int("MAX_INSTRUCTIONS", 100)
+	maxYieldLength  = config.Int("MAX_YIELD_LENGTH", 100)
+	// maxTextLength caps the length, in characters, of each ingredient and instruction
+	maxTextLength = config.Int("MAX_TEXT_LENGTH", 2000)
+)
+
+// normalizeTags trims and lowercases tags so that matching them is
+// case-insensitive, dropping blank and duplicate tags. The order of first
+// appearance is kept.
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// validateRecipe checks a recipe submitted for creation or full update
+func validateRecipe(recipe models.Recipe) fieldErrors {
+	errs := fieldErrors{}
+	if strings.TrimSpace(recipe.Name) == "" {
+		errs["name"] = "name is required"
+	}
+	if len(recipe.Tags) > maxTags {
+		errs["tags"] = fmt.Sprintf("at most %d tags are allowed", maxTags)
+	}
+	if len(recipe.Ingredients) == 0 {
+		errs["ingredients"] = "at least one ingredient is required"
+	} else if len(recipe.Ingredients) > maxIngredients {
+		errs["ingredients"] = fmt.Sprintf("at most %d ingredients are allowed", maxIngredients)
+	}
+	for i, ingredient := range recipe.Ingredients {
+		if len([]rune(ingredient)) > maxTextLength {
+			errs["ingredients"] = fmt.Sprintf("ingredient %d is longer than %d characters", i+1, maxTextLength)
+			break
+		}
+	}
+	if len(recipe.Instructions) == 0 {
+		errs["instructions"] = "at least one instruction is required"
+	} else if len(recipe.Instructions) > maxInstructions {
+		errs["instructions"] = fmt.Sprintf("at most %d instructions are allowed", maxInstructions)
+	}
+	for i, instruction := range recipe.Instructions {
+		if strings.TrimSpace(instruction) == "" {
+			errs["instructions"] = fmt.Sprintf("step %d is empty", i+1)
+			break
+		}
+		if len([]rune(instruction)) > maxTextLength {
+			errs["instructions"] = fmt.Sprintf("step %d is longer than %d characters", i+1, maxTextLength)
+			break
+		}
+	}
+	if !validStatus(recipe.Status) {
+		errs["status"] = "status must be either draft or published"
+	}
+	if !validVisibility(recipe.Visibility) {
+		errs["visibility"] = "visibility must be either public or private"
+	}
+	if !validDifficulty(recipe.Difficulty) {
+		errs["difficulty"] = "difficulty must be easy, medium or hard"
+	}
+	if recipe.TotalTime < 0 {
+		errs["totalTime"] = "totalTime must not be negative"
+	}
+	if recipe.Servings < 0 {
+		errs["servings"] = "servings must not be negative"
+	}
+	if len([]rune(recipe.Yield)) > maxYieldLength {
+		errs["yield"] = fmt.Sprintf("yield must be at most %d characters", maxYieldLength)
+	}
+	if recipe.SourceURL != "" && !validSourceURL(recipe.SourceURL) {
+		errs["sourceUrl"] = "sourceUrl must be an absolute http or https URL"
+	}
+	if len([]rune(recipe.SourceName)) > maxTextLength {
+		errs["sourceName"] = fmt.Sprintf("sourceName must be at most %d characters", maxTextLength)
+	}
+	for _, item := range recipe.Nutrition {
+		facts := item.NutritionFacts
+		if facts.Calories < 0 || facts.Protein < 0 || facts.Carbs < 0 || facts.Fat < 0 {
+			errs["nutrition"] = "nutrition values must not be negative"
+			break
+		}
+	}
+	validateTranslations(recipe.Translations, errs)
+	validateMetadata(recipe.Metadata, errs)
+	return errs
+}
+
+func validSourceURL(value string) bool {
+	source, err := url.Parse(value)
+	return err == nil && (source.Scheme == "http" || source.Scheme == "https") && source.Host != ""
+}
+
+func validStatus(status string) bool {
+	return status == "" || status == models.StatusDraft || status == models.StatusPublished
+}
+
+func validVisibility(visibility string) bool {
+	return visibility == "" || visibility == models.VisibilityPublic || visibility == models.VisibilityPrivate
+}
+
+func validDifficulty(difficulty string) bool {
+	switch difficulty {
+	case "", models.DifficultyEasy, models.DifficultyMedium, models.DifficultyHard:
+		return true
+	}
+	return false
+}
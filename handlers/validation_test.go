@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRecipeSizeLimits(t *testing.T) {
+	defer func(ingredients, instructions, length int) {
+		maxIngredients, maxInstructions, maxTextLength = ingredients, instructions, length
+	}(maxIngredients, maxInstructions, maxTextLength)
+	maxIngredients, maxInstructions, maxTextLength = 3, 3, 20
+
+	withIngredients := func(ingredients ...string) models.Recipe {
+		recipe := newRecipe("Pancakes")
+		recipe.Ingredients = ingredients
+		return recipe
+	}
+	withInstructions := func(instructions ...string) models.Recipe {
+		recipe := newRecipe("Pancakes")
+		recipe.Instructions = instructions
+		return recipe
+	}
+
+	tests := []struct {
+		name   string
+		recipe models.Recipe
+		field  string
+		want   string
+	}{
+		{"too many ingredients", withIngredients("flour", "milk", "eggs", "salt"),
+			"ingredients", "at most 3 ingredients are allowed"},
+		{"too many instructions", withInstructions("mix", "rest", "fry", "serve"),
+			"instructions", "at most 3 instructions are allowed"},
+		{"over-long instruction", withInstructions("mix", strings.Repeat("é", 21)),
+			"instructions", "step 2 is longer than 20 characters"},
+		{"over-long ingredient", withIngredients(strings.Repeat("a", 21)),
+			"ingredients", "ingredient 1 is longer than 20 characters"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := memoryEnv(t).as(newCaller("alice"))
+			existing := env.seed(t, publishedRecipe("Waffles", env.caller.ID))
+			for _, method := range []string{http.MethodPost, http.MethodPut} {
+				target := "/recipes"
+				if method == http.MethodPut {
+					target = fmt.Sprintf("/recipes/%s", existing.ID.Hex())
+				}
+				rec := env.request(method, target, test.recipe)
+				expectStatus(t, rec, http.StatusBadRequest)
+				var body struct {
+					Fields map[string]string `json:"fields"`
+				}
+				decodeBody(t, rec, &body)
+				if body.Fields[test.field] != test.want {
+					t.Errorf("%s: fields = %v, want %s: %s", method, body.Fields, test.field, test.want)
+				}
+			}
+		})
+	}
+
+	// The limits themselves are allowed, counted in characters
+	env := memoryEnv(t).as(newCaller("alice"))
+	atLimit := newRecipe("Pancakes")
+	atLimit.Ingredients = []string{"flour", "milk", strings.Repeat("é", 20)}
+	atLimit.Instructions = []string{"mix", "rest", strings.Repeat("é", 20)}
+	expectStatus(t, env.request(http.MethodPost, "/recipes", atLimit), http.StatusOK)
+}
+
+func TestRecipeSourceAttribution(t *testing.T) {
+	env := memoryEnv(t).as(newCaller("alice"))
+
+	recipe := newRecipe("Pancakes")
+	recipe.SourceURL = "https://example.com/pancakes?ref=api"
+	recipe.SourceName = "Example Kitchen"
+	rec := env.request(http.MethodPost, "/recipes", recipe)
+	expectStatus(t, rec, http.StatusOK)
+	var created map[string]interface{}
+	decodeBody(t, rec, &created)
+	if created["sourceUrl"] != recipe.SourceURL || created["sourceName"] != recipe.SourceName {
+		t.Errorf("created = %v, want the source fields", created)
+	}
+
+	rec = env.request(http.MethodGet, fmt.Sprintf("/recipes/%s", created["id"]), nil)
+	expectStatus(t, rec, http.StatusOK)
+	var fetched map[string]interface{}
+	decodeBody(t, rec, &fetched)
+	if fetched["sourceUrl"] != recipe.SourceURL || fetched["sourceName"] != recipe.SourceName {
+		t.Errorf("fetched = %v, want the source fields", fetched)
+	}
+
+	for _, source := range []string{"example.com/pancakes", "ftp://example.com/pancakes", "javascript:alert(1)", "https://"} {
+		invalid := newRecipe("Waffles")
+		invalid.SourceURL = source
+		rec := env.request(http.MethodPost, "/recipes", invalid)
+		expectStatus(t, rec, http.StatusBadRequest)
+		var body struct {
+			Fields map[string]string `json:"fields"`
+		}
+		decodeBody(t, rec, &body)
+		if body.Fields["sourceUrl"] == "" {
+			t.Errorf("%q: fields = %v, want a problem with sourceUrl", source, body.Fields)
+		}
+	}
+}
+
+func TestRecipeServingsAndYield(t *testing.T) {
+	env := memoryEnv(t).as(newCaller("alice"))
+
+	recipe := newRecipe("Cookies")
+	recipe.Servings = 6
+	recipe.Yield = "24 cookies"
+	rec := env.request(http.MethodPost, "/recipes", recipe)
+	expectStatus(t, rec, http.StatusOK)
+	var created models.Recipe
+	decodeBody(t, rec, &created)
+
+	rec = env.request(http.MethodGet, "/recipes/"+created.ID.Hex(), nil)
+	expectStatus(t, rec, http.StatusOK)
+	var fetched models.Recipe
+	decodeBody(t, rec, &fetched)
+	if fetched.Servings != 6 || fetched.Yield != "24 cookies" {
+		t.Errorf("servings, yield = %d, %q, want 6, 24 cookies", fetched.Servings, fetched.Yield)
+	}
+
+	// Both are optional and left out when unset
+	rec = env.request(http.MethodPost, "/recipes", newRecipe("Bread"))
+	expectStatus(t, rec, http.StatusOK)
+	if body := rec.Body.String(); strings.Contains(body, `"servings"`) || strings.Contains(body, `"yield"`) {
+		t.Errorf("a recipe without servings or yield answered %s", body)
+	}
+
+	negative := newRecipe("Waffles")
+	negative.Servings = -1
+	expectStatus(t, env.request(http.MethodPost, "/recipes", negative), http.StatusBadRequest)
+	long := newRecipe("Waffles")
+	long.Yield = strings.Repeat("a", maxYieldLength+1)
+	expectStatus(t, env.request(http.MethodPost, "/recipes", long), http.StatusBadRequest)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/mail"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// startVerification sets a new verification token on user, returning the
+// token to send while only its hash is stored
+func (handler *AuthHandler) startVerification(user *models.User) (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	expires := handler.now().Add(handler.verificationTTL)
+	user.VerificationToken = hashVerificationToken(hex.EncodeToString(token))
+	user.VerificationExpires = &expires
+	return hex.EncodeToString(token), nil
+}
+
+func hashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sendVerification emails the verification link to user. Failures are
+// logged, since the account has been created already.
+func (handler *AuthHandler) sendVerification(c *gin.Context, user models.User, token string) {
+	link := strings.TrimRight(handler.publicURL, "/") + "/verify-email?token=" + url.QueryEscape(token)
+	message := mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen the link below to verify your email and sign in. It expires in %s.\n\n%s\n",
+			user.Username, handler.verificationTTL, link),
+	}
+	if err := handler.mailer.Send(c.Request.Context(), message); err != nil {
+		log.Printf("Warning: could not send the verification email of %s: %v", user.Username, err)
+	}
+}
+
+// swagger:operation GET /verify-email auth verifyEmail
+// Verifies the email of a user with the token sent on sign up
+// ---
+// parameters:
+// - name: token
+//   in: query
+//   description: token from the verification email
+//   required: true
+//   type: string
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: The email is verified, the user can sign in
+//     '400':
+//         description: The token is invalid or expired
+func (handler *AuthHandler) VerifyEmailHandler(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, errorBody(c, msgVerificationFailed))
+		return
+	}
+
+	var user models.User
+	err := handler.collection.FindOne(c.Request.Context(), bson.M{
+		"verificationToken":   hashVerificationToken(token),
+		"verificationExpires": bson.M{"$gt": handler.now()},
+	}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusBadRequest, errorBody(c, msgVerificationFailed))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = handler.collection.UpdateOne(c.Request.Context(), bson.M{"_id": user.ID}, bson.M{
+		"$set":   bson.M{"emailVerified": true},
+		"$unset": bson.M{"verificationToken": "", "verificationExpires": ""},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	handler.invalidateUser(user.Username)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email has been verified"})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gabrielsscti/Recipes-API/mail"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// recordingMailer keeps the messages sent instead of sending them
+type recordingMailer struct {
+	messages []mail.Message
+}
+
+func (mailer *recordingMailer) Send(ctx context.Context, message mail.Message) error {
+	mailer.messages = append(mailer.messages, message)
+	return nil
+}
+
+func TestEmailVerification(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	signIn := gin.H{"username": "alice", "password": "correct horse"}
+
+	mt.Run("full flow", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		mailer := &recordingMailer{}
+		env.handler.mailer = mailer
+		env.handler.emailVerification = true
+		env.handler.publicURL = "https://recipes.example.com/"
+
+		mt.AddMockResponses(cursorOf(mt), mtest.CreateSuccessResponse())
+		expectStatus(mt.T, env.request(http.MethodPost, "/signup", gin.H{
+			"username": "alice", "password": "correct horse", "email": "alice@example.com",
+		}, ""), http.StatusOK)
+
+		var stored bson.D
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName == "insert" {
+				bson.Unmarshal(event.Command.Lookup("documents").Array().Index(0).Value().Document(), &stored)
+			}
+		}
+		if len(mailer.messages) != 1 || mailer.messages[0].To != "alice@example.com" {
+			mt.Fatalf("messages = %+v, want a verification email to alice", mailer.messages)
+		}
+		link := mailer.messages[0].Body[strings.Index(mailer.messages[0].Body, "https://"):]
+		verifyURL, err := url.Parse(strings.TrimSpace(link))
+		if err != nil || verifyURL.Host != "recipes.example.com" || verifyURL.Path != "/verify-email" {
+			mt.Fatalf("link = %q, want the verify-email URL", link)
+		}
+		token := verifyURL.Query().Get("token")
+		raw, _ := bson.Marshal(stored)
+		if hash := bson.Raw(raw).Lookup("verificationToken").StringValue(); hash != hashVerificationToken(token) || hash == token {
+			mt.Fatalf("stored token = %q, want the hash of the emailed token", hash)
+		}
+
+		mt.AddMockResponses(cursorOf(mt, stored))
+		rec := env.request(http.MethodPost, "/signin", signIn, "")
+		expectStatus(mt.T, rec, http.StatusForbidden)
+		if !strings.Contains(rec.Body.String(), msgEmailNotVerified) {
+			mt.Errorf("body = %s, want %s", rec.Body, msgEmailNotVerified)
+		}
+
+		mt.ClearEvents()
+		mt.AddMockResponses(cursorOf(mt, stored), mtest.CreateSuccessResponse())
+		expectStatus(mt.T, env.request(http.MethodGet, "/verify-email?"+verifyURL.RawQuery, nil, ""), http.StatusOK)
+		lookup := mt.GetStartedEvent()
+		if filter := lookup.Command.Lookup("filter").String(); !strings.Contains(filter, hashVerificationToken(token)) ||
+			!strings.Contains(filter, `"verificationExpires": {"$gt"`) {
+			mt.Errorf("verification lookup = %s, want the unexpired hash of the token", filter)
+		}
+		if update := mt.GetStartedEvent(); !strings.Contains(update.Command.String(), `"emailVerified": true`) {
+			mt.Errorf("update = %s, want the email marked verified", update.Command)
+		}
+
+		verified := bson.D{}
+		for _, field := range stored {
+			if field.Key != "verificationToken" && field.Key != "verificationExpires" {
+				verified = append(verified, field)
+			}
+		}
+		verified = append(verified, bson.E{Key: "emailVerified", Value: true})
+		mt.AddMockResponses(cursorOf(mt, verified))
+		expectStatus(mt.T, env.request(http.MethodPost, "/signin", signIn, ""), http.StatusOK)
+	})
+
+	mt.Run("unknown or expired token", func(mt *mtest.T) {
+		env := newAuthEnv(mt.T, mt.Coll, nil)
+		expectStatus(mt.T, env.request(http.MethodGet, "/verify-email", nil, ""), http.StatusBadRequest)
+
+		mt.AddMockResponses(cursorOf(mt))
+		expectStatus(mt.T, env.request(http.MethodGet, "/verify-email?token=stale", nil, ""), http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"testing"
+)
+
+func TestPrivateRecipesOnlyShownToTheirOwner(t *testing.T) {
+	alice, bob := newCaller("alice"), newCaller("bob")
+	admin := newCaller("root")
+	admin.Role = models.RoleAdmin
+	env := memoryEnv(t)
+	recipe := publishedRecipe("Pancakes", alice.ID)
+	recipe.Tags = []string{"breakfast"}
+	recipe = env.seed(t, recipe)
+	env.seed(t, publishedRecipe("Waffles", bob.ID))
+
+	// sees checks whether user gets the recipe from each read endpoint
+	sees := func(user caller, want bool) {
+		t.Helper()
+		env.as(user)
+		rec := env.request(http.MethodGet, "/recipes/"+recipe.ID.Hex(), nil)
+		if found := rec.Code == http.StatusOK; found != want {
+			t.Errorf("%s: GET /recipes/:id = %d, want visible %v", user.Username, rec.Code, want)
+		}
+		for _, target := range []string{"/recipes?sort=name", "/recipes/search?tag=breakfast", "/recipes/search?q=pancakes"} {
+			rec := env.request(http.MethodGet, target, nil)
+			expectStatus(t, rec, http.StatusOK)
+			names := namesOf(t, rec)
+			if found := len(names) > 0 && names[0] == "Pancakes"; found != want {
+				t.Errorf("%s: GET %s = %v, want Pancakes visible %v", user.Username, target, names, want)
+			}
+		}
+	}
+	setVisibility := func(visibility string) {
+		t.Helper()
+		rec := env.as(alice).request(http.MethodPut, "/recipes/"+recipe.ID.Hex()+"/visibility", gin.H{"visibility": visibility})
+		expectStatus(t, rec, http.StatusOK)
+		var updated models.Recipe
+		decodeBody(t, rec, &updated)
+		if updated.Visibility != visibility {
+			t.Errorf("visibility = %q, want %q", updated.Visibility, visibility)
+		}
+	}
+
+	sees(bob, true)
+	setVisibility(models.VisibilityPrivate)
+	sees(alice, true)
+	sees(bob, false)
+	sees(caller{}, false)
+	// Unlike drafts, private recipes are hidden from admins too
+	sees(admin, false)
+
+	setVisibility(models.VisibilityPublic)
+	sees(bob, true)
+	sees(caller{}, true)
+}
+
+func TestSetVisibilityIsForTheOwner(t *testing.T) {
+	alice, bob := newCaller("alice"), newCaller("bob")
+	env := memoryEnv(t)
+	recipe := env.seed(t, publishedRecipe("Pancakes", alice.ID))
+	target := "/recipes/" + recipe.ID.Hex() + "/visibility"
+
+	rec := env.as(bob).request(http.MethodPut, target, gin.H{"visibility": models.VisibilityPrivate})
+	expectCode(t, rec, http.StatusForbidden, msgNotOwnerEdit)
+	rec = env.as(alice).request(http.MethodPut, target, gin.H{"visibility": "friends"})
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	stored, _ := env.store.GetByID(context.Background(), recipe.ID)
+	if stored.Visibility != "" {
+		t.Errorf("visibility = %q, want it unchanged", stored.Visibility)
+	}
+}
+
+func TestUpdateKeepsTheVisibility(t *testing.T) {
+	alice, bob := newCaller("alice"), newCaller("bob")
+	env := memoryEnv(t)
+	recipe := publishedRecipe("Pancakes", alice.ID)
+	recipe.Visibility = models.VisibilityPrivate
+	recipe = env.seed(t, recipe)
+
+	// The body has no visibility, which must not make the recipe public
+	rec := env.as(alice).request(http.MethodPut, "/recipes/"+recipe.ID.Hex(), newRecipe("Crepes"))
+	expectStatus(t, rec, http.StatusOK)
+
+	stored, _ := env.store.GetByID(context.Background(), recipe.ID)
+	if stored.Visibility != models.VisibilityPrivate {
+		t.Errorf("visibility = %q, want %q", stored.Visibility, models.VisibilityPrivate)
+	}
+	rec = env.as(bob).request(http.MethodPatch, "/recipes/"+recipe.ID.Hex(), gin.H{"name": "Waffles"})
+	expectCode(t, rec, http.StatusNotFound, msgRecipeNotFound)
+	rec = env.request(http.MethodGet, "/recipes", nil)
+	expectStatus(t, rec, http.StatusOK)
+	if names := namesOf(t, rec); len(names) != 0 {
+		t.Errorf("GET /recipes = %v, want the private recipe hidden", names)
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/webhooks"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"time"
+)
+
+type WebhooksHandler struct {
+	collection Collection
+	ctx        context.Context
+}
+
+func NewWebhooksHandler(ctx context.Context, collection Collection) *WebhooksHandler {
+	return &WebhooksHandler{
+		collection: collection,
+		ctx:        ctx,
+	}
+}
+
+// swagger:operation POST /webhooks webhooks newWebhook
+// Register a URL notified whenever one of the caller's recipes changes
+// ---
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation, the response includes the signing secret
+//     '400':
+//         description: Invalid input, or a URL resolving to a loopback, private or link-local address
+func (handler *WebhooksHandler) NewWebhookHandler(c *gin.Context) {
+	var webhook models.Webhook
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := webhooks.ValidateURL(c.Request.Context(), webhook.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook.ID = primitive.NewObjectID()
+	webhook.UserID = currentUserID(c)
+	webhook.Secret = hex.EncodeToString(secret)
+	webhook.CreatedAt = time.Now()
+	if _, err := handler.collection.InsertOne(c.Request.Context(), webhook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error while registering the webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
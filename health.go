@@ -0,0 +1,18 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// swagger:operation GET /healthz meta healthz
+// Reports that the service is up. It keeps answering during maintenance
+// ---
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: The service is up
+func HealthzHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
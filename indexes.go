@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+)
+
+var recipeIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "tags", Value: 1}}},
+	{Keys: bson.D{{Key: "publishedAt", Value: -1}}},
+	{Keys: bson.D{{Key: "userId", Value: 1}}},
+	{Keys: bson.D{{Key: "name", Value: "text"}, {Key: "ingredients", Value: "text"}}},
+	// Sparse, since recipes created before slugs existed have none
+	{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+}
+
+var userIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
+}
+
+var inviteIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetUnique(true)},
+}
+
+// ensureIndexes creates the indexes the queries rely on. Creating an index
+// that already exists with the same definition is a no-op, so this is safe
+// to run on every startup.
+func ensureIndexes(ctx context.Context, recipes *mongo.Collection, users *mongo.Collection, invites *mongo.Collection) error {
+	for collection, indexes := range map[*mongo.Collection][]mongo.IndexModel{
+		recipes: recipeIndexes,
+		users:   userIndexes,
+		invites: inviteIndexes,
+	} {
+		names, err := collection.Indexes().CreateMany(ctx, indexes)
+		if err != nil {
+			return err
+		}
+		log.Printf("Ensured indexes %v on %s", names, collection.Name())
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"reflect"
+	"testing"
+)
+
+func TestEnsureIndexes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("creates every index", func(mt *mtest.T) {
+		users := mt.CreateCollection(mtest.Collection{Name: "users"}, false)
+		invites := mt.CreateCollection(mtest.Collection{Name: "invites"}, false)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse(), mtest.CreateSuccessResponse())
+
+		if err := ensureIndexes(context.Background(), mt.Coll, users, invites); err != nil {
+			mt.Fatalf("ensureIndexes: %v", err)
+		}
+
+		keys := make(map[string][]string)
+		for _, event := range mt.GetAllStartedEvents() {
+			if event.CommandName != "createIndexes" {
+				mt.Fatalf("unexpected %s command", event.CommandName)
+			}
+			collection := event.Command.Lookup("createIndexes").StringValue()
+			values, _ := event.Command.Lookup("indexes").Array().Values()
+			for _, value := range values {
+				index := value.Document()
+				key := index.Lookup("key").String()
+				if unique, ok := index.Lookup("unique").BooleanOK(); ok && unique {
+					key += " unique"
+				}
+				keys[collection] = append(keys[collection], key)
+			}
+		}
+
+		want := map[string][]string{
+			mt.Coll.Name(): {
+				`{"tags": {"$numberInt":"1"}}`,
+				`{"publishedAt": {"$numberInt":"-1"}}`,
+				`{"userId": {"$numberInt":"1"}}`,
+				`{"name": "text","ingredients": "text"}`,
+				`{"slug": {"$numberInt":"1"}} unique`,
+			},
+			"users":   {`{"username": {"$numberInt":"1"}} unique`},
+			"invites": {`{"code": {"$numberInt":"1"}} unique`},
+		}
+		if !reflect.DeepEqual(keys, want) {
+			mt.Errorf("index keys = %v, want %v", keys, want)
+		}
+	})
+
+	mt.Run("fails with the server", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 13, Message: "unauthorized"}))
+		users := mt.CreateCollection(mtest.Collection{Name: "users"}, false)
+		invites := mt.CreateCollection(mtest.Collection{Name: "invites"}, false)
+		if err := ensureIndexes(context.Background(), mt.Coll, users, invites); err == nil {
+			mt.Error("ensureIndexes ignored an error")
+		}
+	})
+}
@@ -0,0 +1,19 @@
+package mail
+
+import (
+	"context"
+	"log"
+)
+
+// LogMailer writes emails to the log instead of sending them. It is the
+// default when no SMTP server is configured, for development.
+type LogMailer struct{}
+
+func NewLogMailer() LogMailer {
+	return LogMailer{}
+}
+
+func (LogMailer) Send(ctx context.Context, message Message) error {
+	log.Printf("Email to %s: %s\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
@@ -0,0 +1,16 @@
+// Package mail sends the emails of the API, such as email verifications
+package mail
+
+import "context"
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPMailer sends emails through an SMTP server
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer sends emails from the given address through the server at
+// addr, such as smtp.example.com:587. The username may be empty for servers
+// not requiring authentication.
+func NewSMTPMailer(addr string, username string, password string, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{
+		addr: addr,
+		auth: auth,
+		from: from,
+	}
+}
+
+// Send does not honor ctx, since net/smtp has no support for cancellation
+func (mailer *SMTPMailer) Send(ctx context.Context, message Message) error {
+	if strings.ContainsAny(message.To+message.Subject, "\r\n") {
+		return fmt.Errorf("invalid recipient or subject for %q", message.To)
+	}
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		mailer.from, message.To, message.Subject, message.Body)
+	return smtp.SendMail(mailer.addr, mailer.auth, mailer.from, []string{message.To}, []byte(body))
+}
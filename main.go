@@ -24,43 +24,176 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/gabrielsscti/Recipes-API/audit"
+	"github.com/gabrielsscti/Recipes-API/cache"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"github.com/gabrielsscti/Recipes-API/events"
 	handlers "github.com/gabrielsscti/Recipes-API/handlers"
+	"github.com/gabrielsscti/Recipes-API/mail"
+	"github.com/gabrielsscti/Recipes-API/middleware"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gabrielsscti/Recipes-API/views"
+	"github.com/gabrielsscti/Recipes-API/webhooks"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"golang.org/x/sync/semaphore"
 	"log"
+	"net/http"
 	"os"
+	"strings"
 	"time"
+	// Embedded so that tz works on hosts without a time zone database
+	_ "time/tzdata"
 )
 
 var authHandler *handlers.AuthHandler
 var recipesHandler *handlers.RecipesHandler
+var webhooksHandler *handlers.WebhooksHandler
+var mealPlansHandler *handlers.MealPlansHandler
+var auditHandler *handlers.AuditHandler
+var rateLimiter middleware.Limiter
+
+// requiredEnv lists the variables the service cannot start without
+func requiredEnv() []string {
+	required := []string{"MONGO_URI", "MONGO_DATABASE"}
+	if os.Getenv("JWT_ALG") == "RS256" {
+		return append(required, "JWT_PUBLIC_KEY")
+	}
+	return append(required, "JWT_SECRET")
+}
+
+// setup connects to the backends and builds the handlers from the
+// environment. It is called by main rather than run as init, so that the
+// package can be tested without a database.
+func setup() {
+	if missing := config.Missing(requiredEnv()...); len(missing) > 0 {
+		log.Fatalf("Missing required environment variables: %s", strings.Join(missing, ", "))
+	}
+
+	// QUANTITY_FORMAT is how ingredient quantities are written in JSON
+	switch format := config.String("QUANTITY_FORMAT", models.QuantityNumber); format {
+	case models.QuantityNumber, models.QuantityFraction:
+		models.QuantityFormat = format
+	default:
+		log.Fatalf("Invalid QUANTITY_FORMAT %q, expected %s or %s", format, models.QuantityNumber, models.QuantityFraction)
+	}
 
-func init() {
 	ctx := context.Background()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(os.Getenv("MONGO_URI")))
-	if err = client.Ping(context.TODO(), readpref.Primary()); err != nil {
+	attempts := config.Int("CONNECT_ATTEMPTS", 5)
+	if attempts < 1 {
+		log.Fatalf("Invalid CONNECT_ATTEMPTS %d, must be at least 1", attempts)
+	}
+	maxDelay := config.Duration("CONNECT_MAX_DELAY", 10*time.Second)
+
+	mongoOptions, err := clientOptions(os.Getenv("MONGO_URI"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var client *mongo.Client
+	err = retry("MongoDB", attempts, maxDelay, func() error {
+		var err error
+		client, err = connectMongo(ctx, mongoOptions)
+		return err
+	})
+	if err != nil {
 		log.Fatal(err)
 	}
 	log.Println("Connected to MongoDB")
-	collection := client.Database(os.Getenv("MONGO_DATABASE")).Collection("recipes")
+	dbOptions, err := databaseOptions()
+	if err != nil {
+		log.Fatal(err)
+	}
+	database := client.Database(os.Getenv("MONGO_DATABASE"), dbOptions)
+	collection := database.Collection(config.String("RECIPES_COLLECTION", "recipes"))
 
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     "localhost:6379",
 		Password: "",
 		DB:       0,
 	})
-	status := redisClient.Ping()
-	fmt.Println(status)
 
-	recipesHandler = handlers.NewRecipesHandler(ctx, collection, redisClient)
+	// CACHE_BACKEND=memory caches in process, for development without Redis.
+	// Rate limits are then kept in process as well, as when Redis is down.
+	// CACHE_PREFIX namespaces the cache keys, as in CACHE_PREFIX=recipes-api:,
+	// and the keys of the rate limits and view counts kept in Redis
+	prefix := config.String("CACHE_PREFIX", "")
+	var recipesCache cache.Cache
+	rateLimiter = middleware.NewMemoryLimiter()
+	var viewCounter views.Counter = views.NewNoopCounter()
+	memoryCache := func() cache.Cache {
+		return cache.NewMemoryCache(config.Int("CACHE_MEMORY_SIZE", 1000), config.Duration("CACHE_MEMORY_TTL", 10*time.Minute))
+	}
+	if config.String("CACHE_BACKEND", "redis") == "memory" {
+		recipesCache = memoryCache()
+	} else {
+		err = retry("Redis", attempts, maxDelay, func() error {
+			status := redisClient.Ping()
+			fmt.Println(status)
+			return status.Err()
+		})
+
+		recipesCache = cache.NewRedisCache(redisClient)
+		if err != nil {
+			log.Println("Redis is unavailable, caching in process")
+			recipesCache = memoryCache()
+		} else {
+			rateLimiter = middleware.NewRedisLimiter(redisClient, prefix)
+			viewCounter = views.NewRedisCounter(redisClient, prefix,
+				config.Duration("VIEWS_DEBOUNCE", 30*time.Minute),
+				config.Duration("VIEWS_RETENTION", 7*24*time.Hour))
+		}
+	}
+	if prefix != "" {
+		recipesCache = cache.NewPrefixedCache(recipesCache, prefix)
+	}
+
+	var publisher events.Publisher = events.NewNoopPublisher()
+	if os.Getenv("EVENTS_BACKEND") == "redis" {
+		publisher = events.NewRedisPublisher(redisClient, "recipes.events")
+	}
+
+	collectionWebhooks := database.Collection(config.String("WEBHOOKS_COLLECTION", "webhooks"))
+	webhooksHandler = handlers.NewWebhooksHandler(ctx, collectionWebhooks)
+	publisher = events.NewMultiPublisher(publisher, webhooks.NewDispatcher(ctx, collectionWebhooks))
+
+	collectionAudit := database.Collection(config.String("AUDIT_COLLECTION", "audit_log"))
+	auditHandler = handlers.NewAuditHandler(ctx, collectionAudit)
+	var recorder audit.Recorder = audit.NewNoopRecorder()
+	if config.Bool("AUDIT_ENABLED", false) {
+		recorder = audit.NewMongoRecorder(collectionAudit)
+	}
+
+	var recipeStore store.RecipeStore = store.NewMongoStore(collection)
+	// SLOW_QUERY_THRESHOLD logs the store calls taking longer, 0 disables it
+	if threshold := config.Duration("SLOW_QUERY_THRESHOLD", 200*time.Millisecond); threshold > 0 {
+		recipeStore = store.NewTimedStore(recipeStore, threshold)
+	}
+	recipesHandler = handlers.NewRecipesHandler(ctx, recipeStore, recipesCache, publisher, recorder, viewCounter)
+	go views.Flush(ctx, viewCounter, recipeStore, config.Duration("VIEWS_FLUSH_INTERVAL", time.Minute))
+
+	collectionMealPlans := database.Collection(config.String("MEALPLANS_COLLECTION", "mealplans"))
+	mealPlansHandler = handlers.NewMealPlansHandler(ctx, collectionMealPlans, recipeStore)
+
+	collectionUsers := database.Collection(config.String("USERS_COLLECTION", "users"))
+	collectionInvites := database.Collection(config.String("INVITES_COLLECTION", "invites"))
+	if err := ensureIndexes(ctx, collection, collectionUsers, collectionInvites); err != nil {
+		log.Fatal(err)
+	}
+	signer, err := handlers.LoadTokenSigner()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	collectionUsers := client.Database(os.Getenv("MONGO_DATABASE")).Collection("users")
-	authHandler = handlers.NewAuthHandler(ctx, collectionUsers)
+	// Without SMTP_ADDR, emails are written to the log
+	var mailer mail.Mailer = mail.NewLogMailer()
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		mailer = mail.NewSMTPMailer(addr, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), config.String("MAIL_FROM", "no-reply@localhost"))
+	}
+	authHandler = handlers.NewAuthHandler(ctx, collectionUsers, collectionInvites, signer, recorder, recipesCache, mailer)
 }
 
 type Recipe struct {
@@ -73,7 +206,66 @@ type Recipe struct {
 }
 
 func main() {
-	router := gin.Default()
+	setup()
+
+	// TRUSTED_PROXIES lists the IPs or CIDRs of the proxies in front of the
+	// API. Forwarded headers from anyone else are ignored, since they can be spoofed.
+	trustedProxies := config.List("TRUSTED_PROXIES")
+
+	// MAINTENANCE_MODE=readonly rejects writes and full rejects everything
+	// but the health check, while deploying or migrating
+	maintenanceMode, err := middleware.ParseMaintenanceMode(config.String("MAINTENANCE_MODE", middleware.MaintenanceOff))
+	if err != nil {
+		log.Fatal("Invalid MAINTENANCE_MODE: ", err)
+	}
+	maintenance := middleware.Maintenance(maintenanceMode,
+		[]string{"/signin", "/refresh", "/recipes/validate", "/shopping-list"},
+		[]string{"/healthz"})
+
+	// With ADMIN_ADDR set, the admin routes are only served by a separate
+	// listener, typically bound to localhost, instead of the public API
+	adminAddr := os.Getenv("ADMIN_ADDR")
+	router, adminRouter := newRouters(trustedProxies, maintenance, adminAddr != "")
+	if adminRouter != nil {
+		adminServer := &http.Server{
+			Addr:    adminAddr,
+			Handler: adminRouter,
+		}
+		go func() {
+			log.Printf("Admin API listening on %s", adminAddr)
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	if err := serve(router); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newRouters builds the router of the public API. With separateAdmin, the
+// admin routes are left out of it and served by adminRouter instead.
+func newRouters(trustedProxies []string, maintenance gin.HandlerFunc, separateAdmin bool) (router *gin.Engine, adminRouter *gin.Engine) {
+	router = gin.Default()
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
+	// MAX_CONCURRENT_REQUESTS caps the requests handled at once, so that load
+	// spikes are turned away with 503 instead of piling up on MongoDB
+	if maxConcurrent := config.Int("MAX_CONCURRENT_REQUESTS", 0); maxConcurrent > 0 {
+		router.Use(middleware.Concurrency(semaphore.NewWeighted(int64(maxConcurrent)), 1))
+	}
+	// RESPONSE_TIME_BUDGET logs the requests taking longer, 0 disables it
+	if budget := config.Duration("RESPONSE_TIME_BUDGET", time.Second); budget > 0 {
+		router.Use(middleware.SlowRequests(budget))
+	}
+	router.Use(maintenance)
+	router.Use(middleware.Timeout(config.Duration("REQUEST_TIMEOUT", 10*time.Second), config.Duration("STREAM_TIMEOUT", 5*time.Minute)))
+	router.Use(middleware.Gzip(config.Int("GZIP_MIN_SIZE", 1024)))
+	// PRETTY_JSON indents every JSON response, otherwise only those asked with ?pretty=true
+	router.Use(middleware.PrettyJSON(config.Bool("PRETTY_JSON", false)))
+	router.Use(handlers.TimeZoneMiddleware())
 
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
@@ -81,22 +273,103 @@ func main() {
 		AllowHeaders:     []string{"Origin"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
+		// CORS_MAX_AGE is how long browsers may cache preflight responses,
+		// sent as Access-Control-Max-Age. 0 leaves the header out.
+		MaxAge: config.Duration("CORS_MAX_AGE", 12*time.Hour),
 	}))
 
-	router.GET("/recipes", recipesHandler.ListRecipesHandler)
-	router.POST("/signin", authHandler.SignInHandler)
-	router.POST("/signup", authHandler.SignUpHandler)
-	router.POST("/refresh", authHandler.RefreshHandler)
+	router.GET("/swagger.json", SwaggerSpecHandler)
+	router.GET("/docs", SwaggerUIHandler)
+	router.GET("/version", VersionHandler)
+	router.GET("/healthz", HealthzHandler)
+	// HTTP_CACHE_* are the max-age of the Cache-Control header of the public
+	// reads, zero forbids caching
+	router.GET("/recipes", cacheFor("HTTP_CACHE_RECIPES", 30*time.Second), authHandler.OptionalAuthMiddleware(), recipesHandler.ListRecipesHandler)
+	router.GET("/recipes/tags", cacheFor("HTTP_CACHE_TAGS", time.Minute), recipesHandler.ListTagsHandler)
+	router.GET("/recipes/random", cacheFor("HTTP_CACHE_RANDOM", 0), recipesHandler.RandomRecipeHandler)
+	router.GET("/recipes/recent", cacheFor("HTTP_CACHE_RECENT", 30*time.Second), recipesHandler.RecentRecipesHandler)
+	router.GET("/recipes/trending", cacheFor("HTTP_CACHE_TRENDING", time.Minute), recipesHandler.TrendingRecipesHandler)
+	router.GET("/recipes/stats", cacheFor("HTTP_CACHE_STATS", 5*time.Minute), recipesHandler.RecipeStatsHandler)
+	router.GET("/recipes/shared", middleware.NoStore(), recipesHandler.GetSharedRecipeHandler)
+
+	// RATE_LIMIT_AUTH and RATE_LIMIT_WRITES are requests per duration, such as
+	// 10/1m, allowed to each client on the sign in and write endpoints
+	authLimit := rateLimit("auth", "RATE_LIMIT_AUTH", "10/1m")
+	writeLimit := rateLimit("writes", "RATE_LIMIT_WRITES", "60/1m")
+
+	router.POST("/signin", authLimit, middleware.NoStore(), authHandler.SignInHandler)
+	router.POST("/signup", authLimit, authHandler.SignUpHandler)
+	router.POST("/refresh", authLimit, middleware.NoStore(), authHandler.RefreshHandler)
+	router.GET("/verify-email", authLimit, authHandler.VerifyEmailHandler)
 	authorized := router.Group("/")
-	authorized.Use(authHandler.AuthMiddleware())
+	authorized.Use(middleware.NoStore(), authHandler.AuthMiddleware())
 	{
-		authorized.POST("/recipes", recipesHandler.NewRecipeHandler)
+		authorized.POST("/recipes", writeLimit, recipesHandler.NewRecipeHandler)
+		authorized.POST("/recipes/validate", recipesHandler.ValidateRecipeHandler)
 		authorized.GET("/recipes/search", recipesHandler.SearchRecipeHandler)
+		authorized.GET("/recipes/by-ingredients", recipesHandler.SearchByIngredientsHandler)
+		authorized.GET("/recipes/slug/:slug", recipesHandler.GetRecipeBySlugHandler)
 		authorized.GET("/recipes/:id", recipesHandler.GetRecipeHandler)
-		authorized.PUT("/recipes/:id", recipesHandler.UpdateRecipeHandler)
-		authorized.DELETE("/recipes/:id", recipesHandler.DeleteRecipeHandler)
+		authorized.PUT("/recipes/:id", writeLimit, recipesHandler.UpdateRecipeHandler)
+		authorized.PATCH("/recipes/:id", writeLimit, recipesHandler.PatchRecipeHandler)
+		authorized.DELETE("/recipes/:id", writeLimit, recipesHandler.DeleteRecipeHandler)
+		authorized.POST("/recipes/:id/clone", writeLimit, recipesHandler.CloneRecipeHandler)
+		authorized.POST("/recipes/:id/publish", writeLimit, recipesHandler.PublishRecipeHandler)
+		authorized.PUT("/recipes/:id/visibility", writeLimit, recipesHandler.SetVisibilityHandler)
+		authorized.POST("/recipes/:id/share", writeLimit, recipesHandler.ShareRecipeHandler)
+		authorized.GET("/recipes/:id/nutrition", recipesHandler.GetRecipeNutritionHandler)
+		authorized.GET("/recipes/:id/related", recipesHandler.RelatedRecipesHandler)
+		authorized.GET("/recipes/:id/similar", recipesHandler.SimilarRecipesHandler)
+		authorized.GET("/recipes/:id/print", recipesHandler.PrintRecipeHandler)
+		authorized.PUT("/recipes/:id/tags", writeLimit, recipesHandler.ReplaceTagsHandler)
+		authorized.POST("/recipes/:id/tags", writeLimit, recipesHandler.AddTagHandler)
+		authorized.DELETE("/recipes/:id/tags/:tag", writeLimit, recipesHandler.RemoveTagHandler)
+		authorized.POST("/recipes/bulk-delete", writeLimit, recipesHandler.BulkDeleteRecipesHandler)
+		authorized.GET("/whoami", authHandler.WhoAmIHandler)
+		authorized.GET("/user/recipes", recipesHandler.ListUserRecipesHandler)
+		authorized.GET("/user/activity", auditHandler.ActivityHandler)
 		authorized.GET("/user/:username", authHandler.GetUserHandler)
+		authorized.POST("/webhooks", writeLimit, webhooksHandler.NewWebhookHandler)
+		authorized.POST("/mealplans", writeLimit, mealPlansHandler.NewMealPlanHandler)
+		authorized.GET("/mealplans/week", mealPlansHandler.WeekHandler)
+		authorized.GET("/mealplans/:id", mealPlansHandler.GetMealPlanHandler)
+		authorized.PUT("/mealplans/:id", writeLimit, mealPlansHandler.UpdateMealPlanHandler)
+		authorized.POST("/shopping-list", recipesHandler.ShoppingListHandler)
+	}
+
+	if separateAdmin {
+		adminRouter = gin.Default()
+		if err := adminRouter.SetTrustedProxies(trustedProxies); err != nil {
+			log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+		}
+		adminRouter.Use(maintenance)
+		registerAdminRoutes(adminRouter.Group("/"))
+	} else {
+		registerAdminRoutes(router.Group("/"))
+	}
+	return router, adminRouter
+}
+
+func registerAdminRoutes(admin *gin.RouterGroup) {
+	admin.Use(middleware.NoStore(), authHandler.AuthMiddleware(), authHandler.AdminMiddleware())
+	{
+		admin.GET("/users", authHandler.ListUsersHandler)
+		admin.GET("/admin/audit", auditHandler.ListAuditHandler)
+		admin.POST("/admin/invites", authHandler.GenerateInvitesHandler)
+		admin.POST("/admin/tags/rename", recipesHandler.RenameTagHandler)
+		admin.POST("/admin/cache/warm", recipesHandler.WarmCacheHandler)
+		admin.POST("/admin/cache/flush", recipesHandler.FlushCacheHandler)
 	}
-	router.Run()
+}
+
+func rateLimit(group, key, fallback string) gin.HandlerFunc {
+	rate, err := middleware.ParseRate(config.String(key, fallback))
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return middleware.RateLimit(rateLimiter, group, rate)
+}
+
+func cacheFor(key string, fallback time.Duration) gin.HandlerFunc {
+	return middleware.CacheControl(config.Duration(key, fallback))
 }
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gabrielsscti/Recipes-API/audit"
+	"github.com/gabrielsscti/Recipes-API/cache"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"github.com/gabrielsscti/Recipes-API/events"
+	"github.com/gabrielsscti/Recipes-API/handlers"
+	"github.com/gabrielsscti/Recipes-API/mail"
+	"github.com/gabrielsscti/Recipes-API/middleware"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gabrielsscti/Recipes-API/views"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// get serves a GET of path by handler, registered on path alone
+func get(path string, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.GET(path, handler)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestRequiredEnvReportsMissingVariables(t *testing.T) {
+	t.Setenv("MONGO_URI", "")
+	t.Setenv("MONGO_DATABASE", "recipes")
+	t.Setenv("JWT_ALG", "")
+	t.Setenv("JWT_SECRET", "secret")
+	if missing := config.Missing(requiredEnv()...); !reflect.DeepEqual(missing, []string{"MONGO_URI"}) {
+		t.Errorf("missing = %v, want [MONGO_URI]", missing)
+	}
+
+	t.Setenv("MONGO_URI", "mongodb://localhost:27017")
+	if missing := config.Missing(requiredEnv()...); len(missing) != 0 {
+		t.Errorf("missing = %v, want none", missing)
+	}
+
+	t.Setenv("JWT_ALG", "RS256")
+	t.Setenv("JWT_PUBLIC_KEY", "")
+	if missing := config.Missing(requiredEnv()...); !reflect.DeepEqual(missing, []string{"JWT_PUBLIC_KEY"}) {
+		t.Errorf("with RS256, missing = %v, want [JWT_PUBLIC_KEY]", missing)
+	}
+}
+
+// setupTestHandlers builds the handlers the routers use over the in-memory
+// store, in place of setup
+func setupTestHandlers(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	signer, err := handlers.LoadTokenSigner()
+	if err != nil {
+		t.Fatalf("LoadTokenSigner: %v", err)
+	}
+	ctx := context.Background()
+	recipeStore := store.NewMemoryStore()
+	recipesCache := cache.NewMemoryCache(100, time.Hour)
+	recipesHandler = handlers.NewRecipesHandler(ctx, recipeStore, recipesCache, events.NewNoopPublisher(), audit.NewNoopRecorder(), views.NewNoopCounter())
+	authHandler = handlers.NewAuthHandler(ctx, nil, nil, signer, audit.NewNoopRecorder(), recipesCache, mail.NewLogMailer())
+	auditHandler = handlers.NewAuditHandler(ctx, nil)
+	webhooksHandler = handlers.NewWebhooksHandler(ctx, nil)
+	mealPlansHandler = handlers.NewMealPlansHandler(ctx, nil, recipeStore)
+	rateLimiter = middleware.NewMemoryLimiter()
+}
+
+func statusOf(t *testing.T, server *httptest.Server, path string) int {
+	t.Helper()
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestAdminRoutesOnlyOnAdminListener(t *testing.T) {
+	setupTestHandlers(t)
+	router, adminRouter := newRouters(nil, func(c *gin.Context) {}, true)
+	if adminRouter == nil {
+		t.Fatal("no admin router with separateAdmin")
+	}
+	public := httptest.NewServer(router)
+	defer public.Close()
+	admin := httptest.NewServer(adminRouter)
+	defer admin.Close()
+
+	for _, path := range []string{"/users", "/admin/audit"} {
+		// Unauthenticated, so reaching the route is answered with 401
+		if status := statusOf(t, admin, path); status != http.StatusUnauthorized {
+			t.Errorf("admin listener: GET %s = %d, want 401", path, status)
+		}
+		if status := statusOf(t, public, path); status != http.StatusNotFound {
+			t.Errorf("public listener: GET %s = %d, want 404", path, status)
+		}
+	}
+	if status := statusOf(t, public, "/healthz"); status != http.StatusOK {
+		t.Errorf("public listener: GET /healthz = %d, want 200", status)
+	}
+	if status := statusOf(t, admin, "/healthz"); status != http.StatusNotFound {
+		t.Errorf("admin listener: GET /healthz = %d, want 404", status)
+	}
+}
+
+func TestAdminRoutesOnPublicListenerByDefault(t *testing.T) {
+	setupTestHandlers(t)
+	router, adminRouter := newRouters(nil, func(c *gin.Context) {}, false)
+	if adminRouter != nil {
+		t.Fatal("an admin router was built without separateAdmin")
+	}
+	public := httptest.NewServer(router)
+	defer public.Close()
+
+	if status := statusOf(t, public, "/users"); status != http.StatusUnauthorized {
+		t.Errorf("GET /users = %d, want 401", status)
+	}
+}
+
+func TestPreflightMaxAge(t *testing.T) {
+	setupTestHandlers(t)
+	tests := map[string]string{"10m": "600", "0": ""}
+	for maxAge, want := range tests {
+		t.Setenv("CORS_MAX_AGE", maxAge)
+		router, _ := newRouters(nil, func(c *gin.Context) {}, false)
+
+		req := httptest.NewRequest(http.MethodOptions, "/recipes", nil)
+		req.Header.Set("Origin", "http://localhost:3000")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Max-Age"); got != want {
+			t.Errorf("CORS_MAX_AGE=%s: Access-Control-Max-Age = %q, want %q", maxAge, got, want)
+		}
+	}
+}
+
+func TestCacheAdminRoutesRequireAnAdmin(t *testing.T) {
+	setupTestHandlers(t)
+	router, _ := newRouters(nil, func(c *gin.Context) {}, false)
+	signer, _ := handlers.LoadTokenSigner()
+	tokenFor := func(role string) string {
+		token, err := signer.Sign(&handlers.Claims{
+			Username:       "alice",
+			UserID:         primitive.NewObjectID(),
+			Role:           role,
+			SessionStart:   time.Now().Unix(),
+			StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()},
+		})
+		if err != nil {
+			t.Fatalf("signing a token: %v", err)
+		}
+		return token
+	}
+
+	tokens := map[string]int{
+		"":                         http.StatusUnauthorized,
+		tokenFor(models.RoleUser):  http.StatusForbidden,
+		tokenFor(models.RoleAdmin): http.StatusOK,
+	}
+	for _, path := range []string{"/admin/cache/warm", "/admin/cache/flush"} {
+		for token, want := range tokens {
+			req := httptest.NewRequest(http.MethodPost, path, nil)
+			if token != "" {
+				req.Header.Set("Authorization", token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != want {
+				t.Errorf("POST %s = %d, want %d", path, rec.Code, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// CacheControl lets browsers and CDNs cache successful responses for maxAge.
+// Requests sending credentials get responses specific to their user, which
+// are marked no-store like errors and a maxAge of zero.
+func CacheControl(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxAge <= 0 || c.GetHeader("Authorization") != "" {
+			noStore(c.Writer.Header())
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+		header.Set("Expires", time.Now().Add(maxAge).UTC().Format(http.TimeFormat))
+		header.Add("Vary", "Accept")
+		c.Writer = &cacheControlWriter{ResponseWriter: c.Writer}
+		c.Next()
+	}
+}
+
+// NoStore forbids caching the responses, for routes specific to the caller
+func NoStore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		noStore(c.Writer.Header())
+		c.Next()
+	}
+}
+
+func noStore(header http.Header) {
+	header.Set("Cache-Control", "no-store")
+	header.Del("Expires")
+}
+
+// cacheControlWriter turns the cache headers into no-store when the handler
+// answers with anything but a success, so that errors are not cached
+type cacheControlWriter struct {
+	gin.ResponseWriter
+}
+
+func (writer *cacheControlWriter) WriteHeader(code int) {
+	if code < 200 || code >= 300 {
+		noStore(writer.Header())
+	}
+	writer.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func answer(status int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(status, gin.H{})
+	}
+}
+
+func TestCacheControlOnPublicReads(t *testing.T) {
+	rec := serve(httptest.NewRequest(http.MethodGet, "/recipes", nil), answer(http.StatusOK), CacheControl(30*time.Second))
+
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=30" {
+		t.Errorf("Cache-Control = %q, want public, max-age=30", got)
+	}
+	expires, err := http.ParseTime(rec.Header().Get("Expires"))
+	if err != nil {
+		t.Fatalf("Expires = %q: %v", rec.Header().Get("Expires"), err)
+	}
+	if until := time.Until(expires); until < 28*time.Second || until > 31*time.Second {
+		t.Errorf("Expires is in %v, want 30s", until)
+	}
+}
+
+func TestCacheControlNoStore(t *testing.T) {
+	authenticated := httptest.NewRequest(http.MethodGet, "/recipes", nil)
+	authenticated.Header.Set("Authorization", "token")
+
+	tests := map[string]*httptest.ResponseRecorder{
+		"authenticated": serve(authenticated, answer(http.StatusOK), CacheControl(30*time.Second)),
+		"error":         serve(httptest.NewRequest(http.MethodGet, "/recipes", nil), answer(http.StatusNotFound), CacheControl(30*time.Second)),
+		"no max age":    serve(httptest.NewRequest(http.MethodGet, "/recipes", nil), answer(http.StatusOK), CacheControl(0)),
+		"user specific": serve(httptest.NewRequest(http.MethodGet, "/whoami", nil), answer(http.StatusOK), NoStore()),
+	}
+	for name, rec := range tests {
+		if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+			t.Errorf("%s: Cache-Control = %q, want no-store", name, got)
+		}
+		if got := rec.Header().Get("Expires"); got != "" {
+			t.Errorf("%s: Expires = %q, want none", name, got)
+		}
+	}
+}
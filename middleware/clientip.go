@@ -0,0 +1,12 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// ClientIP returns the IP of the client. X-Forwarded-For and X-Real-IP are
+// only honoured when the request comes from a proxy the router trusts, see
+// gin's SetTrustedProxies.
+func ClientIP(c *gin.Context) string {
+	return c.ClientIP()
+}
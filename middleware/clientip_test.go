@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPHonoursOnlyTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		want       string
+	}{
+		{"trusted proxy", []string{"10.0.0.1"}, "10.0.0.1:4321", "203.0.113.7"},
+		{"trusted range", []string{"10.0.0.0/8"}, "10.1.2.3:4321", "203.0.113.7"},
+		{"untrusted peer", []string{"10.0.0.1"}, "198.51.100.9:4321", "198.51.100.9"},
+		{"no proxies", nil, "10.0.0.1:4321", "10.0.0.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.New()
+			if err := router.SetTrustedProxies(test.trusted); err != nil {
+				t.Fatalf("SetTrustedProxies: %v", err)
+			}
+			router.GET("/ip", func(c *gin.Context) {
+				c.String(http.StatusOK, ClientIP(c))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = test.remoteAddr
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if ip := rec.Body.String(); ip != test.want {
+				t.Errorf("ClientIP = %q, want %q", ip, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/semaphore"
+	"net/http"
+)
+
+// Concurrency caps the requests in flight, answering 503 with Retry-After
+// instead of queueing requests once the semaphore is exhausted. Each request
+// takes weight from the semaphore, so route groups sharing it can count
+// expensive requests for more.
+func Concurrency(inFlight *semaphore.Weighted, weight int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !inFlight.TryAcquire(weight) {
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Server is busy, try again later"})
+			return
+		}
+		defer inFlight.Release(weight)
+		c.Next()
+	}
+}
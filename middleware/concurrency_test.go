@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/semaphore"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConcurrencyRejectsRequestsOverTheLimit(t *testing.T) {
+	release := make(chan struct{})
+	slow := func(c *gin.Context) {
+		<-release
+		c.Status(http.StatusOK)
+	}
+	limit := Concurrency(semaphore.NewWeighted(2), 1)
+
+	const requests = 5
+	results := make(chan *httptest.ResponseRecorder, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			results <- serve(httptest.NewRequest(http.MethodGet, "/recipes", nil), slow, limit)
+		}()
+	}
+
+	// The two requests holding the semaphore wait for release, so the
+	// others are answered first
+	for i := 0; i < requests-2; i++ {
+		rec := <-results
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+			t.Errorf("rejected request: status = %d, Retry-After = %q, want 503 and 1", rec.Code, rec.Header().Get("Retry-After"))
+		}
+	}
+	close(release)
+	for i := 0; i < 2; i++ {
+		if rec := <-results; rec.Code != http.StatusOK {
+			t.Errorf("admitted request: status = %d, want 200", rec.Code)
+		}
+	}
+
+	// The semaphore is released once the requests are done
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/recipes", nil), slow, limit); rec.Code != http.StatusOK {
+		t.Errorf("request after the burst: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestConcurrencyWeighsRequests(t *testing.T) {
+	inFlight := semaphore.NewWeighted(3)
+	inFlight.TryAcquire(2)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/recipes/search", nil), ok, Concurrency(inFlight, 2)); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("a heavy request with one unit left: status = %d, want 503", rec.Code)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/recipes", nil), ok, Concurrency(inFlight, 1)); rec.Code != http.StatusOK {
+		t.Errorf("a light request with one unit left: status = %d, want 200", rec.Code)
+	}
+}
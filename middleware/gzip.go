@@ -0,0 +1,118 @@
+// Package middleware holds gin middlewares that are not tied to a handler
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+// incompressibleTypes are content types that are already compressed
+var incompressibleTypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/octet-stream",
+}
+
+// Gzip compresses responses for clients sending Accept-Encoding: gzip.
+// Responses smaller than minSize bytes, already encoded responses and
+// already compressed content types are sent as is.
+func Gzip(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+			c.Next()
+			return
+		}
+
+		writer := &gzipWriter{ResponseWriter: c.Writer, minSize: minSize}
+		c.Writer = writer
+		c.Header("Vary", "Accept-Encoding")
+		c.Next()
+		writer.close()
+	}
+}
+
+// gzipWriter buffers the response until it knows whether it is large
+// enough to be worth compressing
+type gzipWriter struct {
+	gin.ResponseWriter
+	minSize int
+	buffer  bytes.Buffer
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (writer *gzipWriter) Write(data []byte) (int, error) {
+	if writer.decided {
+		if writer.gz != nil {
+			return writer.gz.Write(data)
+		}
+		return writer.ResponseWriter.Write(data)
+	}
+
+	writer.buffer.Write(data)
+	if writer.buffer.Len() >= writer.minSize {
+		if err := writer.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (writer *gzipWriter) WriteString(s string) (int, error) {
+	return writer.Write([]byte(s))
+}
+
+// decide flushes the buffered bytes, compressed when large is set and the
+// response is compressible
+func (writer *gzipWriter) decide(large bool) error {
+	writer.decided = true
+	header := writer.Header()
+	if large && header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		writer.gz = gzip.NewWriter(writer.ResponseWriter)
+		_, err := writer.gz.Write(writer.buffer.Bytes())
+		return err
+	}
+	_, err := writer.ResponseWriter.Write(writer.buffer.Bytes())
+	return err
+}
+
+// Flush sends what has been written so far, so that streamed responses
+// reach the client as they are produced
+func (writer *gzipWriter) Flush() {
+	if !writer.decided {
+		writer.decide(writer.buffer.Len() >= writer.minSize)
+	}
+	if writer.gz != nil {
+		writer.gz.Flush()
+	}
+	writer.ResponseWriter.Flush()
+}
+
+func (writer *gzipWriter) close() {
+	if !writer.decided {
+		if writer.buffer.Len() > 0 {
+			writer.decide(false)
+		}
+		return
+	}
+	if writer.gz != nil {
+		writer.gz.Close()
+	}
+}
+
+func compressible(contentType string) bool {
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"github.com/gin-gonic/gin"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipRequest() *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/recipes", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	return req
+}
+
+func TestGzipCompressesLargeResponses(t *testing.T) {
+	names := make([]string, 500)
+	for i := range names {
+		names[i] = "Pancakes"
+	}
+	rec := serve(gzipRequest(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, names)
+	}, Gzip(1024))
+
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("headers = %v, want a gzip encoded response varying on Accept-Encoding", rec.Header())
+	}
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("reading the gzip stream: %v", err)
+	}
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("decompressing: %v", err)
+	}
+	if !strings.HasPrefix(string(body), `["Pancakes","Pancakes"`) || strings.Count(string(body), "Pancakes") != 500 {
+		t.Errorf("decompressed body = %.60s..., want the JSON array", body)
+	}
+}
+
+func TestGzipLeavesSmallResponses(t *testing.T) {
+	rec := serve(gzipRequest(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}, Gzip(1024))
+
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != `{"status":"ok"}` {
+		t.Errorf("encoding, body = %q, %q, want the plain JSON", rec.Header().Get("Content-Encoding"), rec.Body.String())
+	}
+}
+
+func TestGzipSkipsClientsWithoutGzip(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/recipes", nil)
+	large := strings.Repeat("a", 4096)
+	rec := serve(req, func(c *gin.Context) {
+		c.String(http.StatusOK, large)
+	}, Gzip(1024))
+
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != large {
+		t.Errorf("a client not accepting gzip got Content-Encoding %q", rec.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestGzipSkipsCompressedTypes(t *testing.T) {
+	image := make([]byte, 4096)
+	rec := serve(gzipRequest(), func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", image)
+	}, Gzip(1024))
+
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != len(image) {
+		t.Errorf("an image was sent with Content-Encoding %q", rec.Header().Get("Content-Encoding"))
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Maintenance modes, set with MAINTENANCE_MODE
+const (
+	MaintenanceOff      = "off"
+	MaintenanceReadOnly = "readonly"
+	MaintenanceFull     = "full"
+)
+
+// ParseMaintenanceMode validates a maintenance mode, empty meaning off
+func ParseMaintenanceMode(mode string) (string, error) {
+	switch mode {
+	case "", MaintenanceOff:
+		return MaintenanceOff, nil
+	case MaintenanceReadOnly, MaintenanceFull:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("maintenance mode must be one of %s, %s or %s", MaintenanceOff, MaintenanceReadOnly, MaintenanceFull)
+	}
+}
+
+// Maintenance answers 503 Service Unavailable to the requests the mode does
+// not allow. In readonly mode, only GET, HEAD and OPTIONS requests and the
+// routes in reads, which use POST without changing anything, go through. In
+// full mode, only the routes in always do, such as the health check.
+func Maintenance(mode string, reads []string, always []string) gin.HandlerFunc {
+	allowedReads := routeSet(append(reads, always...))
+	allowedAlways := routeSet(always)
+
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		switch {
+		case mode == MaintenanceReadOnly && !safeMethod(c.Request.Method) && !allowedReads[route]:
+		case mode == MaintenanceFull && !allowedAlways[route]:
+		default:
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", "60")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "The API is under maintenance, try again later"})
+	}
+}
+
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func routeSet(routes []string) map[string]bool {
+	set := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		set[route] = true
+	}
+	return set
+}
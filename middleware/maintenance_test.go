@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMaintenance(t *testing.T) {
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/recipes"},
+		{http.MethodPost, "/recipes"},
+		{http.MethodDelete, "/recipes"},
+		{http.MethodPost, "/signin"},
+		{http.MethodGet, "/healthz"},
+	}
+	// allowed lists the requests each mode lets through, in the order above
+	modes := map[string][]bool{
+		MaintenanceOff:      {true, true, true, true, true},
+		MaintenanceReadOnly: {true, false, false, true, true},
+		MaintenanceFull:     {false, false, false, false, true},
+	}
+	for mode, allowed := range modes {
+		t.Run(mode, func(t *testing.T) {
+			maintenance := Maintenance(mode, []string{"/signin"}, []string{"/healthz"})
+			for i, request := range requests {
+				rec := serve(httptest.NewRequest(request.method, request.path, nil), answer(http.StatusOK), maintenance)
+				if allowed[i] && rec.Code != http.StatusOK {
+					t.Errorf("%s %s = %d, want it served", request.method, request.path, rec.Code)
+				}
+				if !allowed[i] && (rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "") {
+					t.Errorf("%s %s = %d, want 503 with Retry-After", request.method, request.path, rec.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMaintenanceMode(t *testing.T) {
+	for value, want := range map[string]string{"": MaintenanceOff, "off": MaintenanceOff, "readonly": MaintenanceReadOnly, "full": MaintenanceFull} {
+		if mode, err := ParseMaintenanceMode(value); err != nil || mode != want {
+			t.Errorf("ParseMaintenanceMode(%q) = %q, %v, want %q", value, mode, err, want)
+		}
+	}
+	if _, err := ParseMaintenanceMode("read-only"); err == nil {
+		t.Error("an unknown mode was accepted")
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// serve routes req through a router running middlewares before handler,
+// which is registered for any method on the request path
+func serve(req *http.Request, handler gin.HandlerFunc, middlewares ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(middlewares...)
+	router.Any(req.URL.Path, handler)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
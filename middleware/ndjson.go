@@ -0,0 +1,17 @@
+package middleware
+
+import "strings"
+
+// NDJSON is the media type of newline-delimited JSON, which the list
+// endpoints stream one recipe per line
+const NDJSON = "application/x-ndjson"
+
+// AcceptsNDJSON reports whether the Accept header asks for NDJSON
+func AcceptsNDJSON(accept string) bool {
+	for _, mediaType := range strings.Split(accept, ",") {
+		if strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0]) == NDJSON {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+)
+
+// PrettyJSON indents the JSON responses of requests passing ?pretty=true, or
+// of every request when always is set, for people reading them by hand.
+// Streamed NDJSON responses are left as they are.
+func PrettyJSON(always bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pretty := always
+		if value, ok := c.GetQuery("pretty"); ok {
+			pretty, _ = strconv.ParseBool(value)
+		}
+		if !pretty || AcceptsNDJSON(c.GetHeader("Accept")) {
+			c.Next()
+			return
+		}
+
+		writer := &prettyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		writer.close()
+	}
+}
+
+// prettyWriter buffers the response so it can be indented as a whole
+type prettyWriter struct {
+	gin.ResponseWriter
+	buffer bytes.Buffer
+}
+
+func (writer *prettyWriter) Write(data []byte) (int, error) {
+	return writer.buffer.Write(data)
+}
+
+func (writer *prettyWriter) WriteString(s string) (int, error) {
+	return writer.Write([]byte(s))
+}
+
+// close sends the buffered response, indented when it is JSON
+func (writer *prettyWriter) close() {
+	if writer.buffer.Len() == 0 {
+		return
+	}
+	body := writer.buffer.Bytes()
+	if strings.HasPrefix(writer.Header().Get("Content-Type"), "application/json") {
+		var indented bytes.Buffer
+		if json.Indent(&indented, body, "", "  ") == nil {
+			indented.WriteByte('\n')
+			body = indented.Bytes()
+		}
+	}
+	writer.ResponseWriter.Write(body)
+}
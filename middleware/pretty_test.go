@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func recipeJSON(c *gin.Context) {
+	c.JSON(http.StatusCreated, gin.H{"name": "Pancakes", "tags": []string{"sweet"}})
+}
+
+func TestPrettyJSON(t *testing.T) {
+	const compact = `{"name":"Pancakes","tags":["sweet"]}`
+	const indented = "{\n  \"name\": \"Pancakes\",\n  \"tags\": [\n    \"sweet\"\n  ]\n}\n"
+	tests := []struct {
+		name   string
+		target string
+		always bool
+		want   string
+	}{
+		{"by default", "/recipes", false, compact},
+		{"asked for", "/recipes?pretty=true", false, indented},
+		{"declined", "/recipes?pretty=false", false, compact},
+		{"always", "/recipes", true, indented},
+		{"declined despite always", "/recipes?pretty=false", true, compact},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := serve(httptest.NewRequest(http.MethodGet, test.target, nil), recipeJSON, PrettyJSON(test.always))
+			if rec.Code != http.StatusCreated {
+				t.Errorf("status = %d, want the handler's 201", rec.Code)
+			}
+			if body := rec.Body.String(); body != test.want {
+				t.Errorf("body = %q, want %q", body, test.want)
+			}
+		})
+	}
+}
+
+func TestPrettyJSONLeavesOtherContentAlone(t *testing.T) {
+	text := func(c *gin.Context) { c.String(http.StatusOK, "{not json}") }
+	rec := serve(httptest.NewRequest(http.MethodGet, "/recipes?pretty=true", nil), text, PrettyJSON(false))
+	if body := rec.Body.String(); body != "{not json}" {
+		t.Errorf("body = %q, want it untouched", body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/recipes?pretty=true", nil)
+	req.Header.Set("Accept", NDJSON)
+	stream := func(c *gin.Context) {
+		c.Header("Content-Type", NDJSON)
+		c.String(http.StatusOK, "{\"name\":\"Pancakes\"}\n{\"name\":\"Waffles\"}\n")
+	}
+	if body := serve(req, stream, PrettyJSON(true)).Body.String(); body != "{\"name\":\"Pancakes\"}\n{\"name\":\"Waffles\"}\n" {
+		t.Errorf("NDJSON body = %q, want one object per line", body)
+	}
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate allows Limit requests per Per, refilled continuously
+type Rate struct {
+	Limit int
+	Per   time.Duration
+}
+
+// ParseRate reads a rate such as "30/1m", meaning 30 requests per minute
+func ParseRate(spec string) (Rate, error) {
+	parts := strings.SplitN(spec, "/", 2)
+	if len(parts) != 2 {
+		return Rate{}, fmt.Errorf("invalid rate %q, expected requests/duration such as 30/1m", spec)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || limit < 1 {
+		return Rate{}, fmt.Errorf("invalid rate %q, the number of requests must be a positive integer", spec)
+	}
+	per, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || per <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q, the duration must be positive such as 1m", spec)
+	}
+	return Rate{Limit: limit, Per: per}, nil
+}
+
+// Limiter is a token bucket per key
+type Limiter interface {
+	// Allow takes a token from the bucket of key, or reports how long until
+	// one is available
+	Allow(key string, rate Rate) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// RateLimit throttles the requests of each caller, identified by user when
+// authenticated and by IP otherwise, answering 429 with Retry-After once
+// their bucket is empty. Buckets are named after group, so each route group
+// has its own limit. Requests are let through when the limiter fails.
+func RateLimit(limiter Limiter, group string, rate Rate) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller := "ip:" + ClientIP(c)
+		if userID, ok := c.Value("userID").(interface{ Hex() string }); ok {
+			caller = "user:" + userID.Hex()
+		}
+
+		allowed, retryAfter, err := limiter.Allow("ratelimit:"+group+":"+caller, rate)
+		if err != nil {
+			log.Printf("Warning: rate limiter unavailable, letting the request through: %v", err)
+		} else if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// MemoryLimiter keeps the buckets in process. Each instance of the API then
+// has its own limits, prefer RedisLimiter when running several.
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket is back to its limit, after which it can be
+	// dropped since a new bucket starts full anyway
+	full time.Time
+}
+
+// sweepInterval is how often Allow drops the buckets that have refilled, so
+// that memory does not grow with every client ever seen
+const sweepInterval = time.Minute
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		swept:   time.Now(),
+	}
+}
+
+func (limiter *MemoryLimiter) Allow(key string, rate Rate) (bool, time.Duration, error) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(limiter.swept) >= sweepInterval {
+		limiter.sweep(now)
+	}
+
+	perToken := rate.Per / time.Duration(rate.Limit)
+	b, ok := limiter.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rate.Limit), updated: now}
+		limiter.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(rate.Limit), b.tokens+float64(now.Sub(b.updated))/float64(perToken))
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(time.Duration((float64(rate.Limit) - b.tokens) * float64(perToken)))
+	if allowed {
+		return true, 0, nil
+	}
+	return false, time.Duration((1 - b.tokens) * float64(perToken)), nil
+}
+
+// sweep drops the buckets that are full again
+func (limiter *MemoryLimiter) sweep(now time.Time) {
+	for key, b := range limiter.buckets {
+		if !now.Before(b.full) {
+			delete(limiter.buckets, key)
+		}
+	}
+	limiter.swept = now
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"github.com/go-redis/redis"
+	"time"
+)
+
+// tokenBucketScript refills and takes from the bucket atomically. It returns
+// whether a token was taken and, when not, the milliseconds until one is.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local perToken = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1]) or capacity
+local updated = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + (now - updated) / perToken)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) * perToken)
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(capacity * perToken))
+return {allowed, retry}
+`)
+
+// RedisLimiter keeps the buckets in Redis, shared by every instance of the API
+type RedisLimiter struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisLimiter prepends prefix to the keys of the buckets, as CACHE_PREFIX
+// does to the cache keys
+func NewRedisLimiter(client *redis.Client, prefix string) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (limiter *RedisLimiter) Allow(key string, rate Rate) (bool, time.Duration, error) {
+	perToken := float64(rate.Per/time.Millisecond) / float64(rate.Limit)
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	result, err := tokenBucketScript.Run(limiter.client, []string{limiter.prefix + key}, rate.Limit, perToken, now).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	values, _ := result.([]interface{})
+	if len(values) != 2 {
+		return false, 0, nil
+	}
+	allowed, _ := values[0].(int64)
+	retry, _ := values[1].(int64)
+	return allowed == 1, time.Duration(retry) * time.Millisecond, nil
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// failingLimiter is a Limiter whose store is down
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(key string, rate Rate) (bool, time.Duration, error) {
+	return false, 0, errors.New("connection refused")
+}
+
+func created(c *gin.Context) {
+	c.JSON(http.StatusCreated, gin.H{"name": "Pancakes"})
+}
+
+// post creates a recipe from remoteAddr through middlewares
+func post(remoteAddr string, middlewares ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/recipes", nil)
+	req.RemoteAddr = remoteAddr
+	return serve(req, created, middlewares...)
+}
+
+func TestRateLimitThrottlesWrites(t *testing.T) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("starting miniredis: %v", err)
+	}
+	defer server.Close()
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	limiters := map[string]Limiter{
+		"memory": NewMemoryLimiter(),
+		"redis":  NewRedisLimiter(client, "recipes-api:"),
+	}
+	for name, limiter := range limiters {
+		t.Run(name, func(t *testing.T) {
+			limit := RateLimit(limiter, "write", Rate{Limit: 3, Per: time.Minute})
+			for i := 0; i < 3; i++ {
+				if rec := post("192.0.2.1:1234", limit); rec.Code != http.StatusCreated {
+					t.Fatalf("request %d: status = %d, want 201", i+1, rec.Code)
+				}
+			}
+
+			rec := post("192.0.2.1:1234", limit)
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("fourth request: status = %d, want 429", rec.Code)
+			}
+			if retryAfter := rec.Header().Get("Retry-After"); retryAfter != "20" {
+				t.Errorf("Retry-After = %q, want 20 seconds for one of 3 tokens a minute", retryAfter)
+			}
+
+			if rec := post("192.0.2.2:1234", limit); rec.Code != http.StatusCreated {
+				t.Errorf("another client: status = %d, want 201", rec.Code)
+			}
+			other := RateLimit(limiter, "search", Rate{Limit: 3, Per: time.Minute})
+			if rec := post("192.0.2.1:1234", other); rec.Code != http.StatusCreated {
+				t.Errorf("another group: status = %d, want 201", rec.Code)
+			}
+		})
+	}
+	if !server.Exists("recipes-api:ratelimit:write:ip:192.0.2.1") {
+		t.Errorf("keys = %v, want the bucket under the prefix", server.Keys())
+	}
+}
+
+func TestRateLimitKeysAuthenticatedUsersByID(t *testing.T) {
+	limiter := NewMemoryLimiter()
+	limit := RateLimit(limiter, "write", Rate{Limit: 1, Per: time.Minute})
+	as := func(id primitive.ObjectID) gin.HandlerFunc {
+		return func(c *gin.Context) { c.Set("userID", id) }
+	}
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+
+	if rec := post("192.0.2.1:1234", as(alice), limit); rec.Code != http.StatusCreated {
+		t.Fatalf("alice: status = %d, want 201", rec.Code)
+	}
+	if rec := post("192.0.2.2:1234", as(alice), limit); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("alice from another IP: status = %d, want 429", rec.Code)
+	}
+	if rec := post("192.0.2.1:1234", as(bob), limit); rec.Code != http.StatusCreated {
+		t.Errorf("bob from the same IP: status = %d, want 201", rec.Code)
+	}
+}
+
+func TestRateLimitLetsRequestsThroughWhenTheLimiterFails(t *testing.T) {
+	limit := RateLimit(failingLimiter{}, "write", Rate{Limit: 1, Per: time.Minute})
+	for i := 0; i < 3; i++ {
+		if rec := post("192.0.2.1:1234", limit); rec.Code != http.StatusCreated {
+			t.Fatalf("request %d: status = %d, want 201", i+1, rec.Code)
+		}
+	}
+}
+
+func TestMemoryLimiterSweepsRefilledBuckets(t *testing.T) {
+	limiter := NewMemoryLimiter()
+	rate := Rate{Limit: 2, Per: time.Minute}
+	limiter.Allow("idle", rate)
+	limiter.Allow("busy", rate)
+	limiter.Allow("busy", rate)
+
+	// Pretend the idle bucket refilled and a sweep is due
+	limiter.buckets["idle"].full = time.Now().Add(-time.Second)
+	limiter.swept = time.Now().Add(-sweepInterval)
+	limiter.Allow("new", rate)
+
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Error("the refilled bucket was kept")
+	}
+	if _, ok := limiter.buckets["busy"]; !ok {
+		t.Error("a bucket still refilling was dropped")
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	rate, err := ParseRate(" 30 / 1m ")
+	if err != nil || rate != (Rate{Limit: 30, Per: time.Minute}) {
+		t.Errorf("ParseRate = %+v, %v, want 30 per minute", rate, err)
+	}
+	for _, spec := range []string{"30", "0/1m", "abc/1m", "30/0s", "30/soon"} {
+		if _, err := ParseRate(spec); err == nil {
+			t.Errorf("ParseRate(%q) accepted an invalid rate", spec)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"log"
+	"time"
+)
+
+// SlowRequests logs a warning for every request taking longer than budget to
+// serve, with its route. The route is also attached to the request context
+// so a store.TimedStore can name it in its slow query warnings.
+func SlowRequests(budget time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "-"
+		}
+		c.Request = c.Request.WithContext(store.WithRoute(c.Request.Context(), route))
+
+		start := time.Now()
+		c.Next()
+		if elapsed := time.Since(start); elapsed > budget {
+			log.Printf("Warning: slow request method=%s route=%s status=%d duration=%s budget=%s",
+				c.Request.Method, route, c.Writer.Status(), elapsed, budget)
+		}
+	}
+}
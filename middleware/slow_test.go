@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"github.com/gabrielsscti/Recipes-API/store"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowStore is an in-memory store taking delay to look recipes up
+type slowStore struct {
+	*store.MemoryStore
+	delay time.Duration
+}
+
+func (slow slowStore) GetByID(ctx context.Context, id primitive.ObjectID) (models.Recipe, error) {
+	time.Sleep(slow.delay)
+	return slow.MemoryStore.GetByID(ctx, id)
+}
+
+// captureLog returns the buffer the standard logger writes to until the test ends
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func serveSlowStore(delay, threshold, budget time.Duration) *httptest.ResponseRecorder {
+	recipes := store.NewTimedStore(slowStore{MemoryStore: store.NewMemoryStore(), delay: delay}, threshold)
+	router := gin.New()
+	router.Use(SlowRequests(budget))
+	router.GET("/recipes/:id", func(c *gin.Context) {
+		recipes.GetByID(c.Request.Context(), primitive.NewObjectID())
+		c.JSON(http.StatusOK, gin.H{})
+	})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/recipes/1", nil))
+	return rec
+}
+
+func TestSlowQueriesAreLogged(t *testing.T) {
+	logged := captureLog(t)
+	serveSlowStore(30*time.Millisecond, 10*time.Millisecond, time.Minute)
+
+	output := logged.String()
+	if !strings.Contains(output, "slow query route=/recipes/:id op=GetByID") {
+		t.Errorf("log = %q, want a slow query warning naming the route and call", output)
+	}
+	if strings.Contains(output, "slow request") {
+		t.Errorf("log = %q, want no slow request warning within the budget", output)
+	}
+}
+
+func TestSlowRequestsAreLogged(t *testing.T) {
+	logged := captureLog(t)
+	serveSlowStore(30*time.Millisecond, time.Minute, 10*time.Millisecond)
+
+	output := logged.String()
+	if !strings.Contains(output, "slow request method=GET route=/recipes/:id status=200") {
+		t.Errorf("log = %q, want a slow request warning", output)
+	}
+	if strings.Contains(output, "slow query") {
+		t.Errorf("log = %q, want no slow query warning within the threshold", output)
+	}
+}
+
+func TestFastQueriesAreNotLogged(t *testing.T) {
+	logged := captureLog(t)
+	serveSlowStore(0, time.Minute, time.Minute)
+	if output := logged.String(); output != "" {
+		t.Errorf("log = %q, want nothing", output)
+	}
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeout cancels the request context after timeout and answers 504 Gateway
+// Timeout if the handlers have not finished by then. Handlers should pass
+// c.Request.Context() to the database so their queries are cancelled too.
+//
+// Requests accepting NDJSON are streamed instead of buffered, so they cannot
+// be turned into a 504. Their context is cancelled after streamTimeout, which
+// ends the stream early.
+func Timeout(timeout, streamTimeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if AcceptsNDJSON(c.GetHeader("Accept")) {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), streamTimeout)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		writer := &timeoutWriter{ResponseWriter: c.Writer, header: make(http.Header)}
+		c.Writer = writer
+
+		done := make(chan struct{})
+		var panicked interface{}
+		go func() {
+			defer close(done)
+			// Hand panics back to this goroutine so gin's recovery sees them
+			defer func() { panicked = recover() }()
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+			if panicked != nil {
+				panic(panicked)
+			}
+			writer.flush()
+		case <-ctx.Done():
+			writer.timeout()
+			// The gin context is recycled once this middleware returns, so
+			// wait for the handlers, which should return quickly now that
+			// their context is cancelled
+			<-done
+		}
+		// Middlewares before this one, such as the logger, read the status
+		// of what was actually sent
+		c.Writer = writer.ResponseWriter
+	}
+}
+
+// timeoutWriter buffers the response so that it can be replaced by a 504
+// when the handlers take too long
+type timeoutWriter struct {
+	gin.ResponseWriter
+	mu sync.Mutex
+	// header belongs to the handlers, which may still be setting headers when
+	// the request times out. It is only read once they are done.
+	header   http.Header
+	buffer   bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (writer *timeoutWriter) Header() http.Header {
+	return writer.header
+}
+
+func (writer *timeoutWriter) WriteHeader(status int) {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	if writer.status == 0 {
+		writer.status = status
+	}
+}
+
+func (writer *timeoutWriter) WriteHeaderNow() {}
+
+// Flush does nothing, the response is only sent once the handlers are done
+func (writer *timeoutWriter) Flush() {}
+
+func (writer *timeoutWriter) Write(data []byte) (int, error) {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	if writer.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if writer.status == 0 {
+		writer.status = http.StatusOK
+	}
+	return writer.buffer.Write(data)
+}
+
+func (writer *timeoutWriter) WriteString(s string) (int, error) {
+	return writer.Write([]byte(s))
+}
+
+func (writer *timeoutWriter) Status() int {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	if writer.status == 0 {
+		return http.StatusOK
+	}
+	return writer.status
+}
+
+func (writer *timeoutWriter) Size() int {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	return writer.buffer.Len()
+}
+
+func (writer *timeoutWriter) Written() bool {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	return writer.status != 0
+}
+
+// flush sends the buffered response once the handlers are done
+func (writer *timeoutWriter) flush() {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	header := writer.ResponseWriter.Header()
+	for key, values := range writer.header {
+		header[key] = values
+	}
+	if writer.status != 0 {
+		writer.ResponseWriter.WriteHeader(writer.status)
+	}
+	writer.ResponseWriter.Write(writer.buffer.Bytes())
+}
+
+// timeout replaces whatever the handlers wrote with a 504. It never reads
+// the headers of the handlers, which are still running.
+func (writer *timeoutWriter) timeout() {
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+
+	writer.timedOut = true
+	header := writer.ResponseWriter.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	writer.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	writer.ResponseWriter.Write([]byte(`{"error":"Request timed out"}`))
+	writer.ResponseWriter.Flush()
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeoutRepliesGatewayTimeout(t *testing.T) {
+	slow := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(time.Second):
+		}
+		// A handler that ignores the cancellation still tries to answer
+		c.Header("X-Late", "yes")
+		c.JSON(http.StatusOK, gin.H{"name": "Pancakes"})
+	}
+	var logged int
+	logger := func(c *gin.Context) {
+		c.Next()
+		logged = c.Writer.Status()
+	}
+	rec := serve(httptest.NewRequest(http.MethodGet, "/recipes", nil), slow, logger, Timeout(20*time.Millisecond, time.Minute))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", rec.Code)
+	}
+	if logged != http.StatusGatewayTimeout {
+		t.Errorf("status seen by an outer middleware = %d, want 504", logged)
+	}
+	if body := rec.Body.String(); body != `{"error":"Request timed out"}` {
+		t.Errorf("body = %s, want the timeout error alone", body)
+	}
+	if rec.Header().Get("X-Late") != "" {
+		t.Error("a header set after the timeout was sent")
+	}
+}
+
+func TestTimeoutPassesFastResponsesThrough(t *testing.T) {
+	fast := func(c *gin.Context) {
+		c.Header("X-Total-Count", "1")
+		c.JSON(http.StatusCreated, gin.H{"name": "Pancakes"})
+	}
+	rec := serve(httptest.NewRequest(http.MethodPost, "/recipes", nil), fast, Timeout(time.Second, time.Minute))
+
+	if rec.Code != http.StatusCreated || rec.Header().Get("X-Total-Count") != "1" {
+		t.Fatalf("status, headers = %d, %v", rec.Code, rec.Header())
+	}
+	if body := rec.Body.String(); body != `{"name":"Pancakes"}` {
+		t.Errorf("body = %s", body)
+	}
+}
+
+func TestTimeoutGivesStreamsTheirOwnDeadline(t *testing.T) {
+	stream := func(c *gin.Context) {
+		time.Sleep(40 * time.Millisecond)
+		if err := c.Request.Context().Err(); err != nil {
+			t.Errorf("the stream context ended early: %v", err)
+		}
+		c.String(http.StatusOK, "{}\n")
+	}
+	req := httptest.NewRequest(http.MethodGet, "/recipes", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	rec := serve(req, stream, Timeout(10*time.Millisecond, time.Minute))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", rec.Code)
+	}
+}
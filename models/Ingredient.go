@@ -0,0 +1,82 @@
+package models
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Ingredient is the structured form of an ingredient line such as "1/2 tsp salt"
+type Ingredient struct {
+	Name     string    `json:"name"`
+	Quantity *Quantity `json:"quantity,omitempty"`
+	Unit     string    `json:"unit,omitempty"`
+}
+
+// units maps the spellings found in ingredient lines to a canonical unit
+var units = map[string]string{
+	"tsp": "tsp", "teaspoon": "tsp", "teaspoons": "tsp",
+	"tbsp": "tbsp", "tablespoon": "tbsp", "tablespoons": "tbsp",
+	"cup": "cup", "cups": "cup",
+	"g": "g", "gram": "g", "grams": "g",
+	"kg": "kg", "kilogram": "kg", "kilograms": "kg",
+	"ml": "ml", "milliliter": "ml", "milliliters": "ml",
+	"l": "l", "liter": "l", "liters": "l",
+	"oz": "oz", "ounce": "oz", "ounces": "oz",
+	"lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+	"pinch": "pinch", "pinches": "pinch",
+	"clove": "clove", "cloves": "clove",
+	"can": "can", "cans": "can",
+}
+
+// ParseIngredient splits an ingredient line into quantity, unit and name.
+// Lines it cannot make sense of are kept whole as the name, without a quantity.
+func ParseIngredient(line string) Ingredient {
+	fields := strings.Fields(line)
+	var quantity *Quantity
+	i := 0
+	for ; i < len(fields); i++ {
+		value, ok := ParseQuantity(fields[i])
+		if !ok {
+			break
+		}
+		quantity = quantity.Plus(value.Rat())
+	}
+
+	ingredient := Ingredient{}
+	if quantity != nil && quantity.Rat().Sign() > 0 {
+		ingredient.Quantity = quantity
+		if i < len(fields) {
+			if unit, ok := units[strings.ToLower(strings.TrimSuffix(fields[i], "."))]; ok {
+				ingredient.Unit = unit
+				i++
+			}
+		}
+	}
+	ingredient.Name = strings.ToLower(strings.Join(fields[i:], " "))
+	return ingredient
+}
+
+// Scaled returns the quantity multiplied by factor, or nil without a quantity
+func (ingredient Ingredient) Scaled(factor *big.Rat) *big.Rat {
+	if ingredient.Quantity == nil {
+		return nil
+	}
+	return new(big.Rat).Mul(ingredient.Quantity.Rat(), factor)
+}
+
+// ShoppingListRequest lists the recipes to shop for, optionally scaled to a
+// number of servings. A list combines at most 50 recipes.
+type ShoppingListRequest struct {
+	Recipes []ShoppingListRecipe `json:"recipes" binding:"required,min=1,max=50"`
+}
+
+type ShoppingListRecipe struct {
+	ID       string `json:"id" binding:"required"`
+	Servings int    `json:"servings"`
+}
+
+// ShoppingList is the combined list of ingredients of several recipes
+type ShoppingList struct {
+	Items   []Ingredient `json:"items"`
+	Missing []string     `json:"missing"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// Step is an instruction with its position in the recipe
+type Step struct {
+	Number int    `json:"number"`
+	Text   string `json:"text"`
+}
+
+// Instructions are the steps of a recipe, in order. They are sent and stored
+// as a list of strings, but can also be submitted as a list of numbered steps.
+type Instructions []string
+
+// UnmarshalJSON accepts either a list of strings, numbered in the order
+// given, or a list of steps, ordered by their numbers
+func (instructions *Instructions) UnmarshalJSON(data []byte) error {
+	var texts []string
+	if err := json.Unmarshal(data, &texts); err == nil {
+		*instructions = texts
+		return nil
+	}
+
+	var steps []Step
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&steps); err != nil {
+		return errors.New("instructions must be a list of strings or of {number, text} steps")
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].Number < steps[j].Number
+	})
+	texts = make([]string, len(steps))
+	for i, step := range steps {
+		if step.Number < 1 {
+			return errors.New("step numbers must be positive")
+		}
+		if i > 0 && steps[i-1].Number == step.Number {
+			return errors.New("step numbers must be unique")
+		}
+		texts[i] = step.Text
+	}
+	*instructions = texts
+	return nil
+}
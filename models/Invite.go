@@ -0,0 +1,27 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Invite is a single-use code required to sign up when SIGNUP_MODE is invite
+type Invite struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	Code      string             `json:"code" bson:"code"`
+	CreatedBy string             `json:"createdBy" bson:"createdBy"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	// The invite cannot be used after ExpiresAt, when set
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
+	// Username of the user who signed up with the invite
+	UsedBy string     `json:"usedBy,omitempty" bson:"usedBy,omitempty"`
+	UsedAt *time.Time `json:"usedAt,omitempty" bson:"usedAt,omitempty"`
+}
+
+// InviteRequest is the payload used to generate invites
+type InviteRequest struct {
+	// Number of invites to generate, 1 by default
+	Count int `json:"count"`
+	// How long the invites can be used, such as 72h. They never expire by default.
+	ExpiresIn string `json:"expiresIn"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// MealPlanDateLayout is the format of the dates in a meal plan, YYYY-MM-DD
+const MealPlanDateLayout = "2006-01-02"
+
+// MealPlan schedules recipes on dates for a user. A plan holds at most 100
+// meals, enough for a month of three meals a day.
+//
+// swagger:model mealPlan
+type MealPlan struct {
+	//swagger:ignore
+	ID primitive.ObjectID `json:"id" bson:"_id"`
+	//swagger:ignore
+	UserID primitive.ObjectID `json:"userId" bson:"userId"`
+	Name   string             `json:"name" bson:"name"`
+	// required: true
+	Meals []PlannedMeal `json:"meals" bson:"meals" binding:"required,max=100"`
+	//swagger:ignore
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// PlannedMeal is a recipe scheduled on a date
+type PlannedMeal struct {
+	// Date of the meal, formatted YYYY-MM-DD
+	Date string `json:"date" bson:"date"`
+	// Optional slot of the day, such as breakfast, lunch or dinner
+	Slot     string             `json:"slot,omitempty" bson:"slot,omitempty"`
+	RecipeID primitive.ObjectID `json:"recipeId" bson:"recipeId"`
+}
+
+// MealPlanWeek lists the meals planned over a week, across all of the user's plans
+type MealPlanWeek struct {
+	Start string        `json:"start"`
+	End   string        `json:"end"`
+	Meals []PlannedMeal `json:"meals"`
+}
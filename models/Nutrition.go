@@ -0,0 +1,43 @@
+package models
+
+// NutritionFacts are the calories (kcal) and macronutrients (grams) of a food
+type NutritionFacts struct {
+	Calories float64 `json:"calories" bson:"calories"`
+	Protein  float64 `json:"protein" bson:"protein"`
+	Carbs    float64 `json:"carbs" bson:"carbs"`
+	Fat      float64 `json:"fat" bson:"fat"`
+}
+
+// IngredientNutrition holds the nutrition facts of an ingredient, in the
+// quantity used by the recipe
+type IngredientNutrition struct {
+	Ingredient     string `json:"ingredient" bson:"ingredient"`
+	NutritionFacts `bson:",inline"`
+}
+
+// NutritionSummary is the nutrition of a whole recipe and of one serving
+type NutritionSummary struct {
+	Servings   int            `json:"servings"`
+	Total      NutritionFacts `json:"total"`
+	PerServing NutritionFacts `json:"perServing"`
+}
+
+// Add returns the sum of both facts
+func (facts NutritionFacts) Add(other NutritionFacts) NutritionFacts {
+	return NutritionFacts{
+		Calories: facts.Calories + other.Calories,
+		Protein:  facts.Protein + other.Protein,
+		Carbs:    facts.Carbs + other.Carbs,
+		Fat:      facts.Fat + other.Fat,
+	}
+}
+
+// Divide returns the facts split into n equal parts
+func (facts NutritionFacts) Divide(n int) NutritionFacts {
+	return NutritionFacts{
+		Calories: facts.Calories / float64(n),
+		Protein:  facts.Protein / float64(n),
+		Carbs:    facts.Carbs / float64(n),
+		Fat:      facts.Fat / float64(n),
+	}
+}
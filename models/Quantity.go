@@ -0,0 +1,95 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+)
+
+// Formats of quantities in JSON, chosen with QuantityFormat
+const (
+	// QuantityNumber writes quantities as JSON numbers, such as 0.5
+	QuantityNumber = "number"
+	// QuantityFraction writes quantities as exact fractions in strings, such as "1/3"
+	QuantityFraction = "fraction"
+)
+
+// QuantityFormat is how quantities are written in JSON. Numbers are the
+// easiest to consume, fractions the only exact format for thirds.
+var QuantityFormat = QuantityNumber
+
+// Quantity is an exact amount of an ingredient. It is kept as a fraction so
+// that parsing "1/3" and scaling it by 3 gives exactly 1, without the drift
+// of floating point.
+type Quantity big.Rat
+
+// NewQuantity returns a quantity holding a copy of value
+func NewQuantity(value *big.Rat) *Quantity {
+	return (*Quantity)(new(big.Rat).Set(value))
+}
+
+// quantityPattern leaves out the exponents and base prefixes that
+// big.Rat.SetString accepts too, such as "1e3" or "0x10"
+var quantityPattern = regexp.MustCompile(`^\d+(\.\d+)?(/\d+)?$`)
+
+// ParseQuantity parses "2", "1.5" or "1/2". Negative amounts and amounts out
+// of the range of a float64 are rejected.
+func ParseQuantity(text string) (*Quantity, bool) {
+	if !quantityPattern.MatchString(text) {
+		return nil, false
+	}
+	value, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, false
+	}
+	if float, _ := value.Float64(); math.IsInf(float, 0) {
+		return nil, false
+	}
+	return (*Quantity)(value), true
+}
+
+// Rat returns the quantity as a big.Rat, which must not be modified
+func (q *Quantity) Rat() *big.Rat {
+	return (*big.Rat)(q)
+}
+
+// Plus returns the sum of q and amount, q being nil for nothing yet
+func (q *Quantity) Plus(amount *big.Rat) *Quantity {
+	sum := new(big.Rat).Set(amount)
+	if q != nil {
+		sum.Add(sum, q.Rat())
+	}
+	return (*Quantity)(sum)
+}
+
+func (q *Quantity) String() string {
+	return q.Rat().RatString()
+}
+
+// MarshalJSON writes the quantity in QuantityFormat, falling back to a
+// fraction for the quantities too large for a JSON number
+func (q *Quantity) MarshalJSON() ([]byte, error) {
+	value, _ := q.Rat().Float64()
+	if QuantityFormat == QuantityFraction || math.IsInf(value, 0) {
+		return json.Marshal(q.String())
+	}
+	return []byte(strconv.FormatFloat(value, 'f', -1, 64)), nil
+}
+
+// UnmarshalJSON accepts both formats, numbers and fractions in strings
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	var quoted string
+	if err := json.Unmarshal(data, &quoted); err == nil {
+		text = quoted
+	}
+	parsed, ok := ParseQuantity(text)
+	if !ok {
+		return fmt.Errorf("invalid quantity %s", data)
+	}
+	q.Rat().Set(parsed.Rat())
+	return nil
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestTriplingAThirdIsExactlyOne(t *testing.T) {
+	ingredient := ParseIngredient("1/3 cup sugar")
+	tripled := ingredient.Scaled(big.NewRat(3, 1))
+	if tripled.Cmp(big.NewRat(1, 1)) != 0 {
+		t.Fatalf("3 × 1/3 = %s, want exactly 1", tripled.RatString())
+	}
+
+	data, _ := json.Marshal(NewQuantity(tripled))
+	if string(data) != "1" {
+		t.Errorf("the tripled quantity is written %s, want 1", data)
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := map[string]string{
+		"2":    "2",
+		"1.5":  "3/2",
+		"1/2":  "1/2",
+		"0.25": "1/4",
+	}
+	for text, want := range tests {
+		if q, ok := ParseQuantity(text); !ok || q.String() != want {
+			t.Errorf("ParseQuantity(%q) = %v, %v, want %s", text, q, ok, want)
+		}
+	}
+	for _, text := range []string{"-1", "one", "1/0", "", "0x10", "1e3", "1e400", "1" + strings.Repeat("0", 400)} {
+		if _, ok := ParseQuantity(text); ok {
+			t.Errorf("ParseQuantity(%q) was accepted", text)
+		}
+	}
+}
+
+func TestQuantityJSON(t *testing.T) {
+	defer func(format string) { QuantityFormat = format }(QuantityFormat)
+	third, _ := ParseQuantity("1/3")
+
+	QuantityFormat = QuantityFraction
+	data, _ := json.Marshal(third)
+	if string(data) != `"1/3"` {
+		t.Errorf("as a fraction, 1/3 is written %s", data)
+	}
+	var decoded Quantity
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Rat().Cmp(big.NewRat(1, 3)) != 0 {
+		t.Errorf("reading %s back gives %s, %v", data, decoded.String(), err)
+	}
+
+	QuantityFormat = QuantityNumber
+	data, _ = json.Marshal(third)
+	if string(data) != "0.3333333333333333" {
+		t.Errorf("as a number, 1/3 is written %s", data)
+	}
+	if err := json.Unmarshal([]byte("0.5"), &decoded); err != nil || decoded.String() != "1/2" {
+		t.Errorf("reading 0.5 gives %s, %v", decoded.String(), err)
+	}
+	if err := json.Unmarshal([]byte(`"a lot"`), &decoded); err == nil {
+		t.Error("an invalid quantity was accepted")
+	}
+}
+
+func TestIngredientsWithExponentsOrBasesHaveNoQuantity(t *testing.T) {
+	for _, line := range []string{"0x10 g sugar", "1e400 g flour"} {
+		ingredient := ParseIngredient(line)
+		if ingredient.Quantity != nil || ingredient.Name != line {
+			t.Errorf("ParseIngredient(%q) = %s %s %q, want the whole line as the name", line, ingredient.Quantity, ingredient.Unit, ingredient.Name)
+		}
+	}
+}
+
+func TestHugeQuantitiesAreWrittenAsFractions(t *testing.T) {
+	huge, _ := new(big.Rat).SetString("1" + strings.Repeat("0", 400))
+	data, err := json.Marshal(NewQuantity(huge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"1` + strings.Repeat("0", 400) + `"`; string(data) != want {
+		t.Errorf("a quantity out of the float64 range is written %s", data)
+	}
+	if !json.Valid(data) {
+		t.Errorf("%s is not valid JSON", data)
+	}
+}
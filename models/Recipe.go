@@ -2,6 +2,7 @@ package models
 
 import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,235 @@ type Recipe struct {
 	Name         string             `json:"name" bson:"name"`
 	Tags         []string           `json:"tags" bson:"tags"`
 	Ingredients  []string           `json:"ingredients" bson:"ingredients"`
-	Instructions []string           `json:"instructions" bson:"instructions"`
+	Instructions Instructions       `json:"instructions" bson:"instructions"`
 	PublishedAt  time.Time          `json:"publishedAt" bson:"publishedAt"`
+	//swagger:ignore
+	UserID primitive.ObjectID `json:"userId" bson:"userId"`
+	// Either draft (the default) or published. Drafts are only visible to their owner
+	Status string `json:"status" bson:"status,omitempty"`
+	// Number of servings the recipe makes
+	Servings int `json:"servings,omitempty" bson:"servings,omitempty"`
+	// Nutrition facts of the ingredients, summed by the nutrition endpoint
+	Nutrition []IngredientNutrition `json:"nutrition,omitempty" bson:"nutrition,omitempty"`
+	// Optional URL of the website the recipe was adapted from
+	SourceURL string `json:"sourceUrl,omitempty" bson:"sourceUrl,omitempty"`
+	// Optional name of the website or book the recipe was adapted from
+	SourceName string `json:"sourceName,omitempty" bson:"sourceName,omitempty"`
+	// What the recipe makes, such as "12 cookies", next to the number of servings
+	Yield string `json:"yield,omitempty" bson:"yield,omitempty"`
+	//swagger:ignore
+	Slug string `json:"slug,omitempty" bson:"slug,omitempty"`
+	// Number of times the recipe was viewed, updated every minute or so
+	//swagger:ignore
+	Views int64 `json:"views,omitempty" bson:"views,omitempty"`
+	// Content in other languages by locale, such as pt or pt-br
+	Translations map[string]RecipeTranslation `json:"translations,omitempty" bson:"translations,omitempty"`
+	// Custom key/value pairs, such as cuisine or equipment
+	Metadata map[string]string `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	// Either public, the default, or private to hide the recipe from everyone but its owner
+	Visibility string `json:"visibility,omitempty" bson:"visibility,omitempty"`
+	// Either easy, medium or hard, empty when not given
+	Difficulty string `json:"difficulty,omitempty" bson:"difficulty,omitempty"`
+	// Minutes the recipe takes from start to finish, 0 when not given
+	TotalTime int `json:"totalTime,omitempty" bson:"totalTime,omitempty"`
+}
+
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+)
+
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// IsDraft reports whether the recipe is hidden from everyone but its owner.
+// Recipes created before statuses existed have none and are public.
+func (recipe Recipe) IsDraft() bool {
+	return recipe.Status == StatusDraft
+}
+
+// IsPrivate reports whether the recipe is only visible to its owner. Unlike
+// drafts, private recipes are hidden from admins too.
+func (recipe Recipe) IsPrivate() bool {
+	return recipe.Visibility == VisibilityPrivate
+}
+
+// IsPublic reports whether everyone may see the recipe, which is neither a
+// draft nor private
+func (recipe Recipe) IsPublic() bool {
+	return !recipe.IsDraft() && !recipe.IsPrivate()
+}
+
+// RecipePatch holds the fields of a partial recipe update. Nil fields were
+// omitted from the request and are left untouched.
+type RecipePatch struct {
+	Name         *string                       `json:"name"`
+	Tags         *[]string                     `json:"tags"`
+	Ingredients  *[]string                     `json:"ingredients"`
+	Instructions *Instructions                 `json:"instructions"`
+	Status       *string                       `json:"status"`
+	Servings     *int                          `json:"servings"`
+	Nutrition    *[]IngredientNutrition        `json:"nutrition"`
+	SourceURL    *string                       `json:"sourceUrl"`
+	SourceName   *string                       `json:"sourceName"`
+	Yield        *string                       `json:"yield"`
+	Translations *map[string]RecipeTranslation `json:"translations"`
+	Metadata     *map[string]string            `json:"metadata"`
+	Visibility   *string                       `json:"visibility"`
+	Difficulty   *string                       `json:"difficulty"`
+	TotalTime    *int                          `json:"totalTime"`
+	//swagger:ignore
+	PublishedAt *time.Time `json:"-"`
+	//swagger:ignore
+	Slug *string `json:"-"`
+}
+
+// IsEmpty reports whether the patch changes nothing
+func (patch RecipePatch) IsEmpty() bool {
+	return patch.Name == nil && patch.Tags == nil && patch.Ingredients == nil &&
+		patch.Instructions == nil && patch.Status == nil && patch.Servings == nil &&
+		patch.Nutrition == nil && patch.SourceURL == nil && patch.SourceName == nil &&
+		patch.Yield == nil && patch.Translations == nil && patch.Metadata == nil &&
+		patch.Visibility == nil && patch.Difficulty == nil && patch.TotalTime == nil &&
+		patch.PublishedAt == nil && patch.Slug == nil
+}
+
+// Apply sets the fields present in the patch on recipe
+func (patch RecipePatch) Apply(recipe *Recipe) {
+	if patch.Name != nil {
+		recipe.Name = *patch.Name
+	}
+	if patch.Tags != nil {
+		recipe.Tags = *patch.Tags
+	}
+	if patch.Ingredients != nil {
+		recipe.Ingredients = *patch.Ingredients
+	}
+	if patch.Instructions != nil {
+		recipe.Instructions = *patch.Instructions
+	}
+	if patch.Status != nil {
+		recipe.Status = *patch.Status
+	}
+	if patch.Servings != nil {
+		recipe.Servings = *patch.Servings
+	}
+	if patch.Nutrition != nil {
+		recipe.Nutrition = *patch.Nutrition
+	}
+	if patch.SourceURL != nil {
+		recipe.SourceURL = *patch.SourceURL
+	}
+	if patch.SourceName != nil {
+		recipe.SourceName = *patch.SourceName
+	}
+	if patch.Yield != nil {
+		recipe.Yield = *patch.Yield
+	}
+	if patch.Translations != nil {
+		recipe.Translations = *patch.Translations
+	}
+	if patch.Metadata != nil {
+		recipe.Metadata = *patch.Metadata
+	}
+	if patch.Visibility != nil {
+		recipe.Visibility = *patch.Visibility
+	}
+	if patch.Difficulty != nil {
+		recipe.Difficulty = *patch.Difficulty
+	}
+	if patch.TotalTime != nil {
+		recipe.TotalTime = *patch.TotalTime
+	}
+	if patch.PublishedAt != nil {
+		recipe.PublishedAt = *patch.PublishedAt
+	}
+	if patch.Slug != nil {
+		recipe.Slug = *patch.Slug
+	}
+}
+
+// TrendingRecipe is a recipe with its number of views in the trending window
+type TrendingRecipe struct {
+	Recipe Recipe `json:"recipe"`
+	Views  int64  `json:"views"`
+}
+
+// SimilarRecipe is a recipe with the Jaccard similarity of its ingredients
+// to those of another recipe, from 0 to 1
+type SimilarRecipe struct {
+	Recipe     Recipe  `json:"recipe"`
+	Similarity float64 `json:"similarity"`
+}
+
+// BulkDeleteRequest lists the recipes to delete in a single request
+type BulkDeleteRequest struct {
+	IDs []string `json:"ids" binding:"required"`
+}
+
+// NormalizeName lowercases name and collapses its spacing, so that names
+// differing only in case or spacing compare equal
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// TagCount is the number of recipes using a tag
+type TagCount struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Count int    `json:"count" bson:"count"`
+}
+
+// RecipeStats summarizes the published recipes, for dashboards
+type RecipeStats struct {
+	Total int `json:"total"`
+	// Tags are the most used tags, most used first
+	Tags               []TagCount   `json:"tags"`
+	AverageIngredients float64      `json:"averageIngredients"`
+	PerMonth           []MonthCount `json:"perMonth"`
+}
+
+// MonthCount is the number of recipes published in a month, such as 2022-03
+type MonthCount struct {
+	Month string `json:"month" bson:"_id"`
+	Count int    `json:"count" bson:"count"`
+}
+
+// VisibilityRequest sets the visibility of a recipe
+type VisibilityRequest struct {
+	Visibility string `json:"visibility" binding:"required"`
+}
+
+// ShareRequest asks for a link to share a recipe
+type ShareRequest struct {
+	// How long the link works, such as 48h. SHARE_TTL by default.
+	ExpiresIn string `json:"expiresIn"`
+}
+
+// ShareLink is a signed link giving access to a recipe until it expires
+type ShareLink struct {
+	URL       string    `json:"url"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// TagRename asks to replace the tag From with To in every recipe
+type TagRename struct {
+	From string `json:"from" binding:"required"`
+	To   string `json:"to" binding:"required"`
+}
+
+// RecipeTranslation is the content of a recipe in another language. Fields
+// left empty fall back to those of the original recipe.
+type RecipeTranslation struct {
+	Name         string       `json:"name,omitempty" bson:"name,omitempty"`
+	Ingredients  []string     `json:"ingredients,omitempty" bson:"ingredients,omitempty"`
+	Instructions Instructions `json:"instructions,omitempty" bson:"instructions,omitempty"`
 }
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxSlugLength keeps slugs short enough for URLs, the counter added to make
+// them unique is not included
+const maxSlugLength = 80
+
+// slugLetters spells the letters that do not decompose into an ASCII letter
+// and an accent
+var slugLetters = strings.NewReplacer("æ", "ae", "œ", "oe", "ø", "o", "ß", "ss", "ł", "l", "đ", "d", "ð", "d", "þ", "th")
+
+// Slugify turns a recipe name into a URL-safe slug, such as "pao-de-queijo"
+// for "Pão de Queijo". Accents are dropped, and every run of characters other
+// than ASCII letters and digits becomes a single dash. Names without any such
+// character give "recipe".
+func Slugify(name string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range norm.NFD.String(slugLetters.Replace(strings.ToLower(name))) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			dash = false
+			slug.WriteRune(r)
+		default:
+			dash = true
+		}
+		if slug.Len() >= maxSlugLength {
+			break
+		}
+	}
+
+	result := strings.TrimRight(slug.String(), "-")
+	if result == "" {
+		return "recipe"
+	}
+	return result
+}
+
+// UniqueSlug returns base, or base followed by the lowest counter starting at
+// 2 such as "pancakes-2", whichever is not in taken
+func UniqueSlug(base string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, slug := range taken {
+		used[slug] = true
+	}
+	if !used[base] {
+		return base
+	}
+	for counter := 2; ; counter++ {
+		candidate := base + "-" + strconv.Itoa(counter)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
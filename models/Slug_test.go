@@ -0,0 +1,45 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Pão de Queijo":           "pao-de-queijo",
+		"  Crème Brûlée!  ":       "creme-brulee",
+		"Mom's 3-Bean Chili":      "mom-s-3-bean-chili",
+		"Smørrebrød & Æbleskiver": "smorrebrod-aebleskiver",
+		"Straße--Pretzel":         "strasse-pretzel",
+		"烤鸭":                      "recipe",
+		"":                        "recipe",
+	}
+	for name, want := range tests {
+		if slug := Slugify(name); slug != want {
+			t.Errorf("Slugify(%q) = %q, want %q", name, slug, want)
+		}
+	}
+
+	long := Slugify(strings.Repeat("ab ", 100))
+	if len(long) > maxSlugLength || strings.HasSuffix(long, "-") {
+		t.Errorf("Slugify of a long name = %q (%d characters), want at most %d without a trailing dash", long, len(long), maxSlugLength)
+	}
+}
+
+func TestUniqueSlug(t *testing.T) {
+	tests := []struct {
+		taken []string
+		want  string
+	}{
+		{nil, "pancakes"},
+		{[]string{"pancakes-2"}, "pancakes"},
+		{[]string{"pancakes"}, "pancakes-2"},
+		{[]string{"pancakes", "pancakes-2", "pancakes-4"}, "pancakes-3"},
+	}
+	for _, test := range tests {
+		if slug := UniqueSlug("pancakes", test.taken); slug != test.want {
+			t.Errorf("UniqueSlug with %v taken = %q, want %q", test.taken, slug, test.want)
+		}
+	}
+}
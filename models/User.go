@@ -1,10 +1,17 @@
 package models
 
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
 // API user credentials
 // It is used to sign in
 //
 // swagger:model user
 type User struct {
+	//swagger:ignore
+	ID primitive.ObjectID `json:"id" bson:"_id"`
 	// User's password
 	//
 	// required: true
@@ -13,4 +20,74 @@ type User struct {
 	//
 	// required: true
 	Username string `json:"username"`
+	// User's email address
+	//
+	// required: false
+	Email string `json:"email,omitempty" bson:"email,omitempty"`
+	//swagger:ignore
+	Role string `json:"role" bson:"role"`
+	//swagger:ignore
+	EmailVerified bool `json:"-" bson:"emailVerified,omitempty"`
+	// Hash of the token sent to verify the email, until it is verified
+	//swagger:ignore
+	VerificationToken string `json:"-" bson:"verificationToken,omitempty"`
+	//swagger:ignore
+	VerificationExpires *time.Time `json:"-" bson:"verificationExpires,omitempty"`
+}
+
+// PendingVerification reports whether the user signed up with email
+// verification and has not verified their email yet. Users created before
+// verification existed have no token and are not held back.
+func (user User) PendingVerification() bool {
+	return !user.EmailVerified && user.VerificationToken != ""
+}
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// SignUpInput is the payload accepted by sign up. The ID and role of the
+// new user are always set by the server.
+type SignUpInput struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"omitempty,email"`
+	Password string `json:"password" binding:"required"`
+	// Required when SIGNUP_MODE is invite
+	InviteCode string `json:"inviteCode,omitempty"`
+}
+
+// UserProfile is the view of a user given to the user and to admins,
+// without credentials
+type UserProfile struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id"`
+	Username string             `json:"username" bson:"username"`
+	Email    string             `json:"email,omitempty" bson:"email,omitempty"`
+	Role     string             `json:"role" bson:"role"`
+	// Set once the user followed the link sent on sign up
+	EmailVerified bool `json:"emailVerified,omitempty" bson:"emailVerified,omitempty"`
+}
+
+// PublicProfile is the view of a user given to other users, without contact details
+type PublicProfile struct {
+	ID       primitive.ObjectID `json:"id"`
+	Username string             `json:"username"`
+	Role     string             `json:"role"`
+}
+
+// Public returns the part of the profile other users may see
+func (profile UserProfile) Public() PublicProfile {
+	return PublicProfile{
+		ID:       profile.ID,
+		Username: profile.Username,
+		Role:     profile.Role,
+	}
+}
+
+// UsersPage is the envelope returned by the paginated user listing
+type UsersPage struct {
+	Users []UserProfile `json:"users"`
+	Page  int           `json:"page"`
+	Limit int           `json:"limit"`
+	Total int64         `json:"total"`
 }
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Webhook subscription to the changes of a user's recipes
+//
+// swagger:model webhook
+type Webhook struct {
+	//swagger:ignore
+	ID primitive.ObjectID `json:"id" bson:"_id"`
+	// URL receiving a POST for every change
+	//
+	// required: true
+	URL string `json:"url" bson:"url" binding:"required"`
+	//swagger:ignore
+	UserID primitive.ObjectID `json:"userId" bson:"userId"`
+	// Secret used to sign the payloads, generated by the server
+	//swagger:ignore
+	Secret string `json:"secret" bson:"secret"`
+	//swagger:ignore
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/config"
+	"log"
+	"net"
+	"net/http"
+	"os"
+)
+
+// tlsVersions maps the TLS_MIN_VERSION values to their crypto/tls constants
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// serverTLSConfig refuses clients offering less than TLS_MIN_VERSION, 1.2 by default
+func serverTLSConfig() (*tls.Config, error) {
+	value := config.String("TLS_MIN_VERSION", "1.2")
+	version, ok := tlsVersions[value]
+	if !ok {
+		return nil, fmt.Errorf("invalid TLS_MIN_VERSION %q, expected 1.0, 1.1, 1.2 or 1.3", value)
+	}
+	return &tls.Config{MinVersion: version}, nil
+}
+
+// serve listens on PORT, 8080 by default like gin. With TLS_CERT_FILE and
+// TLS_KEY_FILE set it serves HTTPS, and HTTPS_REDIRECT_ADDR, such as :80,
+// starts a plain HTTP listener redirecting every request to it.
+func serve(handler http.Handler) error {
+	addr := ":" + config.String("PORT", "8080")
+	certFile, keyFile := os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	if certFile == "" && keyFile == "" {
+		if os.Getenv("HTTPS_REDIRECT_ADDR") != "" {
+			return errors.New("HTTPS_REDIRECT_ADDR requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		log.Printf("Listening and serving HTTP on %s", addr)
+		return http.ListenAndServe(addr, handler)
+	}
+	if certFile == "" || keyFile == "" {
+		return errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	tlsConfig, err := serverTLSConfig()
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Addr:      addr,
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+	}
+
+	if redirectAddr := os.Getenv("HTTPS_REDIRECT_ADDR"); redirectAddr != "" {
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return err
+		}
+		go func() {
+			log.Printf("Redirecting HTTP on %s to HTTPS", redirectAddr)
+			if err := http.ListenAndServe(redirectAddr, httpsRedirect(port)); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	log.Printf("Listening and serving HTTPS on %s", addr)
+	return server.ListenAndServeTLS(certFile, keyFile)
+}
+
+// httpsRedirect sends every request to the same URL over HTTPS on port.
+// 308 keeps the method and body of the request, unlike 301.
+func httpsRedirect(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// tlsServer serves with the configuration of serverTLSConfig and the test certificate of httptest
+func tlsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	tlsConfig, err := serverTLSConfig()
+	if err != nil {
+		t.Fatalf("serverTLSConfig: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = tlsConfig
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// negotiate connects to server with TLS versions up to maxVersion, returning the version negotiated
+func negotiate(server *httptest.Server, maxVersion uint16) (uint16, error) {
+	client := server.Client()
+	transport := client.Transport.(*http.Transport).Clone()
+	// Clients refuse old versions by default, which would hide what the server accepts
+	transport.TLSClientConfig.MinVersion = tls.VersionTLS10
+	transport.TLSClientConfig.MaxVersion = maxVersion
+	client.Transport = transport
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.TLS.Version, nil
+}
+
+func TestServerTLSMinVersion(t *testing.T) {
+	tests := []struct {
+		minVersion string
+		accepted   uint16
+		refused    uint16
+	}{
+		{"", tls.VersionTLS12, tls.VersionTLS11},
+		{"1.1", tls.VersionTLS11, tls.VersionTLS10},
+		{"1.2", tls.VersionTLS12, tls.VersionTLS11},
+		{"1.3", tls.VersionTLS13, tls.VersionTLS12},
+	}
+	for _, test := range tests {
+		t.Run("TLS_MIN_VERSION="+test.minVersion, func(t *testing.T) {
+			t.Setenv("TLS_MIN_VERSION", test.minVersion)
+			server := tlsServer(t)
+			if version, err := negotiate(server, test.accepted); err != nil || version != test.accepted {
+				t.Errorf("a client offering up to %x negotiated %x, %v", test.accepted, version, err)
+			}
+			if version, err := negotiate(server, test.refused); err == nil {
+				t.Errorf("a client offering up to %x was accepted with %x", test.refused, version)
+			}
+		})
+	}
+}
+
+func TestServerTLSConfigRejectsUnknownVersions(t *testing.T) {
+	for _, value := range []string{"1.4", "TLS1.2", "ssl3"} {
+		t.Setenv("TLS_MIN_VERSION", value)
+		if _, err := serverTLSConfig(); err == nil {
+			t.Errorf("TLS_MIN_VERSION=%s was accepted", value)
+		}
+	}
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		port string
+		host string
+		want string
+	}{
+		{"443", "example.com", "https://example.com/recipes?tag=sweet"},
+		{"443", "example.com:80", "https://example.com/recipes?tag=sweet"},
+		{"8443", "example.com:8080", "https://example.com:8443/recipes?tag=sweet"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/recipes?tag=sweet", nil)
+		req.Host = test.host
+		rec := httptest.NewRecorder()
+		httpsRedirect(test.port).ServeHTTP(rec, req)
+		// 308 keeps the method and body of the request
+		if rec.Code != http.StatusPermanentRedirect || rec.Header().Get("Location") != test.want {
+			t.Errorf("port %s, host %s: %d to %q, want 308 to %q", test.port, test.host, rec.Code, rec.Header().Get("Location"), test.want)
+		}
+	}
+}
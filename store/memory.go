@@ -0,0 +1,516 @@
+package store
+
+import (
+	"context"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MemoryStore is an in-memory RecipeStore, meant for tests and local development
+type MemoryStore struct {
+	mu      sync.RWMutex
+	recipes map[primitive.ObjectID]models.Recipe
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		recipes: make(map[primitive.ObjectID]models.Recipe),
+	}
+}
+
+func (store *MemoryStore) Create(ctx context.Context, recipe models.Recipe) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.recipes[recipe.ID] = recipe
+	return nil
+}
+
+func (store *MemoryStore) GetByID(ctx context.Context, id primitive.ObjectID) (models.Recipe, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	recipe, ok := store.recipes[id]
+	if !ok {
+		return recipe, ErrNotFound
+	}
+	return recipe, nil
+}
+
+func (store *MemoryStore) GetBySlug(ctx context.Context, slug string) (models.Recipe, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	for _, recipe := range store.recipes {
+		if recipe.Slug == slug {
+			return recipe, nil
+		}
+	}
+	return models.Recipe{}, ErrNotFound
+}
+
+func (store *MemoryStore) SlugsTaken(ctx context.Context, base string, exclude primitive.ObjectID) ([]string, error) {
+	recipes := store.filter(func(recipe models.Recipe) bool {
+		if recipe.ID == exclude || recipe.Slug == "" {
+			return false
+		}
+		if recipe.Slug == base {
+			return true
+		}
+		counter := strings.TrimPrefix(recipe.Slug, base+"-")
+		_, err := strconv.Atoi(counter)
+		return counter != recipe.Slug && err == nil
+	}, 0)
+
+	slugs := make([]string, len(recipes))
+	for i, recipe := range recipes {
+		slugs[i] = recipe.Slug
+	}
+	return slugs, nil
+}
+
+func (store *MemoryStore) List(ctx context.Context, filter ListFilter) ([]models.Recipe, error) {
+	recipes := store.filter(func(recipe models.Recipe) bool {
+		if filter.IDs != nil && !containsID(filter.IDs, recipe.ID) {
+			return false
+		}
+		if filter.Name != "" && models.NormalizeName(recipe.Name) != models.NormalizeName(filter.Name) {
+			return false
+		}
+		if !MatchMetadata(recipe.Metadata, filter.Metadata) {
+			return false
+		}
+		return filter.UserID.IsZero() || recipe.UserID == filter.UserID
+	}, 0)
+	for i, recipe := range recipes {
+		recipes[i] = project(recipe, filter.Fields)
+	}
+	return recipes, nil
+}
+
+func (store *MemoryStore) ListPage(ctx context.Context, filter ListFilter, page PageRequest) ([]models.Recipe, error) {
+	fields := filter.Fields
+	filter.Fields = nil
+	recipes, err := store.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	less := pageLess(page.Sort)
+	sort.Slice(recipes, func(i, j int) bool {
+		return less(recipes[i], recipes[j])
+	})
+	start := int(page.Skip)
+	if page.After != nil {
+		start = sort.Search(len(recipes), func(i int) bool {
+			return less(*page.After, recipes[i])
+		})
+	}
+	if start > len(recipes) {
+		start = len(recipes)
+	}
+	end := len(recipes)
+	if page.Limit > 0 && start+int(page.Limit) < end {
+		end = start + int(page.Limit)
+	}
+
+	paged := make([]models.Recipe, 0, end-start)
+	for _, recipe := range recipes[start:end] {
+		paged = append(paged, project(recipe, fields))
+	}
+	return paged, nil
+}
+
+// pageLess orders recipes as PageRequest.Sort describes
+func pageLess(order string) func(a, b models.Recipe) bool {
+	field := strings.TrimPrefix(order, "-")
+	descending := strings.HasPrefix(order, "-")
+	return func(a, b models.Recipe) bool {
+		if descending {
+			a, b = b, a
+		}
+		if field == "name" {
+			if x, y := strings.ToLower(a.Name), strings.ToLower(b.Name); x != y {
+				return x < y
+			}
+		} else if !a.PublishedAt.Equal(b.PublishedAt) {
+			return a.PublishedAt.Before(b.PublishedAt)
+		}
+		return a.ID.Hex() < b.ID.Hex()
+	}
+}
+
+func (store *MemoryStore) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	recipes, err := store.List(ctx, filter)
+	return int64(len(recipes)), err
+}
+
+// Stream works on a snapshot of the recipes, taken as List does
+func (store *MemoryStore) Stream(ctx context.Context, filter ListFilter, each func(models.Recipe) error) error {
+	recipes, err := store.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for _, recipe := range recipes {
+		if err := each(recipe); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *MemoryStore) Update(ctx context.Context, id primitive.ObjectID, patch models.RecipePatch) (models.Recipe, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	recipe, ok := store.recipes[id]
+	if !ok {
+		return recipe, ErrNotFound
+	}
+	patch.Apply(&recipe)
+	store.recipes[id] = recipe
+	return recipe, nil
+}
+
+func (store *MemoryStore) Delete(ctx context.Context, id primitive.ObjectID) (models.Recipe, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	recipe, ok := store.recipes[id]
+	if !ok {
+		return recipe, ErrNotFound
+	}
+	delete(store.recipes, id)
+	return recipe, nil
+}
+
+func (store *MemoryStore) DeleteMany(ctx context.Context, ids []primitive.ObjectID, owner primitive.ObjectID) ([]models.Recipe, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	deleted := make([]models.Recipe, 0)
+	for _, id := range ids {
+		recipe, ok := store.recipes[id]
+		if !ok || (!owner.IsZero() && recipe.UserID != owner) {
+			continue
+		}
+		delete(store.recipes, id)
+		deleted = append(deleted, recipe)
+	}
+	return deleted, nil
+}
+
+func (store *MemoryStore) Search(ctx context.Context, criteria SearchCriteria) ([]models.Recipe, error) {
+	name := strings.ToLower(criteria.NameContains)
+	return store.filter(func(recipe models.Recipe) bool {
+		if len(criteria.Tags) > 0 && !matchTags(recipe.Tags, criteria.Tags, criteria.MatchAllTags) {
+			return false
+		}
+		if criteria.SourceContains != "" {
+			source := strings.ToLower(criteria.SourceContains)
+			if !strings.Contains(strings.ToLower(recipe.SourceName), source) && !strings.Contains(strings.ToLower(recipe.SourceURL), source) {
+				return false
+			}
+		}
+		if len(criteria.Ingredients) > 0 && !matchIngredients(recipe.Ingredients, criteria.Ingredients, criteria.MatchAllIngredients) {
+			return false
+		}
+		if !MatchMetadata(recipe.Metadata, criteria.Metadata) {
+			return false
+		}
+		if criteria.PublicOnly && !recipe.IsPublic() && (criteria.OwnedBy.IsZero() || recipe.UserID != criteria.OwnedBy) {
+			return false
+		}
+		if len(criteria.NameContainsAny) > 0 && !containsAny(strings.ToLower(recipe.Name), criteria.NameContainsAny) {
+			return false
+		}
+		return strings.Contains(strings.ToLower(recipe.Name), name)
+	}, criteria.Limit), nil
+}
+
+func (store *MemoryStore) TagCounts(ctx context.Context) ([]models.TagCount, error) {
+	store.mu.RLock()
+	counts := make(map[string]int)
+	for _, recipe := range store.recipes {
+		if !recipe.IsPublic() {
+			continue
+		}
+		for _, tag := range recipe.Tags {
+			counts[tag]++
+		}
+	}
+	store.mu.RUnlock()
+
+	tags := make([]models.TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, models.TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
+
+func (store *MemoryStore) Stats(ctx context.Context, topTags int) (models.RecipeStats, error) {
+	tags, err := store.TagCounts(ctx)
+	if err != nil {
+		return models.RecipeStats{}, err
+	}
+	if len(tags) > topTags {
+		tags = tags[:topTags]
+	}
+
+	published := store.filter(func(recipe models.Recipe) bool {
+		return recipe.IsPublic()
+	}, 0)
+	months := make(map[string]int)
+	ingredients := 0
+	for _, recipe := range published {
+		ingredients += len(recipe.Ingredients)
+		months[recipe.PublishedAt.UTC().Format("2006-01")]++
+	}
+
+	stats := models.RecipeStats{
+		Total:    len(published),
+		Tags:     tags,
+		PerMonth: make([]models.MonthCount, 0, len(months)),
+	}
+	if len(published) > 0 {
+		stats.AverageIngredients = float64(ingredients) / float64(len(published))
+	}
+	for month, count := range months {
+		stats.PerMonth = append(stats.PerMonth, models.MonthCount{Month: month, Count: count})
+	}
+	sort.Slice(stats.PerMonth, func(i, j int) bool {
+		return stats.PerMonth[i].Month < stats.PerMonth[j].Month
+	})
+	return stats, nil
+}
+
+func (store *MemoryStore) RenameTag(ctx context.Context, from, to string) (int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var modified int64
+	for id, recipe := range store.recipes {
+		if !containsTag(recipe.Tags, from) {
+			continue
+		}
+		tags := make([]string, 0, len(recipe.Tags))
+		for _, tag := range recipe.Tags {
+			if tag == from {
+				tag = to
+			}
+			if !containsTag(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		recipe.Tags = tags
+		store.recipes[id] = recipe
+		modified++
+	}
+	return modified, nil
+}
+
+func (store *MemoryStore) Random(ctx context.Context, criteria RandomCriteria) (models.Recipe, error) {
+	candidates := store.filter(func(recipe models.Recipe) bool {
+		if !recipe.IsPublic() {
+			return false
+		}
+		if criteria.Difficulty != "" && recipe.Difficulty != criteria.Difficulty {
+			return false
+		}
+		if criteria.MaxTime > 0 && (recipe.TotalTime <= 0 || recipe.TotalTime > criteria.MaxTime) {
+			return false
+		}
+		return len(criteria.Tags) == 0 || matchTags(recipe.Tags, criteria.Tags, true)
+	}, 0)
+	if len(candidates) == 0 {
+		return models.Recipe{}, ErrNotFound
+	}
+	return candidates[rand.Intn(len(candidates))], nil
+}
+
+func (store *MemoryStore) AddViews(ctx context.Context, views map[primitive.ObjectID]int64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for id, count := range views {
+		if recipe, ok := store.recipes[id]; ok {
+			recipe.Views += count
+			store.recipes[id] = recipe
+		}
+	}
+	return nil
+}
+
+func (store *MemoryStore) Recent(ctx context.Context, limit int64) ([]models.Recipe, error) {
+	recent := store.filter(func(recipe models.Recipe) bool {
+		return recipe.IsPublic()
+	}, 0)
+	sort.SliceStable(recent, func(i, j int) bool {
+		if !recent[i].PublishedAt.Equal(recent[j].PublishedAt) {
+			return recent[i].PublishedAt.After(recent[j].PublishedAt)
+		}
+		return recent[i].ID.Hex() > recent[j].ID.Hex()
+	})
+	if limit > 0 && int64(len(recent)) > limit {
+		recent = recent[:limit]
+	}
+	return recent, nil
+}
+
+func (store *MemoryStore) Related(ctx context.Context, recipe models.Recipe, limit int64) ([]models.Recipe, error) {
+	shared := func(other models.Recipe) int {
+		count := 0
+		for _, tag := range other.Tags {
+			if matchTags(recipe.Tags, []string{tag}, false) {
+				count++
+			}
+		}
+		return count
+	}
+
+	related := store.filter(func(other models.Recipe) bool {
+		return other.ID != recipe.ID && other.IsPublic() && shared(other) > 0
+	}, 0)
+	sort.SliceStable(related, func(i, j int) bool {
+		a, b := shared(related[i]), shared(related[j])
+		if a != b {
+			return a > b
+		}
+		if !related[i].PublishedAt.Equal(related[j].PublishedAt) {
+			return related[i].PublishedAt.After(related[j].PublishedAt)
+		}
+		return related[i].ID.Hex() > related[j].ID.Hex()
+	})
+	if limit > 0 && int64(len(related)) > limit {
+		related = related[:limit]
+	}
+	return related, nil
+}
+
+// filter returns the recipes matching keep ordered by ID,
+// stopping after limit results when limit is positive
+func (store *MemoryStore) filter(keep func(models.Recipe) bool, limit int64) []models.Recipe {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	recipes := make([]models.Recipe, 0)
+	for _, recipe := range store.recipes {
+		if keep(recipe) {
+			recipes = append(recipes, recipe)
+		}
+	}
+	sort.Slice(recipes, func(i, j int) bool {
+		return recipes[i].ID.Hex() < recipes[j].ID.Hex()
+	})
+	if limit > 0 && int64(len(recipes)) > limit {
+		recipes = recipes[:limit]
+	}
+	return recipes
+}
+
+// project clears the fields of recipe other than fields, the way a MongoDB
+// projection leaves them out, or returns recipe as is when fields is empty
+func project(recipe models.Recipe, fields []string) models.Recipe {
+	if len(fields) == 0 {
+		return recipe
+	}
+	data, _ := bson.Marshal(recipe)
+	var all bson.M
+	bson.Unmarshal(data, &all)
+
+	kept := bson.M{"_id": recipe.ID}
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			kept[field] = value
+		}
+	}
+	var projected models.Recipe
+	data, _ = bson.Marshal(kept)
+	bson.Unmarshal(data, &projected)
+	return projected
+}
+
+func matchTags(recipeTags []string, wanted []string, all bool) bool {
+	has := make(map[string]bool, len(recipeTags))
+	for _, tag := range recipeTags {
+		has[tag] = true
+	}
+	for _, tag := range wanted {
+		if has[tag] && !all {
+			return true
+		}
+		if !has[tag] && all {
+			return false
+		}
+	}
+	return all
+}
+
+func matchIngredients(recipeIngredients []string, wanted []string, all bool) bool {
+	for _, item := range wanted {
+		found := false
+		for _, ingredient := range recipeIngredients {
+			if strings.Contains(strings.ToLower(ingredient), strings.ToLower(item)) {
+				found = true
+				break
+			}
+		}
+		if found && !all {
+			return true
+		}
+		if !found && all {
+			return false
+		}
+	}
+	return all
+}
+
+// containsAny reports whether s contains one of substrings, ignoring their case
+func containsAny(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, strings.ToLower(substring)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, candidate := range tags {
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchMetadata reports whether metadata has every key of wanted with the same value
+func MatchMetadata(metadata map[string]string, wanted map[string]string) bool {
+	for key, value := range wanted {
+		if actual, ok := metadata[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,519 @@
+package store
+
+import (
+	"context"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"strings"
+)
+
+// MongoStore is a RecipeStore backed by a MongoDB collection
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoStore(collection *mongo.Collection) *MongoStore {
+	return &MongoStore{
+		collection: collection,
+	}
+}
+
+func (store *MongoStore) Create(ctx context.Context, recipe models.Recipe) error {
+	_, err := store.collection.InsertOne(ctx, recipe)
+	return err
+}
+
+func (store *MongoStore) GetByID(ctx context.Context, id primitive.ObjectID) (models.Recipe, error) {
+	var recipe models.Recipe
+	err := store.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&recipe)
+	if err == mongo.ErrNoDocuments {
+		return recipe, ErrNotFound
+	}
+	return recipe, err
+}
+
+func (store *MongoStore) GetBySlug(ctx context.Context, slug string) (models.Recipe, error) {
+	var recipe models.Recipe
+	err := store.collection.FindOne(ctx, bson.M{"slug": slug}).Decode(&recipe)
+	if err == mongo.ErrNoDocuments {
+		return recipe, ErrNotFound
+	}
+	return recipe, err
+}
+
+func (store *MongoStore) SlugsTaken(ctx context.Context, base string, exclude primitive.ObjectID) ([]string, error) {
+	filter := bson.M{"slug": bson.M{"$regex": "^" + regexp.QuoteMeta(base) + "(-[0-9]+)?$"}}
+	if !exclude.IsZero() {
+		filter["_id"] = bson.M{"$ne": exclude}
+	}
+	recipes, err := store.find(ctx, filter, options.Find().SetProjection(bson.M{"slug": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	slugs := make([]string, len(recipes))
+	for i, recipe := range recipes {
+		slugs[i] = recipe.Slug
+	}
+	return slugs, nil
+}
+
+func (store *MongoStore) List(ctx context.Context, filter ListFilter) ([]models.Recipe, error) {
+	return store.find(ctx, listQuery(filter), options.Find().SetProjection(projection(filter.Fields)))
+}
+
+// ListPage compares names with a case-insensitive collation, so that they
+// sort and page the way they read
+func (store *MongoStore) ListPage(ctx context.Context, filter ListFilter, page PageRequest) ([]models.Recipe, error) {
+	field, direction := strings.TrimPrefix(page.Sort, "-"), 1
+	if strings.HasPrefix(page.Sort, "-") {
+		direction = -1
+	}
+
+	query := listQuery(filter)
+	if page.After != nil {
+		comparison := "$gt"
+		if direction < 0 {
+			comparison = "$lt"
+		}
+		var value interface{} = page.After.PublishedAt
+		if field == "name" {
+			value = page.After.Name
+		}
+		query = bson.M{"$and": bson.A{query, bson.M{"$or": bson.A{
+			bson.M{field: bson.M{comparison: value}},
+			bson.M{field: value, "_id": bson.M{comparison: page.After.ID}},
+		}}}}
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: field, Value: direction}, {Key: "_id", Value: direction}}).
+		SetCollation(&options.Collation{Locale: "en", Strength: 2}).
+		SetSkip(page.Skip).
+		SetLimit(page.Limit).
+		SetProjection(projection(filter.Fields))
+	return store.find(ctx, query, findOptions)
+}
+
+func (store *MongoStore) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	return store.collection.CountDocuments(ctx, listQuery(filter))
+}
+
+func (store *MongoStore) Stream(ctx context.Context, filter ListFilter, each func(models.Recipe) error) error {
+	findOptions := options.Find().SetSort(bson.M{"_id": 1}).SetProjection(projection(filter.Fields))
+	cur, err := store.collection.Find(ctx, listQuery(filter), findOptions)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var recipe models.Recipe
+		if err := cur.Decode(&recipe); err != nil {
+			return err
+		}
+		if err := each(recipe); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// publicQuery matches the recipes visible to everyone, neither drafts nor private
+func publicQuery() bson.M {
+	return bson.M{
+		"status":     bson.M{"$ne": models.StatusDraft},
+		"visibility": bson.M{"$ne": models.VisibilityPrivate},
+	}
+}
+
+func listQuery(filter ListFilter) bson.M {
+	query := bson.M{}
+	if !filter.UserID.IsZero() {
+		query["userId"] = filter.UserID
+	}
+	if filter.IDs != nil {
+		query["_id"] = bson.M{"$in": filter.IDs}
+	}
+	if filter.Name != "" {
+		words := strings.Fields(filter.Name)
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		query["name"] = bson.M{"$regex": `^\s*` + strings.Join(words, `\s+`) + `\s*$`, "$options": "i"}
+	}
+	for key, value := range filter.Metadata {
+		query["metadata."+key] = value
+	}
+	return query
+}
+
+// projection reads only fields, or every field when there are none
+func projection(fields []string) interface{} {
+	if len(fields) == 0 {
+		return nil
+	}
+	included := bson.M{"_id": 1}
+	for _, field := range fields {
+		if field != "id" {
+			included[field] = 1
+		}
+	}
+	return included
+}
+
+func (store *MongoStore) Update(ctx context.Context, id primitive.ObjectID, patch models.RecipePatch) (models.Recipe, error) {
+	update := bson.D{}
+	if patch.Name != nil {
+		update = append(update, bson.E{Key: "name", Value: *patch.Name})
+	}
+	if patch.Instructions != nil {
+		update = append(update, bson.E{Key: "instructions", Value: *patch.Instructions})
+	}
+	if patch.Ingredients != nil {
+		update = append(update, bson.E{Key: "ingredients", Value: *patch.Ingredients})
+	}
+	if patch.Tags != nil {
+		update = append(update, bson.E{Key: "tags", Value: *patch.Tags})
+	}
+	if patch.Status != nil {
+		update = append(update, bson.E{Key: "status", Value: *patch.Status})
+	}
+	if patch.Servings != nil {
+		update = append(update, bson.E{Key: "servings", Value: *patch.Servings})
+	}
+	if patch.Nutrition != nil {
+		update = append(update, bson.E{Key: "nutrition", Value: *patch.Nutrition})
+	}
+	if patch.SourceURL != nil {
+		update = append(update, bson.E{Key: "sourceUrl", Value: *patch.SourceURL})
+	}
+	if patch.SourceName != nil {
+		update = append(update, bson.E{Key: "sourceName", Value: *patch.SourceName})
+	}
+	if patch.Yield != nil {
+		update = append(update, bson.E{Key: "yield", Value: *patch.Yield})
+	}
+	if patch.Translations != nil {
+		update = append(update, bson.E{Key: "translations", Value: *patch.Translations})
+	}
+	if patch.Metadata != nil {
+		update = append(update, bson.E{Key: "metadata", Value: *patch.Metadata})
+	}
+	if patch.Visibility != nil {
+		update = append(update, bson.E{Key: "visibility", Value: *patch.Visibility})
+	}
+	if patch.Difficulty != nil {
+		update = append(update, bson.E{Key: "difficulty", Value: *patch.Difficulty})
+	}
+	if patch.TotalTime != nil {
+		update = append(update, bson.E{Key: "totalTime", Value: *patch.TotalTime})
+	}
+	if patch.PublishedAt != nil {
+		update = append(update, bson.E{Key: "publishedAt", Value: *patch.PublishedAt})
+	}
+	if patch.Slug != nil {
+		update = append(update, bson.E{Key: "slug", Value: *patch.Slug})
+	}
+
+	var recipe models.Recipe
+	err := store.collection.FindOneAndUpdate(ctx, bson.M{
+		"_id": id,
+	}, bson.D{{Key: "$set", Value: update}}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&recipe)
+	if err == mongo.ErrNoDocuments {
+		return recipe, ErrNotFound
+	}
+	return recipe, err
+}
+
+func (store *MongoStore) Delete(ctx context.Context, id primitive.ObjectID) (models.Recipe, error) {
+	var recipe models.Recipe
+	err := store.collection.FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&recipe)
+	if err == mongo.ErrNoDocuments {
+		return recipe, ErrNotFound
+	}
+	return recipe, err
+}
+
+func (store *MongoStore) DeleteMany(ctx context.Context, ids []primitive.ObjectID, owner primitive.ObjectID) ([]models.Recipe, error) {
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	if !owner.IsZero() {
+		filter["userId"] = owner
+	}
+
+	recipes, err := store.find(ctx, filter)
+	if err != nil || len(recipes) == 0 {
+		return recipes, err
+	}
+
+	found := make([]primitive.ObjectID, len(recipes))
+	for i, recipe := range recipes {
+		found[i] = recipe.ID
+	}
+	if _, err := store.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": found}}); err != nil {
+		return nil, err
+	}
+	return recipes, nil
+}
+
+func (store *MongoStore) Search(ctx context.Context, criteria SearchCriteria) ([]models.Recipe, error) {
+	filter := bson.M{}
+	if len(criteria.Tags) > 0 {
+		if criteria.MatchAllTags {
+			filter["tags"] = bson.M{"$all": criteria.Tags}
+		} else {
+			filter["tags"] = bson.M{"$in": criteria.Tags}
+		}
+	}
+	if criteria.NameContains != "" {
+		filter["name"] = bson.M{"$regex": regexp.QuoteMeta(criteria.NameContains), "$options": "i"}
+	}
+	if len(criteria.NameContainsAny) > 0 {
+		fragments := make([]string, len(criteria.NameContainsAny))
+		for i, fragment := range criteria.NameContainsAny {
+			fragments[i] = regexp.QuoteMeta(fragment)
+		}
+		filter["name"] = bson.M{"$regex": strings.Join(fragments, "|"), "$options": "i"}
+	}
+	if criteria.SourceContains != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(criteria.SourceContains), "$options": "i"}
+		filter["$or"] = bson.A{bson.M{"sourceName": pattern}, bson.M{"sourceUrl": pattern}}
+	}
+	if len(criteria.Ingredients) > 0 {
+		ingredients := bson.A{}
+		for _, ingredient := range criteria.Ingredients {
+			ingredients = append(ingredients, bson.M{"ingredients": bson.M{"$regex": regexp.QuoteMeta(ingredient), "$options": "i"}})
+		}
+		if !criteria.MatchAllIngredients {
+			ingredients = bson.A{bson.M{"$or": ingredients}}
+		}
+		filter["$and"] = ingredients
+	}
+	for key, value := range criteria.Metadata {
+		filter["metadata."+key] = value
+	}
+	if criteria.PublicOnly {
+		visible := publicQuery()
+		if !criteria.OwnedBy.IsZero() {
+			visible = bson.M{"$or": bson.A{visible, bson.M{"userId": criteria.OwnedBy}}}
+		}
+		and, _ := filter["$and"].(bson.A)
+		filter["$and"] = append(and, visible)
+	}
+
+	findOptions := options.Find()
+	if criteria.Limit > 0 {
+		// Sorted so that the same recipes make the cut every time
+		findOptions.SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(criteria.Limit)
+	}
+	return store.find(ctx, filter, findOptions)
+}
+
+// TagCounts only counts the tags of recipes visible to everyone
+func (store *MongoStore) TagCounts(ctx context.Context) ([]models.TagCount, error) {
+	cur, err := store.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": publicQuery()},
+		bson.M{"$unwind": "$tags"},
+		bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	tags := make([]models.TagCount, 0)
+	if err := cur.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// Stats computes every figure in a single aggregation, one $facet each
+func (store *MongoStore) Stats(ctx context.Context, topTags int) (models.RecipeStats, error) {
+	stats := models.RecipeStats{Tags: make([]models.TagCount, 0), PerMonth: make([]models.MonthCount, 0)}
+	cur, err := store.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": publicQuery()},
+		bson.M{"$facet": bson.M{
+			"total": bson.A{bson.M{"$count": "count"}},
+			"tags": bson.A{
+				bson.M{"$unwind": "$tags"},
+				bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+				bson.M{"$limit": topTags},
+			},
+			"ingredients": bson.A{
+				bson.M{"$group": bson.M{"_id": nil, "average": bson.M{"$avg": bson.M{"$size": bson.M{"$ifNull": bson.A{"$ingredients", bson.A{}}}}}}},
+			},
+			"perMonth": bson.A{
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$publishedAt"}},
+					"count": bson.M{"$sum": 1},
+				}},
+				bson.M{"$sort": bson.M{"_id": 1}},
+			},
+		}},
+	})
+	if err != nil {
+		return stats, err
+	}
+	defer cur.Close(ctx)
+
+	var facets []struct {
+		Total []struct {
+			Count int `bson:"count"`
+		} `bson:"total"`
+		Tags        []models.TagCount `bson:"tags"`
+		Ingredients []struct {
+			Average float64 `bson:"average"`
+		} `bson:"ingredients"`
+		PerMonth []models.MonthCount `bson:"perMonth"`
+	}
+	if err := cur.All(ctx, &facets); err != nil {
+		return stats, err
+	}
+	if len(facets) == 0 {
+		return stats, nil
+	}
+
+	result := facets[0]
+	if len(result.Total) > 0 {
+		stats.Total = result.Total[0].Count
+	}
+	if len(result.Ingredients) > 0 {
+		stats.AverageIngredients = result.Ingredients[0].Average
+	}
+	if result.Tags != nil {
+		stats.Tags = result.Tags
+	}
+	if result.PerMonth != nil {
+		stats.PerMonth = result.PerMonth
+	}
+	return stats, nil
+}
+
+// RenameTag updates the tags with a pipeline, since $pull and $addToSet cannot
+// change the same field in a single update
+func (store *MongoStore) RenameTag(ctx context.Context, from, to string) (int64, error) {
+	renamed := bson.M{"$map": bson.M{
+		"input": "$tags",
+		"in":    bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$$this", from}}, to, "$$this"}},
+	}}
+	deduplicated := bson.M{"$reduce": bson.M{
+		"input":        renamed,
+		"initialValue": bson.A{},
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$in": bson.A{"$$this", "$$value"}},
+			"$$value",
+			bson.M{"$concatArrays": bson.A{"$$value", bson.A{"$$this"}}},
+		}},
+	}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"tags": deduplicated}}}}
+
+	result, err := store.collection.UpdateMany(ctx, bson.M{"tags": from}, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (store *MongoStore) Random(ctx context.Context, criteria RandomCriteria) (models.Recipe, error) {
+	match := publicQuery()
+	if len(criteria.Tags) > 0 {
+		match["tags"] = bson.M{"$all": criteria.Tags}
+	}
+	if criteria.Difficulty != "" {
+		match["difficulty"] = criteria.Difficulty
+	}
+	if criteria.MaxTime > 0 {
+		match["totalTime"] = bson.M{"$gt": 0, "$lte": criteria.MaxTime}
+	}
+
+	var recipe models.Recipe
+	cur, err := store.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": match},
+		bson.M{"$sample": bson.M{"size": 1}},
+	})
+	if err != nil {
+		return recipe, err
+	}
+	defer cur.Close(ctx)
+
+	if !cur.Next(ctx) {
+		if err := cur.Err(); err != nil {
+			return recipe, err
+		}
+		return recipe, ErrNotFound
+	}
+	return recipe, cur.Decode(&recipe)
+}
+
+func (store *MongoStore) AddViews(ctx context.Context, views map[primitive.ObjectID]int64) error {
+	writes := make([]mongo.WriteModel, 0, len(views))
+	for id, count := range views {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{"$inc": bson.M{"views": count}}))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	_, err := store.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (store *MongoStore) Recent(ctx context.Context, limit int64) ([]models.Recipe, error) {
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "publishedAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(limit)
+	return store.find(ctx, publicQuery(), findOptions)
+}
+
+func (store *MongoStore) Related(ctx context.Context, recipe models.Recipe, limit int64) ([]models.Recipe, error) {
+	recipes := make([]models.Recipe, 0)
+	if len(recipe.Tags) == 0 {
+		return recipes, nil
+	}
+
+	match := publicQuery()
+	match["_id"] = bson.M{"$ne": recipe.ID}
+	match["tags"] = bson.M{"$in": recipe.Tags}
+	cur, err := store.collection.Aggregate(ctx, bson.A{
+		bson.M{"$match": match},
+		bson.M{"$addFields": bson.M{"sharedTags": bson.M{"$size": bson.M{"$setIntersection": bson.A{"$tags", recipe.Tags}}}}},
+		bson.M{"$sort": bson.D{{Key: "sharedTags", Value: -1}, {Key: "publishedAt", Value: -1}, {Key: "_id", Value: -1}}},
+		bson.M{"$limit": limit},
+		bson.M{"$project": bson.M{"sharedTags": 0}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	if err := cur.All(ctx, &recipes); err != nil {
+		return nil, err
+	}
+	return recipes, nil
+}
+
+func (store *MongoStore) find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) ([]models.Recipe, error) {
+	cur, err := store.collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	recipes := make([]models.Recipe, 0)
+	for cur.Next(ctx) {
+		var recipe models.Recipe
+		if err := cur.Decode(&recipe); err != nil {
+			return nil, err
+		}
+		recipes = append(recipes, recipe)
+	}
+	return recipes, cur.Err()
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"strings"
+	"testing"
+)
+
+func cursorOf(mt *mtest.T, docs ...bson.D) bson.D {
+	ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+	return mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, docs...)
+}
+
+// sentProjection returns the projection of the find command mt last started
+func sentProjection(mt *mtest.T) bson.Raw {
+	event := mt.GetStartedEvent()
+	if event == nil || event.CommandName != "find" {
+		mt.Fatalf("no find command was sent")
+	}
+	projection, _ := event.Command.Lookup("projection").DocumentOK()
+	return projection
+}
+
+func TestMongoStoreProjectsFields(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	doc := bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "name", Value: "Pancakes"}}
+
+	mt.Run("list", func(mt *mtest.T) {
+		mt.AddMockResponses(cursorOf(mt, doc))
+		recipes, err := NewMongoStore(mt.Coll).List(context.Background(), ListFilter{Fields: []string{"id", "name", "tags"}})
+		if err != nil || len(recipes) != 1 || recipes[0].Name != "Pancakes" {
+			mt.Fatalf("List = %v, %v", recipes, err)
+		}
+		projection := sentProjection(mt)
+		if elements, _ := projection.Elements(); len(elements) != 3 ||
+			projection.Lookup("_id").Int32() != 1 || projection.Lookup("name").Int32() != 1 || projection.Lookup("tags").Int32() != 1 {
+			mt.Errorf("projection = %v, want _id, name and tags", projection)
+		}
+	})
+
+	mt.Run("stream", func(mt *mtest.T) {
+		mt.AddMockResponses(cursorOf(mt, doc))
+		err := NewMongoStore(mt.Coll).Stream(context.Background(), ListFilter{Fields: []string{"name"}}, func(recipe models.Recipe) error {
+			return nil
+		})
+		if err != nil {
+			mt.Fatalf("Stream: %v", err)
+		}
+		if projection := sentProjection(mt); projection.Lookup("name").Int32() != 1 || projection.Lookup("ingredients").Value != nil {
+			mt.Errorf("projection = %v, want name only", projection)
+		}
+	})
+
+	mt.Run("every field", func(mt *mtest.T) {
+		mt.AddMockResponses(cursorOf(mt, doc))
+		if _, err := NewMongoStore(mt.Coll).List(context.Background(), ListFilter{}); err != nil {
+			mt.Fatalf("List: %v", err)
+		}
+		if projection := sentProjection(mt); projection != nil {
+			mt.Errorf("projection = %v, want none", projection)
+		}
+	})
+}
+
+func TestMongoStoreListPage(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("by offset", func(mt *mtest.T) {
+		mt.AddMockResponses(cursorOf(mt))
+		_, err := NewMongoStore(mt.Coll).ListPage(context.Background(), ListFilter{}, PageRequest{Sort: "-name", Skip: 40, Limit: 21})
+		if err != nil {
+			mt.Fatalf("ListPage: %v", err)
+		}
+		command := mt.GetStartedEvent().Command
+		if command.Lookup("skip").AsInt64() != 40 || command.Lookup("limit").AsInt64() != 21 {
+			mt.Errorf("find %s does not skip 40 and limit to 21", command)
+		}
+		if sort := command.Lookup("sort").Document(); sort.Lookup("name").Int32() != -1 || sort.Lookup("_id").Int32() != -1 {
+			mt.Errorf("sort = %s, want name then _id descending", sort)
+		}
+		if collation := command.Lookup("collation").Document(); collation.Lookup("strength").Int32() != 2 {
+			mt.Errorf("collation = %s, want case-insensitive", collation)
+		}
+	})
+
+	mt.Run("after a recipe", func(mt *mtest.T) {
+		mt.AddMockResponses(cursorOf(mt))
+		last := models.Recipe{ID: primitive.NewObjectID(), Name: "Pancakes"}
+		_, err := NewMongoStore(mt.Coll).ListPage(context.Background(), ListFilter{}, PageRequest{Sort: "name", Limit: 21, After: &last})
+		if err != nil {
+			mt.Fatalf("ListPage: %v", err)
+		}
+		command := mt.GetStartedEvent().Command
+		filter := command.Lookup("filter").String()
+		for _, want := range []string{`{"name": {"$gt": "Pancakes"}}`, `"_id": {"$gt": {"$oid":"` + last.ID.Hex() + `"}}`} {
+			if !strings.Contains(filter, want) {
+				mt.Errorf("filter %s lacks %s", filter, want)
+			}
+		}
+		if skip, _ := command.Lookup("skip").AsInt64OK(); skip != 0 {
+			mt.Errorf("find %s skips recipes after a cursor", command)
+		}
+	})
+}
+
+func TestMongoStoreSearchByNameFragments(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("fragments", func(mt *mtest.T) {
+		mt.AddMockResponses(cursorOf(mt))
+		_, err := NewMongoStore(mt.Coll).Search(context.Background(), SearchCriteria{NameContainsAny: []string{"ch", "a.", "en"}, Limit: 500})
+		if err != nil {
+			mt.Fatalf("Search: %v", err)
+		}
+		command := mt.GetStartedEvent().Command
+		if filter := command.Lookup("filter").String(); !strings.Contains(filter, `"$regex": "ch|a\\.|en"`) {
+			mt.Errorf("filter %s does not match any fragment", filter)
+		}
+		if sort := command.Lookup("sort").Document(); sort.Lookup("_id").Int32() != 1 {
+			mt.Errorf("sort = %s, want _id so the same candidates are kept", sort)
+		}
+	})
+}
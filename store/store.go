@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound is returned when no recipe matches the given ID
+var ErrNotFound = errors.New("recipe not found")
+
+// ListFilter restricts the recipes returned by List. Zero values match everything.
+type ListFilter struct {
+	UserID primitive.ObjectID
+	IDs    []primitive.ObjectID
+	// Name matches names equal to it ignoring case and spacing, see models.NormalizeName
+	Name string
+	// Metadata lists the metadata values the recipes must all have
+	Metadata map[string]string
+	// Fields are the JSON names of the fields read from each recipe, which
+	// are also their BSON names but for id. The ID is always read, and every
+	// field when Fields is empty.
+	Fields []string
+}
+
+// PageRequest selects a page of the recipes returned by ListPage
+type PageRequest struct {
+	// Sort is name, ignoring case, or publishedAt, prefixed with - for
+	// descending. Ties are broken on the ID in the same direction.
+	Sort  string
+	Skip  int64
+	Limit int64
+	// After starts the page right after this recipe in the sort order,
+	// instead of skipping recipes
+	After *models.Recipe
+}
+
+// SearchCriteria describes a recipe search. Zero values match everything.
+type SearchCriteria struct {
+	Tags         []string
+	MatchAllTags bool
+	NameContains string
+	// NameContainsAny keeps the recipes whose name contains at least one of
+	// these substrings, ignoring case
+	NameContainsAny []string
+	// SourceContains is matched against the source name or URL
+	SourceContains string
+	// Ingredients are matched as substrings of the recipe ingredients
+	Ingredients         []string
+	MatchAllIngredients bool
+	// Metadata lists the metadata values the recipes must all have
+	Metadata map[string]string
+	// PublicOnly leaves out drafts and private recipes, except those of
+	// OwnedBy when it is set
+	PublicOnly bool
+	OwnedBy    primitive.ObjectID
+	Limit      int64
+}
+
+// RandomCriteria restricts the recipes Random picks from. Zero values match everything.
+type RandomCriteria struct {
+	Tags       []string
+	Difficulty string
+	// MaxTime keeps the recipes taking at most this many minutes. Recipes
+	// without a total time never match it.
+	MaxTime int
+}
+
+// RecipeStore persists recipes
+type RecipeStore interface {
+	Create(ctx context.Context, recipe models.Recipe) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (models.Recipe, error)
+	// GetBySlug returns the recipe with the given slug, or ErrNotFound
+	GetBySlug(ctx context.Context, slug string) (models.Recipe, error)
+	// SlugsTaken returns the slugs of the recipes other than exclude that are
+	// base itself or base followed by a counter, such as base-2
+	SlugsTaken(ctx context.Context, base string, exclude primitive.ObjectID) ([]string, error)
+	List(ctx context.Context, filter ListFilter) ([]models.Recipe, error)
+	// ListPage returns the page of the recipes matching filter selected by
+	// page, sorting and skipping in the database
+	ListPage(ctx context.Context, filter ListFilter, page PageRequest) ([]models.Recipe, error)
+	// Count returns the number of recipes matching filter
+	Count(ctx context.Context, filter ListFilter) (int64, error)
+	// Stream calls each with the recipes matching filter one at a time, in ID
+	// order, without loading them all in memory. It stops at the first error
+	// returned by each.
+	Stream(ctx context.Context, filter ListFilter, each func(models.Recipe) error) error
+	// Update applies patch and returns the updated recipe
+	Update(ctx context.Context, id primitive.ObjectID, patch models.RecipePatch) (models.Recipe, error)
+	// Delete removes the recipe and returns it as it was before deletion
+	Delete(ctx context.Context, id primitive.ObjectID) (models.Recipe, error)
+	// DeleteMany deletes the given recipes, restricted to those owned by
+	// owner unless owner is the zero ObjectID, and returns the deleted recipes
+	DeleteMany(ctx context.Context, ids []primitive.ObjectID, owner primitive.ObjectID) ([]models.Recipe, error)
+	Search(ctx context.Context, criteria SearchCriteria) ([]models.Recipe, error)
+	// TagCounts returns the number of published recipes using each tag, most used first
+	TagCounts(ctx context.Context) ([]models.TagCount, error)
+	// Stats summarizes the published recipes, with up to topTags tags
+	Stats(ctx context.Context, topTags int) (models.RecipeStats, error)
+	// RenameTag replaces the tag from with to in every recipe, keeping its
+	// position and dropping it when the recipe already has to, and returns
+	// the number of recipes modified
+	RenameTag(ctx context.Context, from, to string) (int64, error)
+	// Random returns a random published recipe matching criteria, or
+	// ErrNotFound when none does
+	Random(ctx context.Context, criteria RandomCriteria) (models.Recipe, error)
+	// AddViews adds views to the view totals of the recipes
+	AddViews(ctx context.Context, views map[primitive.ObjectID]int64) error
+	// Recent returns up to limit published recipes, the most recently
+	// published first
+	Recent(ctx context.Context, limit int64) ([]models.Recipe, error)
+	// Related returns up to limit other published recipes sharing tags with
+	// recipe, those sharing the most tags first
+	Related(ctx context.Context, recipe models.Recipe, limit int64) ([]models.Recipe, error)
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"time"
+)
+
+type routeKey struct{}
+
+// WithRoute attaches the route being served to ctx, so that slow queries
+// can be traced back to it
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+func routeFrom(ctx context.Context) string {
+	if route, ok := ctx.Value(routeKey{}).(string); ok {
+		return route
+	}
+	return "-"
+}
+
+// TimedStore wraps a RecipeStore and logs a slow query warning for every
+// call taking longer than threshold
+type TimedStore struct {
+	store     RecipeStore
+	threshold time.Duration
+}
+
+func NewTimedStore(store RecipeStore, threshold time.Duration) *TimedStore {
+	return &TimedStore{
+		store:     store,
+		threshold: threshold,
+	}
+}
+
+// observe logs op when it has been running for longer than the threshold.
+// It is meant to be deferred with the start time of the call.
+func (store *TimedStore) observe(ctx context.Context, op string, start time.Time) {
+	if elapsed := time.Since(start); elapsed > store.threshold {
+		log.Printf("Warning: slow query route=%s op=%s duration=%s threshold=%s", routeFrom(ctx), op, elapsed, store.threshold)
+	}
+}
+
+func (store *TimedStore) Create(ctx context.Context, recipe models.Recipe) error {
+	defer store.observe(ctx, "Create", time.Now())
+	return store.store.Create(ctx, recipe)
+}
+
+func (store *TimedStore) GetByID(ctx context.Context, id primitive.ObjectID) (models.Recipe, error) {
+	defer store.observe(ctx, "GetByID", time.Now())
+	return store.store.GetByID(ctx, id)
+}
+
+func (store *TimedStore) GetBySlug(ctx context.Context, slug string) (models.Recipe, error) {
+	defer store.observe(ctx, "GetBySlug", time.Now())
+	return store.store.GetBySlug(ctx, slug)
+}
+
+func (store *TimedStore) SlugsTaken(ctx context.Context, base string, exclude primitive.ObjectID) ([]string, error) {
+	defer store.observe(ctx, "SlugsTaken", time.Now())
+	return store.store.SlugsTaken(ctx, base, exclude)
+}
+
+func (store *TimedStore) List(ctx context.Context, filter ListFilter) ([]models.Recipe, error) {
+	defer store.observe(ctx, "List", time.Now())
+	return store.store.List(ctx, filter)
+}
+
+func (store *TimedStore) ListPage(ctx context.Context, filter ListFilter, page PageRequest) ([]models.Recipe, error) {
+	defer store.observe(ctx, "ListPage", time.Now())
+	return store.store.ListPage(ctx, filter, page)
+}
+
+func (store *TimedStore) Count(ctx context.Context, filter ListFilter) (int64, error) {
+	defer store.observe(ctx, "Count", time.Now())
+	return store.store.Count(ctx, filter)
+}
+
+// Stream is not timed, its duration depends on how fast the client reads
+func (store *TimedStore) Stream(ctx context.Context, filter ListFilter, each func(models.Recipe) error) error {
+	return store.store.Stream(ctx, filter, each)
+}
+
+func (store *TimedStore) Update(ctx context.Context, id primitive.ObjectID, patch models.RecipePatch) (models.Recipe, error) {
+	defer store.observe(ctx, "Update", time.Now())
+	return store.store.Update(ctx, id, patch)
+}
+
+func (store *TimedStore) Delete(ctx context.Context, id primitive.ObjectID) (models.Recipe, error) {
+	defer store.observe(ctx, "Delete", time.Now())
+	return store.store.Delete(ctx, id)
+}
+
+func (store *TimedStore) DeleteMany(ctx context.Context, ids []primitive.ObjectID, owner primitive.ObjectID) ([]models.Recipe, error) {
+	defer store.observe(ctx, "DeleteMany", time.Now())
+	return store.store.DeleteMany(ctx, ids, owner)
+}
+
+func (store *TimedStore) Search(ctx context.Context, criteria SearchCriteria) ([]models.Recipe, error) {
+	defer store.observe(ctx, "Search", time.Now())
+	return store.store.Search(ctx, criteria)
+}
+
+func (store *TimedStore) TagCounts(ctx context.Context) ([]models.TagCount, error) {
+	defer store.observe(ctx, "TagCounts", time.Now())
+	return store.store.TagCounts(ctx)
+}
+
+func (store *TimedStore) Stats(ctx context.Context, topTags int) (models.RecipeStats, error) {
+	defer store.observe(ctx, "Stats", time.Now())
+	return store.store.Stats(ctx, topTags)
+}
+
+func (store *TimedStore) RenameTag(ctx context.Context, from, to string) (int64, error) {
+	defer store.observe(ctx, "RenameTag", time.Now())
+	return store.store.RenameTag(ctx, from, to)
+}
+
+func (store *TimedStore) Random(ctx context.Context, criteria RandomCriteria) (models.Recipe, error) {
+	defer store.observe(ctx, "Random", time.Now())
+	return store.store.Random(ctx, criteria)
+}
+
+func (store *TimedStore) AddViews(ctx context.Context, views map[primitive.ObjectID]int64) error {
+	defer store.observe(ctx, "AddViews", time.Now())
+	return store.store.AddViews(ctx, views)
+}
+
+func (store *TimedStore) Recent(ctx context.Context, limit int64) ([]models.Recipe, error) {
+	defer store.observe(ctx, "Recent", time.Now())
+	return store.store.Recent(ctx, limit)
+}
+
+func (store *TimedStore) Related(ctx context.Context, recipe models.Recipe, limit int64) ([]models.Recipe, error) {
+	defer store.observe(ctx, "Related", time.Now())
+	return store.store.Related(ctx, recipe, limit)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Build metadata, injected at build time with
+//
+//	go build -ldflags "-X main.Version=1.2.0 -X main.Commit=$(git rev-parse HEAD) -X main.BuildTime=$(date -u +%FT%TZ)"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// swagger:operation GET /version meta version
+// Returns the build metadata of the running service
+// ---
+// produces:
+// - application/json
+// responses:
+//     '200':
+//         description: Successful operation
+func VersionHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"version":   Version,
+		"commit":    Commit,
+		"buildTime": BuildTime,
+	})
+}
@@ -0,0 +1,43 @@
+package views
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"time"
+)
+
+// Sink persists view totals, such as the recipe store
+type Sink interface {
+	AddViews(ctx context.Context, views map[primitive.ObjectID]int64) error
+}
+
+// Flush adds the pending views of counter to sink every interval until ctx
+// is done. Run it in its own goroutine.
+func Flush(ctx context.Context, counter Counter, sink Sink, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pending, err := counter.Pending()
+			if err != nil {
+				log.Printf("Warning: could not read the pending views: %v", err)
+				continue
+			}
+			if len(pending) == 0 {
+				continue
+			}
+			if err := sink.AddViews(ctx, pending); err != nil {
+				log.Printf("Warning: could not save %d recipe view counts: %v", len(pending), err)
+				// Keep the views for the next flush rather than losing them
+				if err := counter.Restore(pending); err != nil {
+					log.Printf("Warning: could not restore %d recipe view counts: %v", len(pending), err)
+				}
+			}
+		}
+	}
+}
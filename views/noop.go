@@ -0,0 +1,30 @@
+package views
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// NoopCounter counts nothing, so no recipe is ever trending. It is used when
+// Redis is unavailable.
+type NoopCounter struct{}
+
+func NewNoopCounter() NoopCounter {
+	return NoopCounter{}
+}
+
+func (NoopCounter) Record(recipeID primitive.ObjectID, viewer string) error {
+	return nil
+}
+
+func (NoopCounter) Trending(window time.Duration, limit int64) ([]Count, error) {
+	return []Count{}, nil
+}
+
+func (NoopCounter) Pending() (map[primitive.ObjectID]int64, error) {
+	return map[primitive.ObjectID]int64{}, nil
+}
+
+func (NoopCounter) Restore(pending map[primitive.ObjectID]int64) error {
+	return nil
+}
@@ -0,0 +1,125 @@
+package views
+
+import (
+	"github.com/go-redis/redis"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strconv"
+	"time"
+)
+
+// bucketSize is the granularity of trending windows. Views are counted in a
+// sorted set per hour, and a window sums the sets of the hours it covers.
+const bucketSize = time.Hour
+
+const pendingKey = "views:pending"
+
+// takePendingScript reads and clears the pending views atomically, so views
+// recorded while flushing are kept for the next flush
+var takePendingScript = redis.NewScript(`
+local pending = redis.call("HGETALL", KEYS[1])
+redis.call("DEL", KEYS[1])
+return pending
+`)
+
+// RedisCounter counts views in Redis, shared by every instance of the API
+type RedisCounter struct {
+	client    *redis.Client
+	prefix    string
+	debounce  time.Duration
+	retention time.Duration
+}
+
+// NewRedisCounter counts a view at most once per debounce for each viewer,
+// and keeps the hourly counts for retention, the longest trending window.
+// Its keys start with prefix, as the cache keys start with CACHE_PREFIX.
+func NewRedisCounter(client *redis.Client, prefix string, debounce time.Duration, retention time.Duration) *RedisCounter {
+	return &RedisCounter{
+		client:    client,
+		prefix:    prefix,
+		debounce:  debounce,
+		retention: retention,
+	}
+}
+
+func (counter *RedisCounter) Record(recipeID primitive.ObjectID, viewer string) error {
+	id := recipeID.Hex()
+	first, err := counter.client.SetNX(counter.prefix+"views:seen:"+id+":"+viewer, 1, counter.debounce).Result()
+	if err != nil || !first {
+		return err
+	}
+
+	bucket := counter.prefix + bucketKey(time.Now())
+	pipe := counter.client.TxPipeline()
+	pipe.ZIncrBy(bucket, 1, id)
+	pipe.Expire(bucket, counter.retention+bucketSize)
+	pipe.HIncrBy(counter.prefix+pendingKey, id, 1)
+	_, err = pipe.Exec()
+	return err
+}
+
+func (counter *RedisCounter) Trending(window time.Duration, limit int64) ([]Count, error) {
+	if window > counter.retention {
+		window = counter.retention
+	}
+	now := time.Now()
+	keys := make([]string, 0)
+	for t := now.Add(-window); !t.After(now); t = t.Add(bucketSize) {
+		keys = append(keys, counter.prefix+bucketKey(t))
+	}
+
+	// The union is computed from Redis and stored for a few seconds, so
+	// concurrent requests for the same window share it
+	union := counter.prefix + "views:trending:" + strconv.FormatInt(int64(window/time.Second), 10)
+	pipe := counter.client.TxPipeline()
+	pipe.ZUnionStore(union, redis.ZStore{}, keys...)
+	pipe.Expire(union, 5*time.Second)
+	top := pipe.ZRevRangeWithScores(union, 0, limit-1)
+	if _, err := pipe.Exec(); err != nil {
+		return nil, err
+	}
+
+	counts := make([]Count, 0)
+	for _, member := range top.Val() {
+		hex, _ := member.Member.(string)
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			continue
+		}
+		counts = append(counts, Count{RecipeID: id, Views: int64(member.Score)})
+	}
+	return counts, nil
+}
+
+func (counter *RedisCounter) Pending() (map[primitive.ObjectID]int64, error) {
+	result, err := takePendingScript.Run(counter.client, []string{counter.prefix + pendingKey}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[primitive.ObjectID]int64)
+	fields, _ := result.([]interface{})
+	for i := 0; i+1 < len(fields); i += 2 {
+		hex, _ := fields[i].(string)
+		value, _ := fields[i+1].(string)
+		id, err := primitive.ObjectIDFromHex(hex)
+		views, convErr := strconv.ParseInt(value, 10, 64)
+		if err != nil || convErr != nil {
+			continue
+		}
+		pending[id] += views
+	}
+	return pending, nil
+}
+
+func (counter *RedisCounter) Restore(pending map[primitive.ObjectID]int64) error {
+	pipe := counter.client.TxPipeline()
+	for id, views := range pending {
+		pipe.HIncrBy(counter.prefix+pendingKey, id.Hex(), views)
+	}
+	_, err := pipe.Exec()
+	return err
+}
+
+func bucketKey(t time.Time) string {
+	return "views:hour:" + strconv.FormatInt(t.Unix()/int64(bucketSize/time.Second), 10)
+}
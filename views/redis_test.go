@@ -0,0 +1,89 @@
+package views
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCounter(t *testing.T, debounce time.Duration) (*RedisCounter, *miniredis.Miniredis) {
+	t.Helper()
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("starting miniredis: %v", err)
+	}
+	t.Cleanup(server.Close)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisCounter(client, "recipes-api:", debounce, 24*time.Hour), server
+}
+
+func TestRedisCounterCountsEachViewerOnce(t *testing.T) {
+	counter, server := newTestCounter(t, time.Hour)
+	pancakes, waffles := primitive.NewObjectID(), primitive.NewObjectID()
+	for _, viewer := range []string{"ip:192.0.2.1", "ip:192.0.2.1", "user:alice", "user:bob"} {
+		if err := counter.Record(pancakes, viewer); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	counter.Record(waffles, "ip:192.0.2.1")
+	for _, key := range server.Keys() {
+		if !strings.HasPrefix(key, "recipes-api:views:") {
+			t.Errorf("key %s is not under the prefix", key)
+		}
+	}
+
+	pending, err := counter.Pending()
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	want := map[primitive.ObjectID]int64{pancakes: 3, waffles: 1}
+	if !reflect.DeepEqual(pending, want) {
+		t.Errorf("pending = %v, want %v", pending, want)
+	}
+	if pending, _ := counter.Pending(); len(pending) != 0 {
+		t.Errorf("pending after being taken = %v, want none", pending)
+	}
+}
+
+func TestRedisCounterRestoresPendingViews(t *testing.T) {
+	counter, _ := newTestCounter(t, time.Hour)
+	pancakes := primitive.NewObjectID()
+	counter.Record(pancakes, "user:alice")
+	taken, _ := counter.Pending()
+	counter.Record(pancakes, "user:bob")
+
+	if err := counter.Restore(taken); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	pending, err := counter.Pending()
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if want := map[primitive.ObjectID]int64{pancakes: 2}; !reflect.DeepEqual(pending, want) {
+		t.Errorf("pending = %v, want %v", pending, want)
+	}
+}
+
+func TestRedisCounterTrending(t *testing.T) {
+	counter, _ := newTestCounter(t, time.Hour)
+	ids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+	for i, id := range ids {
+		for viewer := 0; viewer <= i; viewer++ {
+			counter.Record(id, "user:"+string(rune('a'+viewer)))
+		}
+	}
+
+	counts, err := counter.Trending(time.Hour, 2)
+	if err != nil {
+		t.Fatalf("Trending: %v", err)
+	}
+	want := []Count{{RecipeID: ids[2], Views: 3}, {RecipeID: ids[1], Views: 2}}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("trending = %v, want %v", counts, want)
+	}
+}
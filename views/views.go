@@ -0,0 +1,29 @@
+package views
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Count is the number of views of a recipe
+type Count struct {
+	RecipeID primitive.ObjectID
+	Views    int64
+}
+
+// Counter counts the views of recipes. Counting is best effort: a failure
+// must not fail the request that viewed the recipe.
+type Counter interface {
+	// Record counts a view of the recipe by viewer, unless the same viewer
+	// already viewed it recently
+	Record(recipeID primitive.ObjectID, viewer string) error
+	// Trending returns up to limit recipes with the most views within
+	// window, the most viewed first
+	Trending(window time.Duration, limit int64) ([]Count, error)
+	// Pending returns the views recorded since the previous call and resets
+	// them, so they can be added to the totals stored with the recipes
+	Pending() (map[primitive.ObjectID]int64, error)
+	// Restore adds views taken by Pending back to the pending views, when
+	// they could not be added to the totals
+	Restore(pending map[primitive.ObjectID]int64) error
+}
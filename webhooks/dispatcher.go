@@ -0,0 +1,117 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gabrielsscti/Recipes-API/events"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Recipes-Signature"
+	EventHeader     = "X-Recipes-Event"
+)
+
+// Dispatcher is an events.Publisher delivering each event to the webhooks
+// registered by the owner of the recipe
+type Dispatcher struct {
+	ctx         context.Context
+	collection  *mongo.Collection
+	client      *http.Client
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+func NewDispatcher(ctx context.Context, collection *mongo.Collection) *Dispatcher {
+	return &Dispatcher{
+		ctx:         ctx,
+		collection:  collection,
+		client:      newClient(10 * time.Second),
+		maxAttempts: 4,
+		baseDelay:   time.Second,
+	}
+}
+
+// Publish looks up the subscriptions of the recipe owner and delivers to them
+// in the background, so slow or failing endpoints never delay the request
+func (dispatcher *Dispatcher) Publish(event events.Event) error {
+	cur, err := dispatcher.collection.Find(dispatcher.ctx, bson.M{"userId": event.UserID})
+	if err != nil {
+		return err
+	}
+	defer cur.Close(dispatcher.ctx)
+
+	subscriptions := make([]models.Webhook, 0)
+	if err := cur.All(dispatcher.ctx, &subscriptions); err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	for _, subscription := range subscriptions {
+		go dispatcher.deliver(subscription, event.Type, payload)
+	}
+	return nil
+}
+
+// deliver POSTs payload to the webhook, retrying with exponential backoff
+// on network errors and 5xx responses
+func (dispatcher *Dispatcher) deliver(subscription models.Webhook, eventType string, payload []byte) {
+	delay := dispatcher.baseDelay
+	for attempt := 1; attempt <= dispatcher.maxAttempts; attempt++ {
+		err := dispatcher.post(subscription, eventType, payload)
+		if err == nil {
+			return
+		}
+		log.Printf("Webhook %s delivery attempt %d failed: %v", subscription.ID.Hex(), attempt, err)
+		if attempt < dispatcher.maxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	log.Printf("Giving up on webhook %s after %d attempts", subscription.ID.Hex(), dispatcher.maxAttempts)
+}
+
+func (dispatcher *Dispatcher) post(subscription models.Webhook, eventType string, payload []byte) error {
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(subscription.Secret, payload))
+
+	resp, err := dispatcher.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("server responded with %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of payload, which receivers
+// compare against the signature header to authenticate deliveries
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
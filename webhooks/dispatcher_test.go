@@ -0,0 +1,140 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gabrielsscti/Recipes-API/events"
+	"github.com/gabrielsscti/Recipes-API/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// delivery is a request received by the webhook target
+type delivery struct {
+	event     string
+	signature string
+	body      []byte
+}
+
+func TestDispatcherDeliversSignedEvents(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("retries on 500", func(mt *mtest.T) {
+		var mu sync.Mutex
+		deliveries := make([]delivery, 0)
+		done := make(chan struct{})
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			defer mu.Unlock()
+			deliveries = append(deliveries, delivery{r.Header.Get(EventHeader), r.Header.Get(SignatureHeader), body})
+			if len(deliveries) < 3 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+			close(done)
+		}))
+		defer target.Close()
+
+		owner := primitive.NewObjectID()
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "url", Value: target.URL},
+			{Key: "userId", Value: owner},
+			{Key: "secret", Value: "s3cret"},
+		}))
+
+		dispatcher := NewDispatcher(context.Background(), mt.Coll)
+		dispatcher.baseDelay = time.Millisecond
+		// The target listens on loopback, which the default client refuses
+		dispatcher.client = target.Client()
+		event := events.NewEvent(events.RecipeUpdated, primitive.NewObjectID(), owner)
+		if err := dispatcher.Publish(event); err != nil {
+			mt.Fatalf("Publish: %v", err)
+		}
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			mt.Fatal("the webhook was not delivered")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(deliveries) != 3 {
+			mt.Fatalf("got %d deliveries, want 2 failures and a success", len(deliveries))
+		}
+		last := deliveries[2]
+		if last.event != events.RecipeUpdated {
+			mt.Errorf("%s = %q, want %q", EventHeader, last.event, events.RecipeUpdated)
+		}
+		if want := "sha256=" + Sign("s3cret", last.body); last.signature != want {
+			mt.Errorf("%s = %q, want %q", SignatureHeader, last.signature, want)
+		}
+		var got events.Event
+		if err := json.Unmarshal(last.body, &got); err != nil || got.RecipeID != event.RecipeID {
+			mt.Errorf("payload %s is not the event: %v", last.body, err)
+		}
+	})
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '{"type":"recipe.created"}' | openssl dgst -sha256 -hmac secret
+	want := "cb6e8749a37e196dd88ffe078ae42bff4662c053730b82a3283295299db46beb"
+	if got := Sign("secret", []byte(`{"type":"recipe.created"}`)); got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"https://93.184.216.34/hook", nil},
+		{"http://[2606:2800:220:1:248:1893:25c8:1946]:8080/hook", nil},
+		{"ftp://93.184.216.34/hook", ErrInvalidURL},
+		{"/hook", ErrInvalidURL},
+		{"http://127.0.0.1/hook", ErrForbiddenTarget},
+		{"http://localhost:8080/hook", ErrForbiddenTarget},
+		{"http://[::1]/hook", ErrForbiddenTarget},
+		{"http://0.0.0.0/hook", ErrForbiddenTarget},
+		{"http://10.1.2.3/hook", ErrForbiddenTarget},
+		{"http://172.16.0.1/hook", ErrForbiddenTarget},
+		{"http://192.168.1.1/hook", ErrForbiddenTarget},
+		{"http://169.254.169.254/latest/meta-data", ErrForbiddenTarget},
+		{"http://[fd00::1]/hook", ErrForbiddenTarget},
+		{"http://[fe80::1]/hook", ErrForbiddenTarget},
+	}
+	for _, test := range tests {
+		if err := ValidateURL(context.Background(), test.url); err != test.want {
+			t.Errorf("ValidateURL(%q) = %v, want %v", test.url, err, test.want)
+		}
+	}
+}
+
+func TestDeliveriesRefusePrivateAddresses(t *testing.T) {
+	hit := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer target.Close()
+
+	// A URL checked on registration may resolve to loopback by the time it is delivered to
+	dispatcher := &Dispatcher{client: newClient(time.Second)}
+	err := dispatcher.post(models.Webhook{URL: target.URL, Secret: "s3cret"}, events.RecipeCreated, []byte("{}"))
+	if !errors.Is(err, ErrForbiddenTarget) || hit {
+		t.Errorf("post to %s = %v, reached = %v, want ErrForbiddenTarget before connecting", target.URL, err, hit)
+	}
+}
@@ -0,0 +1,82 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenTarget is returned for webhook URLs reaching the server itself
+// or its private network, which would let users probe internal services
+var ErrForbiddenTarget = errors.New("webhook URL must not point to a loopback, private or link-local address")
+
+// ErrInvalidURL is returned for webhook URLs that are not absolute http or https URLs
+var ErrInvalidURL = errors.New("webhook URL must be an absolute http or https URL")
+
+// ValidateURL checks that rawURL is an http or https URL whose host only
+// resolves to public addresses. Deliveries check the address again when
+// connecting, since the host may resolve differently by then.
+func ValidateURL(ctx context.Context, rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return ErrInvalidURL
+	}
+
+	host := target.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return checkAddress(ip)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if err := checkAddress(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkAddress returns ErrForbiddenTarget unless ip is a public unicast address
+func checkAddress(ip net.IP) error {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return ErrForbiddenTarget
+	}
+	return nil
+}
+
+// controlDial refuses connections to the addresses ValidateURL rejects, once
+// the host has been resolved for this very connection
+func controlDial(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ErrForbiddenTarget
+	}
+	return checkAddress(ip)
+}
+
+// newClient returns the client deliveries are made with. It connects
+// directly rather than through a proxy, so that controlDial sees the address
+// of the webhook, and applies to redirects too.
+func newClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   controlDial,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}